@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"telegram-chatbot/internal/config"
+)
+
+// runAllowlist adds or removes allowed chats or users in the config file. A
+// running bot applies the change on its next config reload.
+func runAllowlist(args []string) error {
+	if len(args) == 0 || (args[0] != "add" && args[0] != "remove") {
+		return fmt.Errorf("expected: allowlist add|remove [-user] <id>...")
+	}
+	add := args[0] == "add"
+
+	flags := flag.NewFlagSet("allowlist "+args[0], flag.ContinueOnError)
+	users := flags.Bool("user", false, "change allowed users instead of chats")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return fmt.Errorf("no IDs given")
+	}
+
+	ids := make([]int64, 0, flags.NArg())
+	for _, arg := range flags.Args() {
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid ID %s", arg)
+		}
+		ids = append(ids, id)
+	}
+
+	list := config.AllowedChats
+	if *users {
+		list = config.AllowedUsers
+	}
+
+	path := os.Getenv("CONFIG_FILE")
+	if path == "" {
+		return fmt.Errorf("CONFIG_FILE is not set, the allowlist can only be changed in a config file")
+	}
+	// Переменная окружения важнее файла, изменение ни на что бы не повлияло
+	if os.Getenv(list.EnvName()) != "" {
+		return fmt.Errorf("%s is set in the environment and overrides the config file", list.EnvName())
+	}
+
+	changed, err := config.UpdateAllowlist(path, list, ids, add)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		fmt.Println("Allowlist already up to date")
+		return nil
+	}
+
+	fmt.Printf("Updated %s in %s\n", list.EnvName(), path)
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"telegram-chatbot/internal/config"
+	"telegram-chatbot/internal/di"
+
+	"go.uber.org/zap"
+)
+
+// runConfig handles "config validate"
+func runConfig(args []string) error {
+	if len(args) == 0 || args[0] != "validate" {
+		return fmt.Errorf("expected: config validate [-file path]")
+	}
+
+	flags := flag.NewFlagSet("config validate", flag.ContinueOnError)
+	path := flags.String("file", os.Getenv("CONFIG_FILE"), "config file, CONFIG_FILE by default")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadFile(*path)
+	if err != nil {
+		return err
+	}
+
+	// Ключи и модели проверяются при сборке контейнера, проверяем их так же,
+	// но без подключения к Telegram и хранилищу
+	if _, err := di.NewKeyring(cfg); err != nil {
+		return fmt.Errorf("invalid encryption keys: %w", err)
+	}
+	if _, err := di.NewModelRouter(cfg, zap.NewNop()); err != nil {
+		return err
+	}
+
+	source := cfg.ConfigFile
+	if source == "" {
+		source = "environment only"
+	}
+	fmt.Println("Config is valid")
+	fmt.Printf("  source:        %s\n", source)
+	fmt.Printf("  storage:       %s, sessions in %s\n", cfg.StorageBackend, cfg.SessionBackend)
+	fmt.Printf("  default model: %s, %d fallbacks, %d to choose from\n", cfg.LLMModel, len(cfg.LLMFallbacks), len(cfg.LLMModels))
+	fmt.Printf("  allowed:       %d chats, %d users\n", len(cfg.AllowedChatIDs), len(cfg.AllowedUserIDs))
+	fmt.Printf("  personas:      %d chats\n", len(cfg.Personas))
+	return nil
+}
@@ -1,50 +1,63 @@
 package main
 
 import (
-	"context"
+	"fmt"
 	"log"
 	"os"
-	"os/signal"
-	"syscall"
-	docs "telegram-chatbot/docs"
-	"telegram-chatbot/internal/app"
-	"telegram-chatbot/internal/config"
-	"telegram-chatbot/internal/di"
+	_ "time/tzdata" // часовые пояса для образа без tzdata
 
 	"github.com/joho/godotenv"
 )
 
-func main() {
-	// Загружаем .env файл (игнорируем ошибки для продакшена)
-	_ = godotenv.Load(".env.example")
-
-	cfg, err := config.Load()
-	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
-	}
+const usage = `Usage: main [command]
 
-	docs.SwaggerInfo.BasePath = "/"
+Commands:
+  serve                                  run the bot (default)
+  config validate [-file path]           check the configuration
+  sessions list                          list active sessions
+  sessions show <chat_id> <user_id>      print a session's history
+  sessions delete <chat_id> <user_id>    delete a session
+  sessions export [<chat_id> <user_id>]  print a session or all active sessions as JSON
+  allowlist add|remove [-user] <id>...   change allowed chats or users in the config file
+  send -chat <id> <text>                 send a message to an allowed chat
+  migrate [-dry-run]                     upgrade sessions and the archive index in Redis
 
-	container, cleanup, err := di.InitializeContainer(cfg)
-	if err != nil {
-		log.Fatalf("Failed to initialize container: %v", err)
-	}
-	defer cleanup()
+In group chats user_id 0 is the shared session.
+`
 
-	application := app.New(container)
+func main() {
+	// Загружаем .env файл, если он есть (в продакшене переменные задаются окружением)
+	_ = godotenv.Load()
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	command, args := "serve", os.Args[1:]
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
 
-	// Graceful shutdown
-	go func() {
-		c := make(chan os.Signal, 1)
-		signal.Notify(c, os.Interrupt, syscall.SIGTERM)
-		<-c
-		cancel()
-	}()
+	if err := run(command, args); err != nil {
+		log.Fatalf("%s: %v", command, err)
+	}
+}
 
-	if err := application.Run(ctx); err != nil {
-		log.Fatalf("Application failed: %v", err)
+func run(command string, args []string) error {
+	switch command {
+	case "serve":
+		return runServe(args)
+	case "config":
+		return runConfig(args)
+	case "sessions":
+		return runSessions(args)
+	case "allowlist":
+		return runAllowlist(args)
+	case "send":
+		return runSend(args)
+	case "migrate":
+		return runMigrate(args)
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return nil
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("unknown command %q", command)
 	}
 }
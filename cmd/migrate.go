@@ -0,0 +1,96 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"telegram-chatbot/internal/config"
+	"telegram-chatbot/internal/di"
+)
+
+// runMigrate upgrades all sessions stored in Redis to the current format and
+// moves the archive search index to the current encryption key
+func runMigrate(args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "only count outdated sessions and archived conversations")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// SQL-хранилище мигрирует схему само при запуске, в памяти мигрировать нечего
+	if cfg.SessionBackend == "redis" {
+		if err := migrateSessions(cfg, *dryRun); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("Sessions are stored in %s, nothing to migrate\n", cfg.SessionBackend)
+	}
+
+	if cfg.StorageBackend != "redis" {
+		fmt.Printf("Archive is stored in %s, nothing to reindex\n", cfg.StorageBackend)
+		return nil
+	}
+	return reindexArchive(cfg, *dryRun)
+}
+
+func migrateSessions(cfg *config.Config, dryRun bool) error {
+	migrator, cleanup, err := di.InitializeSessionMigrator(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize migrator: %w", err)
+	}
+	defer cleanup()
+
+	report, err := migrator.MigrateSessions(dryRun)
+	if err != nil {
+		return err
+	}
+
+	action := "migrated"
+	if dryRun {
+		action = "to migrate"
+	}
+	fmt.Printf("Sessions scanned: %d, %s: %d, already current: %d, failed: %d\n",
+		report.Scanned, action, report.Migrated, report.Current, len(report.Failed))
+	for _, failure := range report.Failed {
+		fmt.Println("  " + failure)
+	}
+
+	if len(report.Failed) > 0 {
+		return fmt.Errorf("%d sessions could not be migrated", len(report.Failed))
+	}
+	return nil
+}
+
+// reindexArchive rewrites index entries stored in plain text or under a
+// rotated-out key
+func reindexArchive(cfg *config.Config, dryRun bool) error {
+	reindexer, cleanup, err := di.InitializeArchiveReindexer(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize reindexer: %w", err)
+	}
+	defer cleanup()
+
+	report, err := reindexer.ReindexArchive(dryRun)
+	if err != nil {
+		return err
+	}
+
+	action := "reindexed"
+	if dryRun {
+		action = "to reindex"
+	}
+	fmt.Printf("Archived conversations scanned: %d, %s: %d, failed: %d\n",
+		report.Scanned, action, report.Reindexed, len(report.Failed))
+	for _, failure := range report.Failed {
+		fmt.Println("  " + failure)
+	}
+
+	if len(report.Failed) > 0 {
+		return fmt.Errorf("%d archived conversations could not be reindexed", len(report.Failed))
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"telegram-chatbot/internal/config"
+	"telegram-chatbot/internal/di"
+)
+
+// runSend sends a message to an allowed chat on behalf of the bot
+func runSend(args []string) error {
+	flags := flag.NewFlagSet("send", flag.ContinueOnError)
+	chatID := flags.Int64("chat", 0, "chat to send the message to")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	text := strings.TrimSpace(strings.Join(flags.Args(), " "))
+	if *chatID == 0 || text == "" {
+		return fmt.Errorf("expected: send -chat <id> <text>")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	container, cleanup, err := di.InitializeContainer(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize container: %w", err)
+	}
+	defer cleanup()
+
+	if err := container.Bot.SendText(context.Background(), *chatID, text); err != nil {
+		return err
+	}
+
+	fmt.Println("Message sent")
+	return nil
+}
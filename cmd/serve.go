@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	docs "telegram-chatbot/docs"
+	"telegram-chatbot/internal/app"
+	"telegram-chatbot/internal/config"
+	"telegram-chatbot/internal/di"
+)
+
+// runServe starts the bot and its background services until interrupted
+func runServe(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %v", args)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	docs.SwaggerInfo.BasePath = "/"
+
+	container, cleanup, err := di.InitializeContainer(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize container: %w", err)
+	}
+	defer cleanup()
+
+	application := app.New(container)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Graceful shutdown
+	go func() {
+		c := make(chan os.Signal, 1)
+		signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+		<-c
+		cancel()
+	}()
+
+	return application.Run(ctx)
+}
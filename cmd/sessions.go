@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"telegram-chatbot/internal/config"
+	"telegram-chatbot/internal/di"
+	"telegram-chatbot/internal/domain/entities"
+	"telegram-chatbot/internal/domain/repositories"
+	"text/tabwriter"
+	"time"
+)
+
+const sessionsUsage = "expected: sessions list | show <chat_id> <user_id> | delete <chat_id> <user_id> | export [<chat_id> <user_id>]"
+
+// runSessions inspects and manages stored sessions
+func runSessions(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf(sessionsUsage)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	// Сессии в памяти живут только внутри процесса бота
+	if cfg.SessionBackend == "memory" {
+		return fmt.Errorf("sessions are kept in the bot's memory and cannot be reached from the command line")
+	}
+
+	sessionRepo, cleanup, err := di.InitializeSessionStore(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to open session storage: %w", err)
+	}
+	defer cleanup()
+
+	switch command, rest := args[0], args[1:]; command {
+	case "list":
+		return listSessions(sessionRepo)
+	case "show":
+		session, err := findSession(sessionRepo, rest)
+		if err != nil {
+			return err
+		}
+		printSession(session)
+		return nil
+	case "delete":
+		session, err := findSession(sessionRepo, rest)
+		if err != nil {
+			return err
+		}
+		if err := sessionRepo.DeleteSession(session.ChatID, session.UserID); err != nil {
+			return err
+		}
+		fmt.Printf("Session %d/%d deleted\n", session.ChatID, session.UserID)
+		return nil
+	case "export":
+		return exportSessions(sessionRepo, rest)
+	default:
+		return fmt.Errorf(sessionsUsage)
+	}
+}
+
+// activeSessions returns the active sessions, most recently used first
+func activeSessions(sessionRepo repositories.SessionRepository) ([]*entities.ChatSession, error) {
+	sessions, err := sessionRepo.ListIdleSessions(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastActivity().After(sessions[j].LastActivity())
+	})
+	return sessions, nil
+}
+
+func listSessions(sessionRepo repositories.SessionRepository) error {
+	sessions, err := activeSessions(sessionRepo)
+	if err != nil {
+		return err
+	}
+	if len(sessions) == 0 {
+		fmt.Println("No active sessions")
+		return nil
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "CHAT\tUSER\tMESSAGES\tLAST ACTIVITY")
+	for _, session := range sessions {
+		fmt.Fprintf(writer, "%d\t%s\t%d\t%s\n",
+			session.ChatID, sessionOwner(session), len(session.Messages), session.LastActivity().Format(time.DateTime))
+	}
+	return writer.Flush()
+}
+
+// findSession loads the session named by chat and user IDs. Sessions that
+// are neither active nor have history are reported as missing.
+func findSession(sessionRepo repositories.SessionRepository, args []string) (*entities.ChatSession, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("expected <chat_id> <user_id>")
+	}
+
+	chatID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chat ID %s", args[0])
+	}
+	userID, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID %s", args[1])
+	}
+
+	session, err := sessionRepo.GetSession(chatID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !session.IsActive && len(session.Messages) == 0 {
+		return nil, fmt.Errorf("no session for chat %d and user %d", chatID, userID)
+	}
+	return session, nil
+}
+
+func printSession(session *entities.ChatSession) {
+	status := "ended"
+	if session.IsActive {
+		status = "active"
+	}
+	fmt.Printf("Chat %d, user %s: %s, %d messages, last activity %s\n",
+		session.ChatID, sessionOwner(session), status, len(session.Messages), session.LastActivity().Format(time.DateTime))
+
+	for _, msg := range session.Messages {
+		role := msg.Role
+		if msg.Model != "" {
+			role += " (" + msg.Model + ")"
+		}
+		fmt.Printf("\n[%s] %s:\n%s\n", msg.Timestamp.Format(time.DateTime), role, msg.Content)
+		for _, call := range msg.ToolCalls {
+			fmt.Printf("  tool %s(%s) -> %s\n", call.Name, call.Input, strings.TrimSpace(call.Output))
+		}
+	}
+}
+
+// exportSessions prints one session or all active sessions as JSON
+func exportSessions(sessionRepo repositories.SessionRepository, args []string) error {
+	var export any
+	if len(args) == 0 {
+		sessions, err := activeSessions(sessionRepo)
+		if err != nil {
+			return err
+		}
+		export = sessions
+	} else {
+		session, err := findSession(sessionRepo, args)
+		if err != nil {
+			return err
+		}
+		export = session
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(export)
+}
+
+func sessionOwner(session *entities.ChatSession) string {
+	if session.IsShared() {
+		return "shared"
+	}
+	return strconv.FormatInt(session.UserID, 10)
+}
@@ -67,6 +67,69 @@ const docTemplate = `{
                     }
                 }
             }
+        },
+        "/stats/usage": {
+            "get": {
+                "description": "Tokens spent per model since the bot started, including prompt cache reads and writes",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "stats"
+                ],
+                "summary": "Token usage",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/services.UsageSnapshot"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "services.ModelUsage": {
+            "type": "object",
+            "properties": {
+                "cache_hit_ratio": {
+                    "description": "CacheHitRatio is the share of prompt tokens read from the cache",
+                    "type": "number"
+                },
+                "cache_read_tokens": {
+                    "type": "integer"
+                },
+                "cache_write_tokens": {
+                    "type": "integer"
+                },
+                "input_tokens": {
+                    "type": "integer"
+                },
+                "output_tokens": {
+                    "type": "integer"
+                },
+                "requests": {
+                    "type": "integer"
+                }
+            }
+        },
+        "services.UsageSnapshot": {
+            "type": "object",
+            "properties": {
+                "models": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/services.ModelUsage"
+                    }
+                },
+                "since": {
+                    "type": "string"
+                },
+                "total": {
+                    "$ref": "#/definitions/services.ModelUsage"
+                }
+            }
         }
     }
 }`
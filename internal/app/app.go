@@ -17,11 +17,11 @@ func New(container *di.Container) *App {
 }
 
 func (a *App) Run(ctx context.Context) error {
-	// Create a wait group to wait for both services
+	// Create a wait group to wait for all services
 	var wg sync.WaitGroup
 
 	// Create an error channel to collect errors
-	errCh := make(chan error, 2)
+	errCh := make(chan error, 5)
 
 	// Start the bot in a goroutine
 	wg.Add(1)
@@ -41,7 +41,34 @@ func (a *App) Run(ctx context.Context) error {
 		}
 	}()
 
-	// Wait for both services to complete or for an error
+	// Start the reminder scheduler in a goroutine
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := a.container.ReminderScheduler.Start(ctx); err != nil {
+			errCh <- err
+		}
+	}()
+
+	// Start the idle session sweeper in a goroutine
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := a.container.SessionSweeper.Start(ctx); err != nil {
+			errCh <- err
+		}
+	}()
+
+	// Start the config file watcher in a goroutine
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := a.container.ConfigWatcher.Start(ctx); err != nil {
+			errCh <- err
+		}
+	}()
+
+	// Wait for all services to complete or for an error
 	go func() {
 		wg.Wait()
 		close(errCh)
@@ -2,40 +2,150 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"telegram-chatbot/internal/domain/commands"
 	"telegram-chatbot/internal/domain/entities"
 	"telegram-chatbot/internal/domain/repositories"
 	"telegram-chatbot/internal/domain/services"
+	"telegram-chatbot/internal/domain/tools"
+	"time"
 
 	"go.uber.org/zap"
 )
 
-const MaxContextSize = 10000 // Примерный лимит символов
+// InlineQueryTimeout limits one-shot answers so they arrive while the inline query is still valid
+const InlineQueryTimeout = 8 * time.Second
+
+var (
+	ErrNothingToRegenerate = errors.New("no assistant reply to regenerate")
+	ErrNothingToContinue   = errors.New("no truncated reply to continue")
+	ErrMessageNotInHistory = errors.New("edited message is not in the session history")
+)
+
+// Reply is a handler's answer to a user message. Answered is set only when
+// Text is a new assistant turn stored in the session, so the bot links the
+// sent message to it; notices and error texts leave it unset.
+type Reply struct {
+	Text     string
+	Answered bool
+	// Revision is the session revision that stored the answer
+	Revision int64
+}
+
+// notice wraps a service message that is not part of the conversation
+func notice(text string) Reply {
+	return Reply{Text: text}
+}
 
 type CommandHandler struct {
 	sessionRepo   repositories.SessionRepository
+	settingsRepo  repositories.ChatSettingsRepository
+	archiveRepo   repositories.ArchiveRepository
 	claudeService services.ClaudeService
+	settings      services.RuntimeSettings
+	location      *time.Location
+	inlineAnswers *inlineAnswerCache
 	logger        *zap.Logger
 }
 
 func NewCommandHandler(
 	sessionRepo repositories.SessionRepository,
+	settingsRepo repositories.ChatSettingsRepository,
+	archiveRepo repositories.ArchiveRepository,
 	claudeService services.ClaudeService,
+	settings services.RuntimeSettings,
+	location *time.Location,
 	logger *zap.Logger,
 ) *CommandHandler {
 	return &CommandHandler{
 		sessionRepo:   sessionRepo,
+		settingsRepo:  settingsRepo,
+		archiveRepo:   archiveRepo,
 		claudeService: claudeService,
+		settings:      settings,
+		location:      location,
+		inlineAnswers: newInlineAnswerCache(),
 		logger:        logger,
 	}
 }
 
+// resolveSession returns the session the user talks in: the chat-wide one in
+// shared group mode and the personal one otherwise.
+func (h *CommandHandler) resolveSession(chatID, userID int64) (*entities.ChatSession, error) {
+	settings, err := h.settingsRepo.GetSettings(chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	if settings.SharedMode {
+		userID = entities.SharedSessionUserID
+	}
+
+	return h.sessionRepo.GetSession(chatID, userID)
+}
+
+// canManageSession reports whether the user may restart or end the session.
+// A shared session can only be managed by chat admins or by the user who started it.
+func canManageSession(session *entities.ChatSession, userID int64, isAdmin bool) bool {
+	if !session.IsShared() || !session.IsActive {
+		return true
+	}
+	return isAdmin || session.StartedBy == userID
+}
+
+// archiveSession stores the session history in the archive before it is
+// cleared. Archive errors are logged and do not block the session change.
+func (h *CommandHandler) archiveSession(session *entities.ChatSession) {
+	if len(session.Messages) == 0 {
+		return
+	}
+
+	conversation := entities.NewConversation(session)
+	if err := h.archiveRepo.SaveConversation(conversation); err != nil {
+		h.logger.Error("Failed to archive conversation",
+			zap.Int64("chatID", session.ChatID),
+			zap.Int64("userID", session.UserID),
+			zap.Error(err))
+		return
+	}
+
+	session.ConversationID = conversation.ID
+}
+
+// maxSessionAttempts bounds how often a quick session change is made again
+// after another request stored the session first
+const maxSessionAttempts = 3
+
+// retryOnConflict runs a change that reads and stores the session. If another
+// request stores the session in between, the change is made again on a fresh
+// copy. Changes that wait for the model are not retried this way.
+func retryOnConflict(change func() (string, error)) (string, error) {
+	for attempt := 1; ; attempt++ {
+		response, err := change()
+		if !errors.Is(err, repositories.ErrSessionChanged) || attempt == maxSessionAttempts {
+			return response, err
+		}
+	}
+}
+
 func (h *CommandHandler) HandleStart(ctx context.Context, cmd commands.StartCommand) (string, error) {
 	h.logger.Info("Handling start command", zap.Int64("chatID", cmd.ChatID), zap.Int64("userID", cmd.UserID))
 
-	// Полный перезапуск - удаляем сессию
-	if err := h.sessionRepo.DeleteSession(cmd.ChatID, cmd.UserID); err != nil {
+	session, err := h.resolveSession(cmd.ChatID, cmd.UserID)
+	if err != nil {
+		return "", err
+	}
+
+	// Общую сессию чужим пользователям не сбрасываем, просто показываем справку
+	if !canManageSession(session, cmd.UserID, cmd.IsAdmin) {
+		return h.getHelpMessage(), nil
+	}
+
+	// Полный перезапуск - архивируем разговор и удаляем сессию
+	h.archiveSession(session)
+	if err := h.sessionRepo.DeleteSession(session.ChatID, session.UserID); err != nil {
 		h.logger.Error("Failed to delete session", zap.Error(err))
 		return "", err
 	}
@@ -56,13 +166,27 @@ func (h *CommandHandler) getHelpMessage() string {
 /start - Перезапустить бота и показать это меню
 /help - Показать справку по командам
 /begin_chat - Начать сессию общения (бот запомнит контекст)
-/end_chat - Завершить сессию и очистить контекст
+/end_chat - Завершить сессию и очистить контекст (разговор останется в /search, факты из /memories сохранятся)
 /whoami - Показать информацию о пользователе и группе
+/undo - Удалить из контекста последний вопрос и ответ
+/retry - Повторить последний вопрос заново
+/remind <что и когда> - Создать напоминание
+/reminders - Список напоминаний и их отмена
+/list - Семейные списки: /list add молоко, /list done 3
+/remember <факт> - Запомнить факт надолго (всем <факт> - для всей семьи)
+/memories - Что я помню о тебе и семье
+/forget <номер> - Забыть факт
+/search <запрос> - Найти в прошлых разговорах и открыть их снова
+/privacy on|off - Маскировать телефоны, email, карты и адреса перед отправкой в Claude
+/model - Выбрать модель для этого чата
+/think on|off - Размышлять перед ответом, /think <вопрос> - один раз
+/group_mode on|off - Общая сессия для всей группы (только для администраторов)
 
 💬 **Как использовать:**
 • В группах упоминай меня @botname чтобы я ответил
 • В личных сообщениях просто пиши - отвечу на всё
 • Сессия позволяет мне помнить контекст разговора
+• В общем режиме у группы одна сессия, и я вижу, кто что сказал
 • Если контекст станет слишком большим, я его автоматически очищу
 
 ✨ **Возможности:**
@@ -77,55 +201,85 @@ func (h *CommandHandler) getHelpMessage() string {
 func (h *CommandHandler) HandleBeginChat(ctx context.Context, cmd commands.StartBeginCommand) (string, error) {
 	h.logger.Info("Handling start chat command", zap.Int64("chatID", cmd.ChatID), zap.Int64("userID", cmd.UserID))
 
-	session, err := h.sessionRepo.GetSession(cmd.ChatID, cmd.UserID)
-	if err != nil {
-		return "", err
-	}
+	return retryOnConflict(func() (string, error) {
+		session, err := h.resolveSession(cmd.ChatID, cmd.UserID)
+		if err != nil {
+			return "", err
+		}
 
-	if session.IsActive {
-		// Завершаем текущую сессию и начинаем новую
+		if !canManageSession(session, cmd.UserID, cmd.IsAdmin) {
+			return "🚫 Перезапустить общую сессию может только администратор или тот, кто её начал.", nil
+		}
+
+		// Новая сессия всегда начинается с чистой истории. Разговор текущей
+		// сессии архивируется, когда она уже сохранена заново.
+		previous := session.Clone()
 		session.Reset()
-	}
+		session.IsActive = true
+		session.StartedBy = cmd.UserID
+		session.Touch()
 
-	session.IsActive = true
+		if err := h.sessionRepo.SaveSession(session); err != nil {
+			return "", err
+		}
 
-	if err := h.sessionRepo.SaveSession(session); err != nil {
-		return "", err
-	}
+		if previous.IsActive {
+			h.archiveSession(previous)
+		}
+
+		if session.IsShared() {
+			return "💬 Общая сессия группы начата! Я буду помнить, кто что сказал, и следить за разговором.", nil
+		}
 
-	return "💬 Сессия общения начата! Теперь я буду запоминать контекст наших сообщений.", nil
+		return "💬 Сессия общения начата! Теперь я буду запоминать контекст наших сообщений.", nil
+	})
 }
 
 func (h *CommandHandler) HandleEndChat(ctx context.Context, cmd commands.EndChatCommand) (string, error) {
 	h.logger.Info("Handling end chat command", zap.Int64("chatID", cmd.ChatID), zap.Int64("userID", cmd.UserID))
 
-	session, err := h.sessionRepo.GetSession(cmd.ChatID, cmd.UserID)
-	if err != nil {
-		return "", err
-	}
+	return retryOnConflict(func() (string, error) {
+		session, err := h.resolveSession(cmd.ChatID, cmd.UserID)
+		if err != nil {
+			return "", err
+		}
 
-	if !session.IsActive {
-		return "ℹ️ Сессия общения уже не активна.", nil
-	}
+		if !session.IsActive {
+			return "ℹ️ Сессия общения уже не активна.", nil
+		}
 
-	session.IsActive = false
-	session.Reset()
+		if !canManageSession(session, cmd.UserID, cmd.IsAdmin) {
+			return "🚫 Завершить общую сессию может только администратор или тот, кто её начал.", nil
+		}
 
-	if err := h.sessionRepo.SaveSession(session); err != nil {
-		return "", err
-	}
+		ended := session.Clone()
+		session.IsActive = false
+		session.Reset()
 
-	return "👋 Сессия общения завершена. Контекст очищен.", nil
+		if err := h.sessionRepo.SaveSession(session); err != nil {
+			return "", err
+		}
+		h.archiveSession(ended)
+
+		return "👋 Сессия общения завершена. Контекст очищен.", nil
+	})
 }
 
 func (h *CommandHandler) HandleWhoAmI(ctx context.Context, cmd commands.WhoAmICommand) (string, error) {
 	h.logger.Info("Handling whoami command", zap.Int64("chatID", cmd.ChatID), zap.Int64("userID", cmd.UserID))
 
-	isActive := h.sessionRepo.IsSessionActive(cmd.ChatID, cmd.UserID)
+	session, err := h.resolveSession(cmd.ChatID, cmd.UserID)
+	if err != nil {
+		return "", err
+	}
+
 	status := "неактивна"
-	if isActive {
+	if session.IsActive {
 		status = "активна"
 	}
+	if session.IsShared() {
+		status += " (общая для группы)"
+	}
 
 	name := cmd.FirstName
 	if cmd.LastName != "" {
@@ -143,46 +297,492 @@ func (h *CommandHandler) HandleWhoAmI(ctx context.Context, cmd commands.WhoAmICo
 	), nil
 }
 
-// GetSession retrieves a chat session for the given chat and user IDs
+// GetSession retrieves the chat session the given user currently talks in
 func (h *CommandHandler) GetSession(ctx context.Context, chatID, userID int64) (*entities.ChatSession, error) {
-	return h.sessionRepo.GetSession(chatID, userID)
+	return h.resolveSession(chatID, userID)
 }
 
-func (h *CommandHandler) HandleMessage(ctx context.Context, cmd commands.ProcessMessageCommand) (string, error) {
-	h.logger.Info("Handling message", zap.Int64("chatID", cmd.ChatID), zap.Int64("userID", cmd.UserID))
+func (h *CommandHandler) HandleGroupMode(ctx context.Context, cmd commands.GroupModeCommand) (string, error) {
+	h.logger.Info("Handling group mode command",
+		zap.Int64("chatID", cmd.ChatID),
+		zap.Int64("userID", cmd.UserID),
+		zap.String("mode", cmd.Mode))
+
+	if !cmd.IsGroup {
+		return "ℹ️ Общий режим доступен только в группах.", nil
+	}
+
+	settings, err := h.settingsRepo.GetSettings(cmd.ChatID)
+	if err != nil {
+		return "", err
+	}
+
+	var enabled bool
+	switch cmd.Mode {
+	case "":
+		if settings.SharedMode {
+			return "👥 Сейчас включён общий режим: у группы одна сессия на всех.", nil
+		}
+		return "👤 Сейчас у каждого участника своя сессия. Включить общий режим: /group_mode on", nil
+	case "on":
+		enabled = true
+	case "off":
+		enabled = false
+	default:
+		return "ℹ️ Использование: /group_mode on|off", nil
+	}
+
+	if !cmd.IsAdmin {
+		return "🚫 Менять режим может только администратор группы.", nil
+	}
+
+	settings.SharedMode = enabled
+	if err := h.settingsRepo.SaveSettings(settings); err != nil {
+		return "", err
+	}
+
+	if enabled {
+		return "👥 Общий режим включён. Начните общую сессию командой /begin_chat.", nil
+	}
+	return "👤 Общий режим выключен. Теперь у каждого участника своя сессия.", nil
+}
+
+// HandlePrivacy turns masking of personal data before it is sent to Claude on or off
+func (h *CommandHandler) HandlePrivacy(ctx context.Context, cmd commands.PrivacyCommand) (string, error) {
+	h.logger.Info("Handling privacy command",
+		zap.Int64("chatID", cmd.ChatID),
+		zap.Int64("userID", cmd.UserID),
+		zap.String("mode", cmd.Mode))
+
+	settings, err := h.settingsRepo.GetSettings(cmd.ChatID)
+	if err != nil {
+		return "", err
+	}
+
+	var enabled bool
+	switch cmd.Mode {
+	case "":
+		if settings.RedactPII {
+			return "🔒 Телефоны, email, номера карт и адреса маскируются перед отправкой в Claude. Выключить: /privacy off", nil
+		}
+		return "🔓 Личные данные отправляются в Claude как есть. Включить маскировку: /privacy on", nil
+	case "on":
+		enabled = true
+	case "off":
+		enabled = false
+	default:
+		return "ℹ️ Использование: /privacy on|off", nil
+	}
+
+	if cmd.IsGroup && !cmd.IsAdmin {
+		return "🚫 Менять настройку в группе может только администратор.", nil
+	}
+
+	settings.RedactPII = enabled
+	if err := h.settingsRepo.SaveSettings(settings); err != nil {
+		return "", err
+	}
+
+	if enabled {
+		return "🔒 Маскировка включена: телефоны, email, номера карт и адреса заменяются метками до отправки в Claude, а в ответах возвращаются на место.", nil
+	}
+	return "🔓 Маскировка выключена.", nil
+}
+
+// HandleThink turns extended thinking before every answer in the chat on or off
+func (h *CommandHandler) HandleThink(ctx context.Context, cmd commands.ThinkCommand) (string, error) {
+	h.logger.Info("Handling think command",
+		zap.Int64("chatID", cmd.ChatID),
+		zap.Int64("userID", cmd.UserID),
+		zap.String("mode", cmd.Mode))
 
-	session, err := h.sessionRepo.GetSession(cmd.ChatID, cmd.UserID)
+	settings, err := h.settingsRepo.GetSettings(cmd.ChatID)
 	if err != nil {
 		return "", err
 	}
 
+	var enabled bool
+	switch cmd.Mode {
+	case "":
+		if settings.Thinking && !h.claudeService.CanThink(cmd.ChatID) {
+			return "💬 Размышления включены, но модель чата их не поддерживает, поэтому она отвечает сразу. Выключить: /think off", nil
+		}
+		if settings.Thinking {
+			return "🧠 Claude размышляет перед каждым ответом. Выключить: /think off", nil
+		}
+		return "💬 Claude отвечает сразу. Включить размышления: /think on, или спроси один раз: /think <вопрос>", nil
+	case "on":
+		enabled = true
+	case "off":
+		enabled = false
+	default:
+		return "ℹ️ Использование: /think on|off или /think <вопрос>", nil
+	}
+
+	if cmd.IsGroup && !cmd.IsAdmin {
+		return "🚫 Менять настройку в группе может только администратор.", nil
+	}
+	if enabled && !h.claudeService.CanThink(cmd.ChatID) {
+		return "🤷 Модель этого чата не умеет размышлять перед ответом. Выбери другую через /model или спроси администратора бота.", nil
+	}
+
+	settings.Thinking = enabled
+	if err := h.settingsRepo.SaveSettings(settings); err != nil {
+		return "", err
+	}
+
+	if enabled {
+		return "🧠 Размышления включены: ответы станут точнее в задачах и планировании, но будут приходить дольше.", nil
+	}
+	return "💬 Размышления выключены.", nil
+}
+
+func (h *CommandHandler) HandleMessage(ctx context.Context, cmd commands.ProcessMessageCommand) (Reply, error) {
+	h.logger.Info("Handling message", zap.Int64("chatID", cmd.ChatID), zap.Int64("userID", cmd.UserID))
+
+	ctx = withCaller(ctx, cmd.ChatID, cmd.UserID)
+	if cmd.Think {
+		ctx = services.WithExtendedThinking(ctx)
+	}
+
+	session, err := h.resolveSession(cmd.ChatID, cmd.UserID)
+	if err != nil {
+		return Reply{}, err
+	}
+
 	if !session.IsActive {
-		return "ℹ️ Сессия не активна. Используй /begin_chat чтобы начать общение.", nil
+		return notice("ℹ️ Сессия не активна. Используй /begin_chat чтобы начать общение."), nil
 	}
 
-	session.AddMessage("user", cmd.Message)
+	session.AddMessage("user", userContent(session, cmd.DisplayName, cmd.Message))
+	session.LastMessage().TelegramMessageID = cmd.MessageID
 
-	if session.GetContextSize() > MaxContextSize {
+	if session.GetContextSize() > h.settings.MaxContextSize() {
+		overflowed := session.Clone()
 		session.Reset()
 		if err := h.sessionRepo.SaveSession(session); err != nil {
-			return "", err
+			return Reply{}, err
 		}
-		return "⚠️ Контекст стал слишком большим и был очищен. Пожалуйста, повтори свой вопрос.", nil
+		h.archiveSession(overflowed)
+		return notice("⚠️ Контекст стал слишком большим и был очищен. Пожалуйста, повтори свой вопрос."), nil
 	}
 
 	// Генерируем ответ
-	response, err := h.claudeService.GenerateResponse(session.Messages)
+	response, err := h.claudeService.GenerateResponse(ctx, session.Messages)
 	if err != nil {
 		h.logger.Error("Failed to generate response", zap.Error(err))
-		return "😔 Произошла ошибка при генерации ответа. Попробуй позже.", nil
+		return notice("😔 Произошла ошибка при генерации ответа. Попробуй позже."), nil
 	}
 
 	// Добавляем ответ ассистента
-	session.AddMessage("assistant", response)
+	session.AddMessage("assistant", response.Text)
+	session.LastMessage().Truncated = response.Truncated()
+	session.LastMessage().ToolCalls = response.ToolCalls
+	session.LastMessage().Model = response.Model
+	session.LastMessage().Thinking = response.Thinking
+
+	// Вопрос и ответ дописываются в конец истории, не переписывая её
+	turn := session.Messages[len(session.Messages)-2:]
+	if err := h.sessionRepo.AppendMessage(session, turn...); err != nil {
+		if errors.Is(err, repositories.ErrSessionChanged) {
+			// Сессию завершили или начали заново, пока готовился ответ: ответ
+			// показываем, но в новую историю он не попадает
+			h.logger.Info("Session changed while answering, turn not stored",
+				zap.Int64("chatID", cmd.ChatID),
+				zap.Int64("userID", cmd.UserID))
+			return notice(response.Text), nil
+		}
+		return Reply{}, err
+	}
 
-	if err := h.sessionRepo.SaveSession(session); err != nil {
+	return Reply{Text: response.Text, Answered: true, Revision: session.Revision}, nil
+}
+
+// withCaller tells tools and the Claude client which chat and user the answer
+// is prepared for. In shared sessions this is the user who is speaking.
+func withCaller(ctx context.Context, chatID, userID int64) context.Context {
+	return tools.WithCaller(ctx, tools.Caller{
+		ChatID: chatID,
+		UserID: userID,
+	})
+}
+
+// userContent builds the stored text of a user turn
+func userContent(session *entities.ChatSession, displayName, text string) string {
+	if session.IsShared() {
+		// В общей сессии Claude должен понимать, кто из участников говорит
+		return fmt.Sprintf("%s: %s", displayName, text)
+	}
+	return text
+}
+
+// isLatestReply reports whether the Telegram message holds the last assistant
+// reply of the session. A reply without a stored ID matches no message: in a
+// group the button may belong to another member's session.
+func isLatestReply(session *entities.ChatSession, messageID int) bool {
+	last := session.LastMessage()
+	if last == nil || last.Role != "assistant" || last.TelegramMessageID == 0 {
+		return false
+	}
+	return last.TelegramMessageID == messageID
+}
+
+// HandleRegenerate replaces the last assistant reply with a freshly generated one
+func (h *CommandHandler) HandleRegenerate(ctx context.Context, cmd commands.RegenerateCommand) (string, error) {
+	h.logger.Info("Handling regenerate", zap.Int64("chatID", cmd.ChatID), zap.Int64("userID", cmd.UserID))
+
+	ctx = withCaller(ctx, cmd.ChatID, cmd.UserID)
+
+	session, err := h.resolveSession(cmd.ChatID, cmd.UserID)
+	if err != nil {
 		return "", err
 	}
 
+	if !session.IsActive || !isLatestReply(session, cmd.MessageID) {
+		return "", ErrNothingToRegenerate
+	}
+
+	session.RemoveLastMessage()
+
+	// Ответ остаётся в том же сообщении, которое бот отредактирует.
+	// Если за время генерации сессия изменилась, ответ не сохраняется.
+	response, err := h.generateAndSave(ctx, session, cmd.MessageID)
+	if err != nil {
+		return "", fmt.Errorf("failed to regenerate response: %w", err)
+	}
+
+	return response.Text, nil
+}
+
+// generateAndSave generates the next assistant turn and stores it in the
+// session. replyMessageID is the Telegram message holding the reply, if known.
+func (h *CommandHandler) generateAndSave(ctx context.Context, session *entities.ChatSession, replyMessageID int) (*services.Response, error) {
+	response, err := h.claudeService.GenerateResponse(ctx, session.Messages)
+	if err != nil {
+		return nil, err
+	}
+
+	session.AddMessage("assistant", response.Text)
+	session.LastMessage().Truncated = response.Truncated()
+	session.LastMessage().ToolCalls = response.ToolCalls
+	session.LastMessage().Model = response.Model
+	session.LastMessage().Thinking = response.Thinking
+	session.LastMessage().TelegramMessageID = replyMessageID
+
+	if err := h.sessionRepo.SaveSession(session); err != nil {
+		return nil, err
+	}
+
 	return response, nil
 }
+
+// HandleUndo removes the last question and answer from the session context
+func (h *CommandHandler) HandleUndo(ctx context.Context, cmd commands.UndoCommand) (string, error) {
+	h.logger.Info("Handling undo command", zap.Int64("chatID", cmd.ChatID), zap.Int64("userID", cmd.UserID))
+
+	return retryOnConflict(func() (string, error) {
+		session, err := h.resolveSession(cmd.ChatID, cmd.UserID)
+		if err != nil {
+			return "", err
+		}
+
+		if !session.IsActive {
+			return "ℹ️ Сессия не активна. Используй /begin_chat чтобы начать общение.", nil
+		}
+
+		if err := session.UndoLastExchange(); err != nil {
+			if errors.Is(err, entities.ErrNothingToUndo) {
+				return "ℹ️ Отменять нечего: история пуста.", nil
+			}
+			return "", err
+		}
+
+		if err := h.sessionRepo.SaveSession(session); err != nil {
+			return "", err
+		}
+
+		return "↩️ Последний вопрос и ответ удалены из контекста.", nil
+	})
+}
+
+// HandleRetry sends the last user message to Claude again
+func (h *CommandHandler) HandleRetry(ctx context.Context, cmd commands.RetryCommand) (Reply, error) {
+	h.logger.Info("Handling retry command", zap.Int64("chatID", cmd.ChatID), zap.Int64("userID", cmd.UserID))
+
+	ctx = withCaller(ctx, cmd.ChatID, cmd.UserID)
+
+	session, err := h.resolveSession(cmd.ChatID, cmd.UserID)
+	if err != nil {
+		return Reply{}, err
+	}
+
+	if !session.IsActive {
+		return notice("ℹ️ Сессия не активна. Используй /begin_chat чтобы начать общение."), nil
+	}
+
+	if _, err := session.RewindToLastUserMessage(); err != nil {
+		if errors.Is(err, entities.ErrNothingToRetry) {
+			return notice("ℹ️ Повторять нечего: в сессии ещё нет вопросов."), nil
+		}
+		return Reply{}, err
+	}
+
+	response, err := h.generateAndSave(ctx, session, 0)
+	if errors.Is(err, repositories.ErrSessionChanged) {
+		return notice("ℹ️ Пока готовился ответ, разговор изменился. Попробуй ещё раз."), nil
+	}
+	if err != nil {
+		h.logger.Error("Failed to generate response", zap.Error(err))
+		return notice("😔 Произошла ошибка при генерации ответа. Попробуй позже."), nil
+	}
+
+	return Reply{Text: response.Text, Answered: true, Revision: session.Revision}, nil
+}
+
+// HandleContinue asks Claude to continue a reply cut off by the token limit.
+// The continuation is appended to the stored reply and returned on its own.
+func (h *CommandHandler) HandleContinue(ctx context.Context, cmd commands.ContinueCommand) (Reply, error) {
+	h.logger.Info("Handling continue", zap.Int64("chatID", cmd.ChatID), zap.Int64("userID", cmd.UserID))
+
+	ctx = withCaller(ctx, cmd.ChatID, cmd.UserID)
+
+	session, err := h.resolveSession(cmd.ChatID, cmd.UserID)
+	if err != nil {
+		return Reply{}, err
+	}
+
+	if !session.IsActive || !isLatestReply(session, cmd.MessageID) || !session.LastMessage().Truncated {
+		return Reply{}, ErrNothingToContinue
+	}
+
+	// Последнее сообщение ассистента служит префиксом, Claude продолжит его
+	response, err := h.claudeService.GenerateResponse(ctx, session.Messages)
+	if err != nil {
+		return Reply{}, fmt.Errorf("failed to continue response: %w", err)
+	}
+
+	// Продолжение уходит отдельным сообщением, его ID запишет бот после отправки
+	last := session.LastMessage()
+	last.Content = strings.TrimRight(last.Content, " \t\n") + response.Text
+	last.Truncated = response.Truncated()
+	last.ToolCalls = append(last.ToolCalls, response.ToolCalls...)
+	last.Model = response.Model
+
+	// Если за время генерации в сессию дописали другой ход, продолжение не
+	// сохраняется: иначе оно попало бы не в тот ответ
+	if err := h.sessionRepo.UpdateLastMessage(session); err != nil {
+		return Reply{}, fmt.Errorf("failed to store continuation: %w", err)
+	}
+
+	return Reply{Text: response.Text, Answered: true, Revision: session.Revision}, nil
+}
+
+// HandleEditedMessage regenerates the answer to a user message that was edited
+// in Telegram. The history is truncated at that message and continues from the
+// edited text. It returns the new answer and the Telegram ID of the old reply.
+func (h *CommandHandler) HandleEditedMessage(ctx context.Context, cmd commands.EditMessageCommand) (Reply, int, error) {
+	h.logger.Info("Handling edited message",
+		zap.Int64("chatID", cmd.ChatID),
+		zap.Int64("userID", cmd.UserID),
+		zap.Int("messageID", cmd.MessageID))
+
+	ctx = withCaller(ctx, cmd.ChatID, cmd.UserID)
+
+	session, err := h.resolveSession(cmd.ChatID, cmd.UserID)
+	if err != nil {
+		return Reply{}, 0, err
+	}
+
+	if !session.IsActive {
+		return Reply{}, 0, ErrMessageNotInHistory
+	}
+
+	replyID, err := session.RewindToMessage(cmd.MessageID)
+	if err != nil {
+		if errors.Is(err, entities.ErrMessageNotFound) {
+			return Reply{}, 0, ErrMessageNotInHistory
+		}
+		return Reply{}, 0, err
+	}
+
+	session.AddMessage("user", userContent(session, cmd.DisplayName, cmd.Message))
+	session.LastMessage().TelegramMessageID = cmd.MessageID
+
+	// Новый ответ заменит старый в том же сообщении
+	response, err := h.generateAndSave(ctx, session, replyID)
+	if err != nil {
+		return Reply{}, 0, fmt.Errorf("failed to regenerate edited answer: %w", err)
+	}
+
+	return Reply{Text: response.Text, Answered: true, Revision: session.Revision}, replyID, nil
+}
+
+// HandleKeepSession restarts the idle timeout of the session after an expiry warning
+func (h *CommandHandler) HandleKeepSession(ctx context.Context, cmd commands.KeepSessionCommand) (string, error) {
+	h.logger.Info("Handling keep session", zap.Int64("chatID", cmd.ChatID), zap.Int64("userID", cmd.UserID))
+
+	return retryOnConflict(func() (string, error) {
+		session, err := h.resolveSession(cmd.ChatID, cmd.UserID)
+		if err != nil {
+			return "", err
+		}
+
+		if !session.IsActive {
+			return "ℹ️ Сессия уже завершена. Начать новую: /begin_chat", nil
+		}
+
+		// История не переписывается, меняется только время активности
+		session.Touch()
+		if err := h.sessionRepo.UpdateActivity(session); err != nil {
+			return "", err
+		}
+
+		return "✅ Сессия продлена, продолжаем разговор.", nil
+	})
+}
+
+// HandleRecordReply links the stored answer to the Telegram message it was
+// sent in, so later edits and buttons can find it. Nothing is recorded if the
+// session changed after the answer was stored: the last message may then be
+// another member's turn.
+func (h *CommandHandler) HandleRecordReply(ctx context.Context, cmd commands.RecordReplyCommand) error {
+	session, err := h.resolveSession(cmd.ChatID, cmd.UserID)
+	if err != nil {
+		return err
+	}
+
+	last := session.LastMessage()
+	if !session.IsActive || session.Revision != cmd.Revision || last == nil || last.Role != "assistant" {
+		return nil
+	}
+
+	last.TelegramMessageID = cmd.MessageID
+	if err := h.sessionRepo.UpdateLastMessage(session); err != nil && !errors.Is(err, repositories.ErrSessionChanged) {
+		return err
+	}
+	return nil
+}
+
+// HandleInlineQuery answers an inline query with a one-shot reply. No session
+// is read or stored. Answers are cached per user and query. The query has no
+// chat, so no memory is added and personal data is always masked.
+func (h *CommandHandler) HandleInlineQuery(ctx context.Context, cmd commands.InlineQueryCommand) (string, error) {
+	if text, ok := h.inlineAnswers.get(cmd.UserID, cmd.Query, time.Now()); ok {
+		return text, nil
+	}
+
+	h.logger.Info("Handling inline query", zap.Int64("userID", cmd.UserID))
+
+	ctx, cancel := context.WithTimeout(ctx, InlineQueryTimeout)
+	defer cancel()
+
+	response, err := h.claudeService.GenerateResponse(ctx, []entities.Message{
+		{Role: "user", Content: cmd.Query, Timestamp: time.Now()},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to answer inline query: %w", err)
+	}
+
+	h.inlineAnswers.put(cmd.UserID, cmd.Query, response.Text, time.Now())
+	return response.Text, nil
+}
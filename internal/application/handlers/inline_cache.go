@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// inlineAnswerTTL keeps one-shot answers as long as Telegram caches inline results
+const inlineAnswerTTL = 5 * time.Minute
+
+type inlineAnswer struct {
+	text      string
+	expiresAt time.Time
+}
+
+// inlineAnswerCache remembers inline answers by user and query, so a query
+// that Telegram sends again while the user types costs one model call
+type inlineAnswerCache struct {
+	answers map[string]inlineAnswer
+	mutex   sync.Mutex
+}
+
+func newInlineAnswerCache() *inlineAnswerCache {
+	return &inlineAnswerCache{answers: make(map[string]inlineAnswer)}
+}
+
+func (c *inlineAnswerCache) key(userID int64, query string) string {
+	return fmt.Sprintf("%d:%s", userID, query)
+}
+
+func (c *inlineAnswerCache) get(userID int64, query string, now time.Time) (string, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	answer, ok := c.answers[c.key(userID, query)]
+	if !ok || !now.Before(answer.expiresAt) {
+		return "", false
+	}
+	return answer.text, true
+}
+
+func (c *inlineAnswerCache) put(userID int64, query, text string, now time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// Устаревшие ответы вычищаются при записи, отдельной горутины не нужно
+	for key, answer := range c.answers {
+		if !now.Before(answer.expiresAt) {
+			delete(c.answers, key)
+		}
+	}
+
+	c.answers[c.key(userID, query)] = inlineAnswer{text: text, expiresAt: now.Add(inlineAnswerTTL)}
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"telegram-chatbot/internal/domain/commands"
+	"telegram-chatbot/internal/domain/entities"
+	"telegram-chatbot/internal/domain/repositories"
+
+	"go.uber.org/zap"
+)
+
+const listUsage = `ℹ️ Использование:
+/list - показать список покупок
+/list add молоко, хлеб - добавить пункты
+/list done 3 - отметить пункт выполненным
+/list clear - убрать отмеченные пункты
+/list дела add позвонить врачу - работа с другим списком`
+
+var listActions = map[string]string{
+	"add":      "add",
+	"добавить": "add",
+	"show":     "show",
+	"показать": "show",
+	"done":     "done",
+	"готово":   "done",
+	"clear":    "clear",
+	"очистить": "clear",
+}
+
+type ListHandler struct {
+	listRepo repositories.ListRepository
+	logger   *zap.Logger
+}
+
+func NewListHandler(listRepo repositories.ListRepository, logger *zap.Logger) *ListHandler {
+	return &ListHandler{
+		listRepo: listRepo,
+		logger:   logger,
+	}
+}
+
+// HandleList runs a /list subcommand and returns the reply along with the list
+// it concerns, so the bot can attach the checkbox keyboard
+func (h *ListHandler) HandleList(ctx context.Context, cmd commands.ListCommand) (string, *entities.FamilyList, error) {
+	h.logger.Info("Handling list command", zap.Int64("chatID", cmd.ChatID), zap.Int64("userID", cmd.UserID))
+
+	name, action, rest := parseListArgs(cmd.Args)
+	if action == "" {
+		return listUsage, nil, nil
+	}
+
+	if err := entities.ValidateListName(name); err != nil {
+		return "⚠️ Название списка слишком длинное, выбери покороче.", nil, nil
+	}
+
+	list, err := h.listRepo.GetList(cmd.ChatID, name)
+	if err != nil {
+		return "", nil, err
+	}
+
+	switch action {
+	case "add":
+		added := list.AddItems(strings.Split(rest, ","), cmd.DisplayName)
+		if added == 0 {
+			return "ℹ️ Напиши, что добавить: /list add молоко, хлеб", nil, nil
+		}
+	case "done":
+		for _, field := range strings.FieldsFunc(rest, func(r rune) bool { return r == ',' || r == ' ' }) {
+			number, err := strconv.Atoi(field)
+			if err == nil {
+				err = list.SetDone(number, true)
+			}
+			if err != nil {
+				return fmt.Sprintf("⚠️ В списке нет пункта %q.", field), list, nil
+			}
+		}
+	case "clear":
+		list.RemoveDone()
+	case "show":
+		return h.formatList(list), list, nil
+	}
+
+	if err := h.listRepo.SaveList(list); err != nil {
+		return "", nil, err
+	}
+
+	return h.formatList(list), list, nil
+}
+
+// HandleToggleListItem flips the done mark of an item from the inline keyboard
+func (h *ListHandler) HandleToggleListItem(ctx context.Context, cmd commands.ToggleListItemCommand) (string, *entities.FamilyList, error) {
+	h.logger.Info("Handling toggle list item",
+		zap.Int64("chatID", cmd.ChatID),
+		zap.String("list", cmd.ListName),
+		zap.Int("number", cmd.Number))
+
+	list, err := h.listRepo.GetList(cmd.ChatID, cmd.ListName)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Если пункт уже удалён, просто показываем актуальный список
+	if err := list.ToggleDone(cmd.Number); err == nil {
+		if err := h.listRepo.SaveList(list); err != nil {
+			return "", nil, err
+		}
+	}
+
+	return h.formatList(list), list, nil
+}
+
+func (h *ListHandler) HandleClearList(ctx context.Context, cmd commands.ClearListCommand) (string, *entities.FamilyList, error) {
+	h.logger.Info("Handling clear list", zap.Int64("chatID", cmd.ChatID), zap.String("list", cmd.ListName))
+
+	list, err := h.listRepo.GetList(cmd.ChatID, cmd.ListName)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if list.RemoveDone() > 0 {
+		if err := h.listRepo.SaveList(list); err != nil {
+			return "", nil, err
+		}
+	}
+
+	return h.formatList(list), list, nil
+}
+
+// parseListArgs splits "[name] action rest". Without an explicit action the
+// list is shown.
+func parseListArgs(args string) (name, action, rest string) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		return entities.DefaultListName, "show", ""
+	}
+
+	if action, ok := listActions[strings.ToLower(fields[0])]; ok {
+		return entities.DefaultListName, action, strings.Join(fields[1:], " ")
+	}
+
+	name = entities.NormalizeListName(fields[0])
+	if len(fields) == 1 {
+		return name, "show", ""
+	}
+
+	action, ok := listActions[strings.ToLower(fields[1])]
+	if !ok {
+		return name, "", ""
+	}
+	return name, action, strings.Join(fields[2:], " ")
+}
+
+func (h *ListHandler) formatList(list *entities.FamilyList) string {
+	if len(list.Items) == 0 {
+		return fmt.Sprintf("📝 Список «%s» пуст. Добавь пункты: /list add молоко, хлеб", list.Name)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("📝 Список «%s»:\n", list.Name))
+	for i, item := range list.Items {
+		mark := "☐"
+		if item.Done {
+			mark = "☑"
+		}
+		sb.WriteString(fmt.Sprintf("%d. %s %s\n", i+1, mark, item.Text))
+	}
+	sb.WriteString("\nНажми на пункт, чтобы отметить его.")
+
+	return sb.String()
+}
@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"telegram-chatbot/internal/domain/commands"
+	"telegram-chatbot/internal/domain/entities"
+	"telegram-chatbot/internal/domain/repositories"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// MaxMemoriesPerChat limits facts visible to a user in a chat
+	MaxMemoriesPerChat = 50
+	// MaxFactLength keeps facts short, they are sent with every request
+	MaxFactLength = 300
+)
+
+// chatWideMemoryPrefixes mark a /remember fact as belonging to the whole chat
+var chatWideMemoryPrefixes = []string{"всем ", "всем:", "chat "}
+
+type MemoryHandler struct {
+	memoryRepo repositories.MemoryRepository
+	logger     *zap.Logger
+}
+
+func NewMemoryHandler(memoryRepo repositories.MemoryRepository, logger *zap.Logger) *MemoryHandler {
+	return &MemoryHandler{
+		memoryRepo: memoryRepo,
+		logger:     logger,
+	}
+}
+
+func (h *MemoryHandler) HandleRemember(ctx context.Context, cmd commands.RememberCommand) (string, error) {
+	h.logger.Info("Handling remember command", zap.Int64("chatID", cmd.ChatID), zap.Int64("userID", cmd.UserID))
+
+	fact := strings.TrimSpace(cmd.Fact)
+	userID := cmd.UserID
+	for _, prefix := range chatWideMemoryPrefixes {
+		if strings.HasPrefix(strings.ToLower(fact), prefix) {
+			fact = strings.TrimSpace(fact[len(prefix):])
+			userID = entities.SharedSessionUserID
+			break
+		}
+	}
+
+	if fact == "" {
+		return "ℹ️ Использование: /remember <факт>\nНапример: /remember у Маши аллергия на орехи\nДля всей семьи: /remember всем <факт>", nil
+	}
+	if len([]rune(fact)) > MaxFactLength {
+		return fmt.Sprintf("⚠️ Факт слишком длинный, уложись в %d символов.", MaxFactLength), nil
+	}
+
+	memories, err := h.memoryRepo.ListMemories(cmd.ChatID, cmd.UserID)
+	if err != nil {
+		return "", err
+	}
+	if len(memories) >= MaxMemoriesPerChat {
+		return "⚠️ Я уже помню слишком много. Удали ненужное через /memories и /forget.", nil
+	}
+
+	memory := &entities.Memory{
+		ID:        entities.NewID(),
+		ChatID:    cmd.ChatID,
+		UserID:    userID,
+		Fact:      fact,
+		CreatedAt: time.Now(),
+	}
+	if err := h.memoryRepo.SaveMemory(memory); err != nil {
+		return "", err
+	}
+
+	if memory.IsChatWide() {
+		return "🧠 Запомнил для всей семьи: " + fact, nil
+	}
+	return "🧠 Запомнил: " + fact, nil
+}
+
+func (h *MemoryHandler) HandleListMemories(ctx context.Context, cmd commands.ListMemoriesCommand) (string, error) {
+	h.logger.Info("Handling memories command", zap.Int64("chatID", cmd.ChatID), zap.Int64("userID", cmd.UserID))
+
+	memories, err := h.confirmedMemories(cmd.ChatID, cmd.UserID)
+	if err != nil {
+		return "", err
+	}
+
+	if len(memories) == 0 {
+		return "🧠 Я пока ничего не запомнил. Расскажи мне что-нибудь через /remember.", nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString("🧠 Что я помню:\n")
+	for i, memory := range memories {
+		marker := ""
+		if memory.IsChatWide() {
+			marker = " 👥"
+		}
+		sb.WriteString(fmt.Sprintf("%d. %s%s\n", i+1, memory.Fact, marker))
+	}
+	sb.WriteString("\n👥 — факт для всей семьи. Удалить: /forget <номер>")
+
+	return sb.String(), nil
+}
+
+func (h *MemoryHandler) HandleForget(ctx context.Context, cmd commands.ForgetCommand) (string, error) {
+	h.logger.Info("Handling forget command", zap.Int64("chatID", cmd.ChatID), zap.Int64("userID", cmd.UserID))
+
+	fields := strings.FieldsFunc(cmd.Args, func(r rune) bool { return r == ',' || r == ' ' })
+	if len(fields) == 0 {
+		return "ℹ️ Использование: /forget <номер из /memories>", nil
+	}
+
+	memories, err := h.confirmedMemories(cmd.ChatID, cmd.UserID)
+	if err != nil {
+		return "", err
+	}
+
+	// Сначала проверяем все номера, чтобы не удалить часть фактов при опечатке
+	toDelete := make([]entities.Memory, 0, len(fields))
+	for _, field := range fields {
+		number, err := strconv.Atoi(field)
+		if err != nil || number < 1 || number > len(memories) {
+			return fmt.Sprintf("⚠️ Нет факта с номером %q. Посмотри список: /memories", field), nil
+		}
+		toDelete = append(toDelete, memories[number-1])
+	}
+
+	for _, memory := range toDelete {
+		if _, err := h.memoryRepo.DeleteMemory(cmd.ChatID, memory.ID); err != nil {
+			return "", err
+		}
+	}
+
+	return fmt.Sprintf("🗑 Забыл фактов: %d.", len(toDelete)), nil
+}
+
+// HandleTakeMemoryProposals returns facts Claude proposed that the user has
+// not been asked about yet, and marks them as asked
+func (h *MemoryHandler) HandleTakeMemoryProposals(ctx context.Context, cmd commands.TakeMemoryProposalsCommand) ([]entities.Memory, error) {
+	memories, err := h.memoryRepo.ListMemories(cmd.ChatID, cmd.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	var proposals []entities.Memory
+	for _, memory := range memories {
+		if !memory.Pending || memory.Announced {
+			continue
+		}
+
+		memory.Announced = true
+		if err := h.memoryRepo.SaveMemory(&memory); err != nil {
+			return nil, err
+		}
+		proposals = append(proposals, memory)
+	}
+
+	return proposals, nil
+}
+
+// HandleConfirmMemory keeps or discards a fact proposed by Claude
+func (h *MemoryHandler) HandleConfirmMemory(ctx context.Context, cmd commands.ConfirmMemoryCommand) (string, error) {
+	h.logger.Info("Handling memory confirmation",
+		zap.Int64("chatID", cmd.ChatID),
+		zap.Int64("userID", cmd.UserID),
+		zap.Bool("accept", cmd.Accept))
+
+	memories, err := h.memoryRepo.ListMemories(cmd.ChatID, cmd.UserID)
+	if err != nil {
+		return "", err
+	}
+
+	for _, memory := range memories {
+		if memory.ID != cmd.MemoryID || !memory.Pending {
+			continue
+		}
+
+		if !cmd.Accept {
+			if _, err := h.memoryRepo.DeleteMemory(cmd.ChatID, memory.ID); err != nil {
+				return "", err
+			}
+			return "👌 Не буду запоминать: " + memory.Fact, nil
+		}
+
+		memory.Pending = false
+		if err := h.memoryRepo.SaveMemory(&memory); err != nil {
+			return "", err
+		}
+		return "🧠 Запомнил: " + memory.Fact, nil
+	}
+
+	return "ℹ️ Это предложение уже неактуально.", nil
+}
+
+func (h *MemoryHandler) confirmedMemories(chatID, userID int64) ([]entities.Memory, error) {
+	memories, err := h.memoryRepo.ListMemories(chatID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	confirmed := memories[:0]
+	for _, memory := range memories {
+		if !memory.Pending {
+			confirmed = append(confirmed, memory)
+		}
+	}
+	return confirmed, nil
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"telegram-chatbot/internal/domain/commands"
+	"telegram-chatbot/internal/domain/entities"
+	"telegram-chatbot/internal/domain/repositories"
+
+	"go.uber.org/zap"
+)
+
+// DefaultModelAlias resets the chat to the model configured by the admin
+const DefaultModelAlias = "default"
+
+type ModelHandler struct {
+	settingsRepo repositories.ChatSettingsRepository
+	catalog      entities.ModelCatalog
+	logger       *zap.Logger
+}
+
+func NewModelHandler(settingsRepo repositories.ChatSettingsRepository, catalog entities.ModelCatalog, logger *zap.Logger) *ModelHandler {
+	return &ModelHandler{
+		settingsRepo: settingsRepo,
+		catalog:      catalog,
+		logger:       logger,
+	}
+}
+
+// HandleModel shows the models the chat may use or switches to the chosen one.
+// The catalog is returned only when the choice is shown, for the keyboard.
+func (h *ModelHandler) HandleModel(ctx context.Context, cmd commands.ModelCommand) (string, entities.ModelCatalog, error) {
+	h.logger.Info("Handling model command",
+		zap.Int64("chatID", cmd.ChatID),
+		zap.Int64("userID", cmd.UserID),
+		zap.String("alias", cmd.Alias))
+
+	if len(h.catalog) == 0 {
+		return "ℹ️ Выбор модели не настроен администратором бота.", nil, nil
+	}
+
+	settings, err := h.settingsRepo.GetSettings(cmd.ChatID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if cmd.Alias == "" {
+		return h.describeChoice(settings.Model), h.catalog, nil
+	}
+
+	var choice string
+	if !strings.EqualFold(cmd.Alias, DefaultModelAlias) {
+		option, ok := h.catalog.Find(cmd.Alias)
+		if !ok {
+			return fmt.Sprintf("❓ Модели «%s» нет в списке. Доступны: %s", cmd.Alias, h.aliases()), nil, nil
+		}
+		choice = option.Alias
+	}
+
+	if cmd.IsGroup && !cmd.IsAdmin {
+		return "🚫 Менять модель в группе может только администратор.", nil, nil
+	}
+
+	settings.Model = choice
+	if err := h.settingsRepo.SaveSettings(settings); err != nil {
+		return "", nil, err
+	}
+
+	if choice == "" {
+		return "🤖 Снова отвечает модель по умолчанию.", nil, nil
+	}
+	option, _ := h.catalog.Find(choice)
+	return fmt.Sprintf("🤖 Теперь отвечает %s (%s).", option.Alias, option.Model), nil, nil
+}
+
+// describeChoice lists the catalog and marks the model the chat uses now
+func (h *ModelHandler) describeChoice(current string) string {
+	var text strings.Builder
+	text.WriteString("🤖 Модели для этого чата:\n")
+
+	// Псевдоним мог пропасть из списка после изменения настроек
+	_, known := h.catalog.Find(current)
+	for _, option := range h.catalog {
+		marker := "▫️"
+		if known && option.Alias == current {
+			marker = "✅"
+		}
+		fmt.Fprintf(&text, "%s %s - %s\n", marker, option.Alias, option.Model)
+	}
+
+	if !known {
+		text.WriteString("\nСейчас отвечает модель по умолчанию.")
+	}
+	text.WriteString("\nВыбери модель кнопкой или командой /model <название>, вернуть модель по умолчанию: /model default")
+
+	return text.String()
+}
+
+func (h *ModelHandler) aliases() string {
+	aliases := make([]string, 0, len(h.catalog))
+	for _, option := range h.catalog {
+		aliases = append(aliases, option.Alias)
+	}
+	return strings.Join(aliases, ", ")
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"telegram-chatbot/internal/domain/commands"
+	"telegram-chatbot/internal/domain/entities"
+	"telegram-chatbot/internal/domain/repositories"
+	"telegram-chatbot/internal/domain/services"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// MaxRemindersPerUser limits pending reminders of a single user in a chat
+const MaxRemindersPerUser = 20
+
+type ReminderHandler struct {
+	reminderRepo   repositories.ReminderRepository
+	reminderParser services.ReminderParser
+	location       *time.Location
+	logger         *zap.Logger
+}
+
+func NewReminderHandler(
+	reminderRepo repositories.ReminderRepository,
+	reminderParser services.ReminderParser,
+	location *time.Location,
+	logger *zap.Logger,
+) *ReminderHandler {
+	return &ReminderHandler{
+		reminderRepo:   reminderRepo,
+		reminderParser: reminderParser,
+		location:       location,
+		logger:         logger,
+	}
+}
+
+func (h *ReminderHandler) HandleRemind(ctx context.Context, cmd commands.RemindCommand) (string, error) {
+	h.logger.Info("Handling remind command", zap.Int64("chatID", cmd.ChatID), zap.Int64("userID", cmd.UserID))
+
+	request := strings.TrimSpace(cmd.Request)
+	if request == "" {
+		return "ℹ️ Использование: /remind <что и когда>\nНапример: /remind забрать детей в 5", nil
+	}
+
+	pending, err := h.reminderRepo.ListReminders(cmd.ChatID, cmd.UserID)
+	if err != nil {
+		return "", err
+	}
+	if len(pending) >= MaxRemindersPerUser {
+		return fmt.Sprintf("⚠️ У тебя уже %d напоминаний. Отмени ненужные через /reminders.", len(pending)), nil
+	}
+
+	// Настройки чата решают, маскировать ли личные данные в тексте напоминания
+	ctx = withCaller(ctx, cmd.ChatID, cmd.UserID)
+
+	now := time.Now()
+	parsed, err := h.reminderParser.ParseReminder(ctx, request, now)
+	if err != nil {
+		if errors.Is(err, services.ErrReminderNotUnderstood) {
+			return "🤔 Не понял, когда напомнить. Попробуй так: /remind позвонить бабушке завтра в 10:00", nil
+		}
+		h.logger.Error("Failed to parse reminder", zap.Error(err))
+		return "😔 Не удалось разобрать напоминание. Попробуй позже.", nil
+	}
+
+	if !parsed.DueAt.After(now) {
+		return "⚠️ Это время уже прошло. Укажи время в будущем.", nil
+	}
+
+	reminder := &entities.Reminder{
+		ID:          entities.NewID(),
+		ChatID:      cmd.ChatID,
+		UserID:      cmd.UserID,
+		DisplayName: cmd.DisplayName,
+		Text:        parsed.Text,
+		DueAt:       parsed.DueAt,
+		CreatedAt:   now,
+	}
+
+	if err := h.reminderRepo.SaveReminder(reminder); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("⏰ Напомню %s: %s", h.formatTime(reminder.DueAt), reminder.Text), nil
+}
+
+// HandleListReminders returns the pending reminders of the user along with their description
+func (h *ReminderHandler) HandleListReminders(ctx context.Context, cmd commands.ListRemindersCommand) (string, []entities.Reminder, error) {
+	h.logger.Info("Handling reminders command", zap.Int64("chatID", cmd.ChatID), zap.Int64("userID", cmd.UserID))
+
+	reminders, err := h.reminderRepo.ListReminders(cmd.ChatID, cmd.UserID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if len(reminders) == 0 {
+		return "📭 Напоминаний нет. Создай новое командой /remind.", nil, nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString("⏰ Твои напоминания:\n")
+	for i, reminder := range reminders {
+		sb.WriteString(fmt.Sprintf("%d. %s — %s\n", i+1, h.formatTime(reminder.DueAt), reminder.Text))
+	}
+	sb.WriteString("\nНажми на номер, чтобы отменить напоминание.")
+
+	return sb.String(), reminders, nil
+}
+
+func (h *ReminderHandler) HandleCancelReminder(ctx context.Context, cmd commands.CancelReminderCommand) (string, error) {
+	h.logger.Info("Handling cancel reminder",
+		zap.Int64("chatID", cmd.ChatID),
+		zap.Int64("userID", cmd.UserID),
+		zap.String("reminderID", cmd.ReminderID))
+
+	deleted, err := h.reminderRepo.DeleteReminder(cmd.ChatID, cmd.UserID, cmd.ReminderID)
+	if err != nil {
+		return "", err
+	}
+
+	if !deleted {
+		return "ℹ️ Напоминание уже сработало или было отменено.", nil
+	}
+
+	return "🗑 Напоминание отменено.", nil
+}
+
+// formatTime prints a reminder time in the family's time zone
+func (h *ReminderHandler) formatTime(t time.Time) string {
+	return t.In(h.location).Format("02.01.2006 15:04")
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"telegram-chatbot/internal/domain/commands"
+	"telegram-chatbot/internal/domain/entities"
+	"telegram-chatbot/internal/domain/search"
+
+	"go.uber.org/zap"
+)
+
+// MaxSearchResults caps the number of conversations shown by /search
+const MaxSearchResults = 5
+
+// SearchSnippetLength is the length of a quoted fragment in search results
+const SearchSnippetLength = 160
+
+// HandleSearch looks the query up in the user's and the chat-wide archived
+// conversations. The matches are returned so the bot can offer to reopen them.
+func (h *CommandHandler) HandleSearch(ctx context.Context, cmd commands.SearchCommand) (string, []entities.ConversationMatch, error) {
+	h.logger.Info("Handling search command", zap.Int64("chatID", cmd.ChatID), zap.Int64("userID", cmd.UserID))
+
+	query := strings.TrimSpace(cmd.Query)
+	terms := search.UniqueTerms(query)
+	if len(terms) == 0 {
+		return "ℹ️ Использование: /search <что искать>, например: /search рецепт пирога", nil, nil
+	}
+
+	matches, err := h.archiveRepo.Search(cmd.ChatID, cmd.UserID, query, MaxSearchResults)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if len(matches) == 0 {
+		return "🔎 В прошлых разговорах ничего не нашлось.", nil, nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString("🔎 Нашлось в прошлых разговорах:\n")
+	for i, match := range matches {
+		conversation := match.Conversation
+		fmt.Fprintf(&sb, "\n%d. 📅 %s", i+1, conversation.StartedAt.In(h.location).Format("02.01.2006 15:04"))
+		if conversation.UserID == entities.SharedSessionUserID {
+			sb.WriteString(" (общий)")
+		}
+		fmt.Fprintf(&sb, "\n«%s»\n", bestSnippet(conversation, terms))
+	}
+	sb.WriteString("\nНажми на номер, чтобы продолжить этот разговор.")
+
+	return sb.String(), matches, nil
+}
+
+// bestSnippet quotes the message that contains the most query terms
+func bestSnippet(conversation entities.Conversation, terms []string) string {
+	if len(conversation.Messages) == 0 {
+		return ""
+	}
+
+	wanted := make(map[string]bool, len(terms))
+	for _, term := range terms {
+		wanted[term] = true
+	}
+
+	best, bestHits := 0, -1
+	for i, msg := range conversation.Messages {
+		hits := 0
+		for _, term := range search.Tokenize(msg.Content) {
+			if wanted[term] {
+				hits++
+			}
+		}
+		if hits > bestHits {
+			best, bestHits = i, hits
+		}
+	}
+
+	snippet := search.Snippet(conversation.Messages[best].Content, terms, SearchSnippetLength)
+	return strings.Join(strings.Fields(snippet), " ")
+}
+
+// HandleReopenConversation restores an archived conversation into the user's
+// current session. The replaced history is archived.
+func (h *CommandHandler) HandleReopenConversation(ctx context.Context, cmd commands.ReopenConversationCommand) (string, error) {
+	h.logger.Info("Handling reopen conversation",
+		zap.Int64("chatID", cmd.ChatID),
+		zap.Int64("userID", cmd.UserID),
+		zap.String("conversationID", cmd.ConversationID))
+
+	conversation, err := h.archiveRepo.GetConversation(cmd.ChatID, cmd.ConversationID)
+	if err != nil {
+		if errors.Is(err, entities.ErrConversationNotFound) {
+			return "ℹ️ Этот разговор не найден в архиве.", nil
+		}
+		return "", err
+	}
+
+	// Чужой личный разговор открыть нельзя
+	if conversation.UserID != cmd.UserID && conversation.UserID != entities.SharedSessionUserID {
+		return "🚫 Это чужой разговор.", nil
+	}
+
+	return retryOnConflict(func() (string, error) {
+		session, err := h.resolveSession(cmd.ChatID, cmd.UserID)
+		if err != nil {
+			return "", err
+		}
+
+		// Личный разговор не должен стать виден всей группе
+		if session.IsShared() && conversation.UserID != entities.SharedSessionUserID {
+			return "🚫 Личный разговор нельзя открыть в общей сессии группы.", nil
+		}
+
+		if !canManageSession(session, cmd.UserID, cmd.IsAdmin) {
+			return "🚫 Заменить общую сессию может только администратор или тот, кто её начал.", nil
+		}
+
+		// Текущий разговор архивируется до замены, даже если открывают его же:
+		// в сессии могут быть реплики новее архивной копии
+		previous := session.Clone()
+		h.archiveSession(previous)
+
+		messages := conversation.Messages
+		if previous.IsActive && previous.ConversationID == conversation.ID {
+			messages = previous.Messages
+		}
+
+		session.Reset()
+		session.Messages = messages
+		// Запись в архиве продолжается, только если у сессии тот же владелец.
+		// Общий разговор, открытый в личной сессии, архивируется как новый.
+		if conversation.UserID == session.UserID {
+			session.ConversationID = conversation.ID
+		}
+		session.IsActive = true
+		session.StartedBy = cmd.UserID
+		session.Touch()
+
+		if err := h.sessionRepo.SaveSession(session); err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("📂 Разговор от %s снова открыт, можно продолжать.",
+			conversation.StartedAt.In(h.location).Format("02.01.2006 15:04")), nil
+	})
+}
@@ -0,0 +1,84 @@
+package scheduler
+
+import (
+	"context"
+	"telegram-chatbot/internal/domain/entities"
+	"telegram-chatbot/internal/domain/repositories"
+	"telegram-chatbot/internal/domain/services"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// ReminderPollInterval is how often due reminders are checked
+	ReminderPollInterval = 15 * time.Second
+
+	maxDeliveryAttempts = 3
+	retryDelay          = time.Minute
+)
+
+// ReminderScheduler delivers due reminders to their chats
+type ReminderScheduler struct {
+	reminderRepo repositories.ReminderRepository
+	notifier     services.Notifier
+	logger       *zap.Logger
+}
+
+func NewReminderScheduler(
+	reminderRepo repositories.ReminderRepository,
+	notifier services.Notifier,
+	logger *zap.Logger,
+) *ReminderScheduler {
+	return &ReminderScheduler{
+		reminderRepo: reminderRepo,
+		notifier:     notifier,
+		logger:       logger,
+	}
+}
+
+// Start polls for due reminders until the context is cancelled
+func (s *ReminderScheduler) Start(ctx context.Context) error {
+	s.logger.Info("Starting reminder scheduler", zap.Duration("interval", ReminderPollInterval))
+
+	ticker := time.NewTicker(ReminderPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("Reminder scheduler stopping...")
+			return nil
+		case <-ticker.C:
+			s.deliverDue(ctx)
+		}
+	}
+}
+
+func (s *ReminderScheduler) deliverDue(ctx context.Context) {
+	reminders, err := s.reminderRepo.PopDueReminders(time.Now())
+	if err != nil {
+		s.logger.Error("Failed to get due reminders", zap.Error(err))
+	}
+
+	for _, reminder := range reminders {
+		if err := s.notifier.SendReminder(ctx, reminder); err != nil {
+			s.logger.Error("Failed to deliver reminder", zap.String("reminderID", reminder.ID), zap.Error(err))
+			s.retry(reminder)
+		}
+	}
+}
+
+// retry puts a reminder that failed to deliver back into the schedule
+func (s *ReminderScheduler) retry(reminder entities.Reminder) {
+	reminder.Attempts++
+	if reminder.Attempts >= maxDeliveryAttempts {
+		s.logger.Warn("Dropping reminder after failed deliveries", zap.String("reminderID", reminder.ID))
+		return
+	}
+
+	reminder.DueAt = time.Now().Add(retryDelay)
+	if err := s.reminderRepo.SaveReminder(&reminder); err != nil {
+		s.logger.Error("Failed to reschedule reminder", zap.String("reminderID", reminder.ID), zap.Error(err))
+	}
+}
@@ -0,0 +1,141 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"telegram-chatbot/internal/domain/entities"
+	"telegram-chatbot/internal/domain/repositories"
+	"telegram-chatbot/internal/domain/services"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// SessionSweepInterval is how often idle sessions are checked
+const SessionSweepInterval = time.Minute
+
+// SessionSweeper ends sessions that stayed idle past the timeout. Users are
+// warned shortly before and told once the session has ended.
+type SessionSweeper struct {
+	sessionRepo repositories.SessionRepository
+	archiveRepo repositories.ArchiveRepository
+	notifier    services.Notifier
+	settings    services.RuntimeSettings
+	logger      *zap.Logger
+}
+
+func NewSessionSweeper(
+	sessionRepo repositories.SessionRepository,
+	archiveRepo repositories.ArchiveRepository,
+	notifier services.Notifier,
+	settings services.RuntimeSettings,
+	logger *zap.Logger,
+) *SessionSweeper {
+	return &SessionSweeper{
+		sessionRepo: sessionRepo,
+		archiveRepo: archiveRepo,
+		notifier:    notifier,
+		settings:    settings,
+		logger:      logger,
+	}
+}
+
+// Start sweeps idle sessions until the context is cancelled
+func (s *SessionSweeper) Start(ctx context.Context) error {
+	s.logger.Info("Starting session sweeper",
+		zap.Duration("interval", SessionSweepInterval),
+		zap.Duration("idleTimeout", s.settings.SessionIdleTimeout()))
+
+	ticker := time.NewTicker(SessionSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("Session sweeper stopping...")
+			return nil
+		case <-ticker.C:
+			s.sweep(ctx)
+		}
+	}
+}
+
+func (s *SessionSweeper) sweep(ctx context.Context) {
+	now := time.Now()
+	// Таймауты читаются на каждом проходе, их можно поменять без перезапуска
+	idle, warning := s.settings.SessionIdleTimeout(), s.settings.SessionExpiryWarning()
+
+	// Берём все сессии, которым пора хотя бы показать предупреждение
+	sessions, err := s.sessionRepo.ListIdleSessions(now.Add(-(idle - warning)))
+	if err != nil {
+		s.logger.Error("Failed to get idle sessions", zap.Error(err))
+		return
+	}
+
+	for _, session := range sessions {
+		expiresAt := session.LastActivity().Add(idle)
+
+		switch {
+		case !now.Before(expiresAt):
+			s.expire(ctx, session)
+		case !session.ExpiryWarned:
+			s.warn(ctx, session, expiresAt)
+		}
+	}
+}
+
+// warn tells the user the session is about to end. The warning is sent once
+// per idle period.
+func (s *SessionSweeper) warn(ctx context.Context, session *entities.ChatSession, expiresAt time.Time) {
+	if err := s.notifier.SendSessionExpiryWarning(ctx, *session, expiresAt); err != nil {
+		s.logger.Error("Failed to send session expiry warning",
+			zap.Int64("chatID", session.ChatID),
+			zap.Int64("userID", session.UserID),
+			zap.Error(err))
+		return
+	}
+
+	// Записываем только флаг предупреждения и только если сессию не трогали
+	// после чтения: иначе затёрли бы свежие сообщения
+	session.ExpiryWarned = true
+	if err := s.sessionRepo.UpdateActivity(session); err != nil && !errors.Is(err, repositories.ErrSessionChanged) {
+		s.logger.Error("Failed to save session", zap.Error(err))
+	}
+}
+
+// expire ends an idle session and archives its history. A session used after
+// it was read is left alone.
+func (s *SessionSweeper) expire(ctx context.Context, session *entities.ChatSession) {
+	ended := session.Clone()
+	session.IsActive = false
+	session.Reset()
+
+	if err := s.sessionRepo.SaveSession(session); err != nil {
+		if errors.Is(err, repositories.ErrSessionChanged) {
+			s.logger.Debug("Idle session was used meanwhile, keeping it",
+				zap.Int64("chatID", session.ChatID),
+				zap.Int64("userID", session.UserID))
+			return
+		}
+		s.logger.Error("Failed to save session", zap.Error(err))
+		return
+	}
+
+	s.logger.Info("Ended idle session",
+		zap.Int64("chatID", session.ChatID),
+		zap.Int64("userID", session.UserID),
+		zap.Time("lastActivity", ended.LastActivity()))
+
+	if len(ended.Messages) > 0 {
+		if err := s.archiveRepo.SaveConversation(entities.NewConversation(ended)); err != nil {
+			s.logger.Error("Failed to archive conversation", zap.Error(err))
+		}
+	}
+
+	if err := s.notifier.SendSessionExpired(ctx, *session); err != nil {
+		s.logger.Error("Failed to send session expired notice",
+			zap.Int64("chatID", session.ChatID),
+			zap.Int64("userID", session.UserID),
+			zap.Error(err))
+	}
+}
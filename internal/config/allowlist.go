@@ -0,0 +1,156 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Allowlist names an ID list in the access section of the config file
+type Allowlist string
+
+const (
+	AllowedChats Allowlist = "chat_ids"
+	AllowedUsers Allowlist = "user_ids"
+)
+
+// EnvName returns the environment variable that overrides the list
+func (a Allowlist) EnvName() string {
+	if a == AllowedUsers {
+		return "ALLOWED_USER_IDS"
+	}
+	return "ALLOWED_CHAT_IDS"
+}
+
+// UpdateAllowlist adds or removes IDs in the config file and reports whether
+// the file changed. A running bot picks the change up on its next reload.
+// YAML comments are kept; a TOML file is rewritten without them.
+func UpdateAllowlist(path string, list Allowlist, ids []int64, add bool) (bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	file, err := readConfigFile(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read config file %s: %v", path, err)
+	}
+
+	current := file.Access.ChatIDs
+	if list == AllowedUsers {
+		current = file.Access.UserIDs
+	}
+
+	updated := slices.Clone(current)
+	for _, id := range ids {
+		switch {
+		case add && !slices.Contains(updated, id):
+			updated = append(updated, id)
+		case !add:
+			updated = slices.DeleteFunc(updated, func(existing int64) bool { return existing == id })
+		}
+	}
+	if slices.Equal(current, updated) {
+		return false, nil
+	}
+	if list == AllowedChats && len(updated) == 0 {
+		return false, fmt.Errorf("at least one allowed chat is required")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+
+	ext := filepath.Ext(path)
+	if strings.EqualFold(ext, ".toml") {
+		data, err = setTOMLAllowlist(data, list, updated)
+	} else {
+		data, err = setYAMLAllowlist(data, list, updated)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// Проверяем результат до записи, чтобы не оставить испорченный файл
+	if _, err := decodeConfigFile(data, ext); err != nil {
+		return false, fmt.Errorf("updated config is invalid: %v", err)
+	}
+
+	// Файл перезаписывается на месте: в контейнере он может быть смонтирован отдельно
+	return true, os.WriteFile(path, data, info.Mode().Perm())
+}
+
+// setYAMLAllowlist replaces the list in the YAML document, keeping comments
+// and the order of the other keys
+func setYAMLAllowlist(data []byte, list Allowlist, ids []int64) ([]byte, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	if document.Kind == 0 {
+		// Пустой файл
+		document = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+
+	root := document.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config file must be a mapping")
+	}
+	access := mappingValue(root, "access")
+	if access.Kind != yaml.MappingNode {
+		access.Kind, access.Tag, access.Value = yaml.MappingNode, "", ""
+	}
+
+	sequence := mappingValue(access, string(list))
+	*sequence = yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle, LineComment: sequence.LineComment}
+	for _, id := range ids {
+		sequence.Content = append(sequence.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: fmt.Sprint(id)})
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&document); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// mappingValue returns the value node for the key, adding an empty one if missing
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+
+	value := &yaml.Node{Kind: yaml.MappingNode}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+	return value
+}
+
+// setTOMLAllowlist replaces the list in the TOML document
+func setTOMLAllowlist(data []byte, list Allowlist, ids []int64) ([]byte, error) {
+	document := make(map[string]any)
+	if err := toml.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+
+	access, ok := document["access"].(map[string]any)
+	if !ok {
+		access = make(map[string]any)
+		document["access"] = access
+	}
+	access[string(list)] = ids
+
+	return toml.Marshal(document)
+}
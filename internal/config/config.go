@@ -5,69 +5,139 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"telegram-chatbot/internal/domain/entities"
+	"time"
 )
 
 type Config struct {
 	TelegramBotToken string
 	ClaudeAPIKey     string
 	AllowedChatIDs   []int64
+	AllowedUserIDs   []int64
 	LogLevel         string
 	RedisHost        string
 	RedisPort        string
 	RedisUsername    string
 	RedisPassword    string
 	RedisDB          int
-	HealthCheckPort  string
+	// Адреса узлов кластера или sentinel; по умолчанию RedisHost:RedisPort
+	RedisAddrs            []string
+	RedisMasterName       string // имя мастера в Sentinel
+	RedisSentinelUsername string
+	RedisSentinelPassword string
+	RedisCluster          bool
+	RedisTLS              bool
+	RedisTLSCAFile        string
+	RedisTLSCertFile      string
+	RedisTLSKeyFile       string
+	RedisTLSServerName    string
+	RedisTLSInsecure      bool
+	RedisPoolSize         int
+	RedisMinIdleConns     int
+	RedisKeyPrefix        string
+	HealthCheckPort       string
+	TimeZone              string
+	MemoryProposals       bool
+	// Время жизни сессии в Redis и таймаут неактивности
+	SessionTTL           time.Duration
+	SessionIdleTimeout   time.Duration
+	SessionExpiryWarning time.Duration
+	// Сколько последних сообщений хранить в истории сессии (0 - без ограничения)
+	SessionMaxMessages int
+	// Ключи шифрования переписки в формате "id:base64key", текущий ключ первый
+	EncryptionKeys string
+	// Хранилище данных бота: redis или memory (для локального запуска без Redis)
+	StorageBackend    string
+	MemoryMaxSessions int
+	// Хранилище сессий: как StorageBackend, sqlite или postgres
+	SessionBackend string
+	SQLitePath     string
+	PostgresDSN    string
+	// Модель по умолчанию и модели отдельных чатов в формате provider:model
+	LLMModel      entities.ModelRef
+	ChatModels    map[int64]entities.ModelRef
+	OpenAIAPIKey  string
+	OpenAIBaseURL string
+	OllamaURL     string
+	// Резервные модели по порядку и автомат отключения отказавших моделей
+	LLMFallbacks       []entities.ModelRef
+	LLMBreakerFailures int
+	LLMBreakerCooldown time.Duration
+	// Модели, которые можно выбрать командой /model, с псевдонимами
+	LLMModels entities.ModelCatalog
+	// Бюджет токенов на размышления Claude в режиме /think и показ хода мыслей
+	ThinkingBudget int
+	ThinkingShow   bool
+	// Файл конфигурации, из которого перечитываются изменения без перезапуска
+	ConfigFile string
+	// Системный промпт бота и отдельных чатов (персоны), пусто - промпт по умолчанию
+	SystemPrompt string
+	Personas     map[int64]string
+	// Лимит контекста сессии в символах и длины одного ответа в токенах
+	MaxContextSize    int
+	MaxResponseTokens int
 }
 
+// Load reads the config file named by CONFIG_FILE, if set, with environment
+// variables taking precedence over it
 func Load() (*Config, error) {
-	botToken := os.Getenv("TELEGRAM_BOT_TOKEN")
+	return LoadFile(os.Getenv("CONFIG_FILE"))
+}
+
+// LoadFile reads the config from a YAML or TOML file overridden by
+// environment variables. An empty path reads the environment only.
+func LoadFile(path string) (*Config, error) {
+	src, file, err := newSource(path)
+	if err != nil {
+		return nil, err
+	}
+
+	botToken := src.get("TELEGRAM_BOT_TOKEN")
 	if botToken == "" {
 		return nil, fmt.Errorf("TELEGRAM_BOT_TOKEN is required")
 	}
 
-	claudeAPIKey := os.Getenv("CLAUDE_API_KEY")
-	if claudeAPIKey == "" {
-		return nil, fmt.Errorf("CLAUDE_API_KEY is required")
-	}
+	claudeAPIKey := src.get("CLAUDE_API_KEY")
 
-	chatIDsStr := os.Getenv("ALLOWED_CHAT_IDS")
+	chatIDsStr := src.get("ALLOWED_CHAT_IDS")
 	if chatIDsStr == "" {
 		return nil, fmt.Errorf("ALLOWED_CHAT_IDS is required")
 	}
 
-	chatIDStrings := strings.Split(chatIDsStr, ",")
-	chatIDs := make([]int64, 0, len(chatIDStrings))
+	chatIDs, err := parseIDList(chatIDsStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chat ID in ALLOWED_CHAT_IDS: %v", err)
+	}
 
-	for _, idStr := range chatIDStrings {
-		idStr = strings.TrimSpace(idStr)
-		chatID, err := strconv.ParseInt(idStr, 10, 64)
+	// Пользователи, которым доступен inline-режим (необязательно)
+	var userIDs []int64
+	if userIDsStr := src.get("ALLOWED_USER_IDS"); userIDsStr != "" {
+		userIDs, err = parseIDList(userIDsStr)
 		if err != nil {
-			return nil, fmt.Errorf("invalid chat ID in ALLOWED_CHAT_IDS: %s - %v", idStr, err)
+			return nil, fmt.Errorf("invalid user ID in ALLOWED_USER_IDS: %v", err)
 		}
-		chatIDs = append(chatIDs, chatID)
 	}
 
-	logLevel := os.Getenv("LOG_LEVEL")
+	logLevel := src.get("LOG_LEVEL")
 	if logLevel == "" {
 		logLevel = "info"
 	}
 
 	// Redis configuration
-	redisHost := os.Getenv("REDIS_HOST")
+	redisHost := src.get("REDIS_HOST")
 	if redisHost == "" {
 		redisHost = "localhost"
 	}
 
-	redisPort := os.Getenv("REDIS_PORT")
+	redisPort := src.get("REDIS_PORT")
 	if redisPort == "" {
 		redisPort = "6379"
 	}
 
-	redisUsername := os.Getenv("REDIS_USERNAME")
-	redisPassword := os.Getenv("REDIS_PASSWORD")
+	redisUsername := src.get("REDIS_USERNAME")
+	redisPassword := src.get("REDIS_PASSWORD")
 
-	redisDBStr := os.Getenv("REDIS_DB")
+	redisDBStr := src.get("REDIS_DB")
 	redisDB := 0
 	if redisDBStr != "" {
 		var dbErr error
@@ -77,21 +147,428 @@ func Load() (*Config, error) {
 		}
 	}
 
-	healthCheckPort := os.Getenv("HEALTH_CHECK_PORT")
+	redisAddrs := []string{fmt.Sprintf("%s:%s", redisHost, redisPort)}
+	if value := src.get("REDIS_ADDRS"); value != "" {
+		redisAddrs = nil
+		for _, addr := range strings.Split(value, ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				redisAddrs = append(redisAddrs, addr)
+			}
+		}
+	}
+
+	redisCluster, err := src.flag("REDIS_CLUSTER")
+	if err != nil {
+		return nil, err
+	}
+	redisMasterName := src.get("REDIS_SENTINEL_MASTER")
+	if redisCluster && redisDB != 0 {
+		return nil, fmt.Errorf("REDIS_DB is not supported with REDIS_CLUSTER")
+	}
+	if redisCluster && redisMasterName != "" {
+		return nil, fmt.Errorf("set either REDIS_CLUSTER or REDIS_SENTINEL_MASTER, not both")
+	}
+
+	// TLS включается явно или указанием сертификатов
+	redisTLS, err := src.flag("REDIS_TLS")
+	if err != nil {
+		return nil, err
+	}
+	redisTLSInsecure, err := src.flag("REDIS_TLS_INSECURE_SKIP_VERIFY")
+	if err != nil {
+		return nil, err
+	}
+	redisTLSCAFile := src.get("REDIS_TLS_CA_FILE")
+	redisTLSCertFile := src.get("REDIS_TLS_CERT_FILE")
+	redisTLSKeyFile := src.get("REDIS_TLS_KEY_FILE")
+	if (redisTLSCertFile == "") != (redisTLSKeyFile == "") {
+		return nil, fmt.Errorf("REDIS_TLS_CERT_FILE and REDIS_TLS_KEY_FILE must be set together")
+	}
+	redisTLS = redisTLS || redisTLSInsecure || redisTLSCAFile != "" || redisTLSCertFile != ""
+
+	// 0 оставляет размеры пула по умолчанию go-redis
+	redisPoolSize, err := src.nonNegativeInt("REDIS_POOL_SIZE")
+	if err != nil {
+		return nil, err
+	}
+	redisMinIdleConns, err := src.nonNegativeInt("REDIS_MIN_IDLE_CONNS")
+	if err != nil {
+		return nil, err
+	}
+
+	healthCheckPort := src.get("HEALTH_CHECK_PORT")
 	if healthCheckPort == "" {
 		healthCheckPort = "8080"
 	}
 
-	return &Config{
+	// Часовой пояс семьи для инструментов даты и времени
+	timeZone := src.get("TIME_ZONE")
+	if timeZone == "" {
+		timeZone = "UTC"
+	}
+	if _, err := time.LoadLocation(timeZone); err != nil {
+		return nil, fmt.Errorf("invalid TIME_ZONE: %v", err)
+	}
+
+	// Разрешить Claude предлагать факты для долговременной памяти
+	memoryProposals := false
+	if value := src.get("MEMORY_PROPOSALS"); value != "" {
+		var parseErr error
+		memoryProposals, parseErr = strconv.ParseBool(value)
+		if parseErr != nil {
+			return nil, fmt.Errorf("invalid MEMORY_PROPOSALS: %v", parseErr)
+		}
+	}
+
+	// Сессии без сообщений дольше SESSION_IDLE_TIMEOUT завершаются,
+	// за SESSION_EXPIRY_WARNING до этого пользователь получает предупреждение
+	sessionTTL, err := src.duration("SESSION_TTL", 24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	sessionIdleTimeout, err := src.duration("SESSION_IDLE_TIMEOUT", 6*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	sessionExpiryWarning, err := src.duration("SESSION_EXPIRY_WARNING", 15*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	// История длиннее SESSION_MAX_MESSAGES обрезается с начала
+	sessionMaxMessages := 200
+	if value := src.get("SESSION_MAX_MESSAGES"); value != "" {
+		var parseErr error
+		sessionMaxMessages, parseErr = strconv.Atoi(value)
+		if parseErr != nil || sessionMaxMessages < 0 {
+			return nil, fmt.Errorf("invalid SESSION_MAX_MESSAGES: %s", value)
+		}
+	}
+
+	// Без ключей шифрования данные не шифруются. Файл ключей передаётся
+	// как секрет ENCRYPTION_KEYS_FILE, по ключу на строку.
+	encryptionKeys := src.get("ENCRYPTION_KEYS")
+
+	storageBackend := strings.ToLower(src.get("STORAGE_BACKEND"))
+	if storageBackend == "" {
+		storageBackend = "redis"
+	}
+	if storageBackend != "redis" && storageBackend != "memory" {
+		return nil, fmt.Errorf("invalid STORAGE_BACKEND: %s (expected redis or memory)", storageBackend)
+	}
+
+	// Лимит сессий в памяти, самые давние вытесняются
+	memoryMaxSessions := 1000
+	if value := src.get("MEMORY_MAX_SESSIONS"); value != "" {
+		var parseErr error
+		memoryMaxSessions, parseErr = strconv.Atoi(value)
+		if parseErr != nil || memoryMaxSessions < 0 {
+			return nil, fmt.Errorf("invalid MEMORY_MAX_SESSIONS: %s", value)
+		}
+	}
+
+	// Сессии можно хранить в SQL, чтобы история не пропадала по TTL
+	sessionBackend := strings.ToLower(src.get("SESSION_BACKEND"))
+	if sessionBackend == "" {
+		sessionBackend = storageBackend
+	}
+
+	sqlitePath := src.get("SQLITE_PATH")
+	if sqlitePath == "" {
+		sqlitePath = "data/chatbot.db"
+	}
+
+	postgresDSN := src.get("POSTGRES_DSN")
+
+	switch sessionBackend {
+	case storageBackend, "sqlite":
+	case "postgres":
+		if postgresDSN == "" {
+			return nil, fmt.Errorf("POSTGRES_DSN is required when SESSION_BACKEND=postgres")
+		}
+	default:
+		return nil, fmt.Errorf("invalid SESSION_BACKEND: %s (expected %s, sqlite or postgres)", sessionBackend, storageBackend)
+	}
+
+	// Модель задаётся для всего бота и при необходимости отдельно для чатов
+	llmModel := entities.ModelRef{Provider: entities.ProviderAnthropic, Model: "claude-3-5-sonnet-20241022"}
+	if value := src.get("LLM_MODEL"); value != "" {
+		if llmModel, err = entities.ParseModelRef(value); err != nil {
+			return nil, fmt.Errorf("invalid LLM_MODEL: %v", err)
+		}
+	}
+
+	chatModels, err := parseChatModels(src.get("CHAT_MODELS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid CHAT_MODELS: %v", err)
+	}
+
+	// При ошибке модели ответ запрашивается у резервных по очереди
+	llmFallbacks, err := parseModelList(src.get("LLM_FALLBACKS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LLM_FALLBACKS: %v", err)
+	}
+
+	// После LLM_BREAKER_FAILURES ошибок подряд модель пропускается на LLM_BREAKER_COOLDOWN
+	llmBreakerFailures := 3
+	if value := src.get("LLM_BREAKER_FAILURES"); value != "" {
+		var parseErr error
+		llmBreakerFailures, parseErr = strconv.Atoi(value)
+		if parseErr != nil || llmBreakerFailures < 1 {
+			return nil, fmt.Errorf("invalid LLM_BREAKER_FAILURES: %s", value)
+		}
+	}
+	llmBreakerCooldown, err := src.duration("LLM_BREAKER_COOLDOWN", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	// Выбор модели в чатах ограничен списком администратора
+	llmModels, err := parseModelCatalog(src.get("LLM_MODELS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LLM_MODELS: %v", err)
+	}
+
+	// API требует не меньше 1024 токенов на размышления
+	thinkingBudget := 4096
+	if value := src.get("THINKING_BUDGET"); value != "" {
+		var parseErr error
+		thinkingBudget, parseErr = strconv.Atoi(value)
+		if parseErr != nil || thinkingBudget < 1024 {
+			return nil, fmt.Errorf("invalid THINKING_BUDGET: %s (expected at least 1024)", value)
+		}
+	}
+	thinkingShow, err := src.flag("THINKING_SHOW")
+	if err != nil {
+		return nil, err
+	}
+
+	configuredModels := append([]entities.ModelRef{llmModel}, llmFallbacks...)
+	for _, option := range llmModels {
+		configuredModels = append(configuredModels, option.Model)
+	}
+	if claudeAPIKey == "" && usesProvider(entities.ProviderAnthropic, chatModels, configuredModels...) {
+		return nil, fmt.Errorf("CLAUDE_API_KEY is required for anthropic models")
+	}
+
+	// Контекст длиннее MAX_CONTEXT_SIZE символов очищается
+	maxContextSize := 10000
+	if value := src.get("MAX_CONTEXT_SIZE"); value != "" {
+		var parseErr error
+		maxContextSize, parseErr = strconv.Atoi(value)
+		if parseErr != nil || maxContextSize < 1 {
+			return nil, fmt.Errorf("invalid MAX_CONTEXT_SIZE: %s", value)
+		}
+	}
+	maxResponseTokens := 1024
+	if value := src.get("MAX_RESPONSE_TOKENS"); value != "" {
+		var parseErr error
+		maxResponseTokens, parseErr = strconv.Atoi(value)
+		if parseErr != nil || maxResponseTokens < 1 {
+			return nil, fmt.Errorf("invalid MAX_RESPONSE_TOKENS: %s", value)
+		}
+	}
+
+	personas, err := file.personas()
+	if err != nil {
+		return nil, err
+	}
+
+	openAIBaseURL := src.get("OPENAI_BASE_URL")
+	if openAIBaseURL == "" {
+		openAIBaseURL = "https://api.openai.com/v1"
+	}
+
+	ollamaURL := src.get("OLLAMA_URL")
+	if ollamaURL == "" {
+		ollamaURL = "http://localhost:11434"
+	}
+
+	cfg := &Config{
 		TelegramBotToken: botToken,
 		ClaudeAPIKey:     claudeAPIKey,
 		AllowedChatIDs:   chatIDs,
+		AllowedUserIDs:   userIDs,
 		LogLevel:         logLevel,
 		RedisHost:        redisHost,
 		RedisPort:        redisPort,
 		RedisUsername:    redisUsername,
 		RedisPassword:    redisPassword,
 		RedisDB:          redisDB,
-		HealthCheckPort:  healthCheckPort,
-	}, nil
+
+		RedisAddrs:            redisAddrs,
+		RedisMasterName:       redisMasterName,
+		RedisSentinelUsername: src.get("REDIS_SENTINEL_USERNAME"),
+		RedisSentinelPassword: src.get("REDIS_SENTINEL_PASSWORD"),
+		RedisCluster:          redisCluster,
+		RedisTLS:              redisTLS,
+		RedisTLSCAFile:        redisTLSCAFile,
+		RedisTLSCertFile:      redisTLSCertFile,
+		RedisTLSKeyFile:       redisTLSKeyFile,
+		RedisTLSServerName:    src.get("REDIS_TLS_SERVER_NAME"),
+		RedisTLSInsecure:      redisTLSInsecure,
+		RedisPoolSize:         redisPoolSize,
+		RedisMinIdleConns:     redisMinIdleConns,
+		RedisKeyPrefix:        src.get("REDIS_KEY_PREFIX"),
+
+		HealthCheckPort: healthCheckPort,
+		TimeZone:        timeZone,
+		MemoryProposals: memoryProposals,
+
+		SessionTTL:           sessionTTL,
+		SessionIdleTimeout:   sessionIdleTimeout,
+		SessionExpiryWarning: sessionExpiryWarning,
+		SessionMaxMessages:   sessionMaxMessages,
+		EncryptionKeys:       encryptionKeys,
+		StorageBackend:       storageBackend,
+		MemoryMaxSessions:    memoryMaxSessions,
+		SessionBackend:       sessionBackend,
+		SQLitePath:           sqlitePath,
+		PostgresDSN:          postgresDSN,
+
+		LLMModel:      llmModel,
+		ChatModels:    chatModels,
+		OpenAIAPIKey:  src.get("OPENAI_API_KEY"),
+		OpenAIBaseURL: strings.TrimRight(openAIBaseURL, "/"),
+		OllamaURL:     strings.TrimRight(ollamaURL, "/"),
+
+		LLMFallbacks:       llmFallbacks,
+		LLMBreakerFailures: llmBreakerFailures,
+		LLMBreakerCooldown: llmBreakerCooldown,
+		LLMModels:          llmModels,
+
+		ThinkingBudget: thinkingBudget,
+		ThinkingShow:   thinkingShow,
+
+		ConfigFile:        path,
+		SystemPrompt:      strings.TrimSpace(src.get("SYSTEM_PROMPT")),
+		Personas:          personas,
+		MaxContextSize:    maxContextSize,
+		MaxResponseTokens: maxResponseTokens,
+	}
+	if err := cfg.validateSessionTimeouts(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// validateSessionTimeouts checks that the session limits fit each other. The
+// idle timeout and warning reload at runtime while the TTL does not, so the
+// check is repeated on the merged config.
+func (c *Config) validateSessionTimeouts() error {
+	if c.SessionIdleTimeout > c.SessionTTL {
+		return fmt.Errorf("SESSION_IDLE_TIMEOUT must not exceed SESSION_TTL")
+	}
+	if c.SessionExpiryWarning >= c.SessionIdleTimeout {
+		return fmt.Errorf("SESSION_EXPIRY_WARNING must be shorter than SESSION_IDLE_TIMEOUT")
+	}
+	return nil
+}
+
+// parseChatModels parses "chatID=provider:model" pairs separated by commas
+func parseChatModels(value string) (map[int64]entities.ModelRef, error) {
+	models := make(map[int64]entities.ModelRef)
+	if strings.TrimSpace(value) == "" {
+		return models, nil
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		chatIDStr, modelStr, found := strings.Cut(pair, "=")
+		if !found {
+			return nil, fmt.Errorf("%q: expected chatID=provider:model", pair)
+		}
+
+		chatID, err := strconv.ParseInt(strings.TrimSpace(chatIDStr), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s - %v", chatIDStr, err)
+		}
+
+		model, err := entities.ParseModelRef(modelStr)
+		if err != nil {
+			return nil, err
+		}
+		models[chatID] = model
+	}
+
+	return models, nil
+}
+
+// parseModelList parses comma-separated provider:model references
+func parseModelList(value string) ([]entities.ModelRef, error) {
+	var models []entities.ModelRef
+	for _, part := range strings.Split(value, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		model, err := entities.ParseModelRef(part)
+		if err != nil {
+			return nil, err
+		}
+		models = append(models, model)
+	}
+	return models, nil
+}
+
+// parseModelCatalog parses "alias=provider:model" pairs separated by commas
+func parseModelCatalog(value string) (entities.ModelCatalog, error) {
+	var catalog entities.ModelCatalog
+	if strings.TrimSpace(value) == "" {
+		return catalog, nil
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		alias, modelStr, found := strings.Cut(pair, "=")
+		if !found {
+			return nil, fmt.Errorf("%q: expected alias=provider:model", pair)
+		}
+
+		// Псевдоним попадает в данные кнопки, а их размер ограничен 64 байтами
+		alias = strings.ToLower(strings.TrimSpace(alias))
+		if alias == "" || len(alias) > 32 || strings.ContainsAny(alias, " :") || alias == "default" {
+			return nil, fmt.Errorf("%q: alias must be a short word other than default", pair)
+		}
+		if _, exists := catalog.Find(alias); exists {
+			return nil, fmt.Errorf("duplicate alias %s", alias)
+		}
+
+		model, err := entities.ParseModelRef(modelStr)
+		if err != nil {
+			return nil, err
+		}
+		catalog = append(catalog, entities.ModelOption{Alias: alias, Model: model})
+	}
+
+	return catalog, nil
+}
+
+// usesProvider reports whether any of the configured models is served by the provider
+func usesProvider(provider string, chatModels map[int64]entities.ModelRef, models ...entities.ModelRef) bool {
+	for _, model := range models {
+		if model.Provider == provider {
+			return true
+		}
+	}
+	for _, model := range chatModels {
+		if model.Provider == provider {
+			return true
+		}
+	}
+	return false
+}
+
+// parseIDList parses a comma-separated list of Telegram IDs
+func parseIDList(value string) ([]int64, error) {
+	idStrings := strings.Split(value, ",")
+	ids := make([]int64, 0, len(idStrings))
+
+	for _, idStr := range idStrings {
+		idStr = strings.TrimSpace(idStr)
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s - %v", idStr, err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
 }
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"telegram-chatbot/internal/domain/entities"
+	"testing"
+)
+
+func TestParseModelCatalog(t *testing.T) {
+	haiku := entities.ModelRef{Provider: entities.ProviderAnthropic, Model: "claude-3-5-haiku-20241022"}
+	llama := entities.ModelRef{Provider: entities.ProviderOllama, Model: "llama3.1:8b"}
+
+	tests := []struct {
+		name    string
+		value   string
+		want    entities.ModelCatalog
+		wantErr string
+	}{
+		{name: "empty", value: "  ", want: nil},
+		{
+			name:  "keeps order",
+			value: "fast=anthropic:claude-3-5-haiku-20241022,local=ollama:llama3.1:8b",
+			want:  entities.ModelCatalog{{Alias: "fast", Model: haiku}, {Alias: "local", Model: llama}},
+		},
+		{
+			name:  "alias is lowercased and trimmed",
+			value: " Fast = anthropic:claude-3-5-haiku-20241022",
+			want:  entities.ModelCatalog{{Alias: "fast", Model: haiku}},
+		},
+		{name: "no alias", value: "anthropic:claude-3-5-haiku-20241022", wantErr: "expected alias=provider:model"},
+		{name: "empty alias", value: "=anthropic:claude-3-5-haiku-20241022", wantErr: "alias must be a short word"},
+		{name: "reserved alias", value: "default=anthropic:claude-3-5-haiku-20241022", wantErr: "alias must be a short word"},
+		{name: "alias with colon", value: "a:b=anthropic:claude-3-5-haiku-20241022", wantErr: "alias must be a short word"},
+		{name: "alias too long", value: strings.Repeat("a", 33) + "=anthropic:claude", wantErr: "alias must be a short word"},
+		{name: "duplicate alias", value: "fast=anthropic:a,FAST=anthropic:b", wantErr: "duplicate alias fast"},
+		{name: "bad model", value: "fast=gemini:pro", wantErr: "unknown provider gemini"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseModelCatalog(tt.value)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseModelCatalog() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,270 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// fileConfig is the structured config file. Every value has an environment
+// variable that overrides it; personas of single chats exist only in the file.
+type fileConfig struct {
+	Bot      fileBot      `yaml:"bot" toml:"bot"`
+	Access   fileAccess   `yaml:"access" toml:"access"`
+	Claude   fileClaude   `yaml:"claude" toml:"claude"`
+	OpenAI   fileOpenAI   `yaml:"openai" toml:"openai"`
+	Ollama   fileOllama   `yaml:"ollama" toml:"ollama"`
+	Storage  fileStorage  `yaml:"storage" toml:"storage"`
+	Limits   fileLimits   `yaml:"limits" toml:"limits"`
+	Personas filePersonas `yaml:"personas" toml:"personas"`
+}
+
+type fileBot struct {
+	Token           string `yaml:"token" toml:"token"`
+	LogLevel        string `yaml:"log_level" toml:"log_level"`
+	HealthCheckPort string `yaml:"health_check_port" toml:"health_check_port"`
+	TimeZone        string `yaml:"time_zone" toml:"time_zone"`
+	MemoryProposals *bool  `yaml:"memory_proposals" toml:"memory_proposals"`
+}
+
+type fileAccess struct {
+	ChatIDs []int64 `yaml:"chat_ids" toml:"chat_ids"`
+	UserIDs []int64 `yaml:"user_ids" toml:"user_ids"`
+}
+
+type fileClaude struct {
+	APIKey          string            `yaml:"api_key" toml:"api_key"`
+	Model           string            `yaml:"model" toml:"model"`
+	ChatModels      map[string]string `yaml:"chat_models" toml:"chat_models"`
+	Fallbacks       []string          `yaml:"fallbacks" toml:"fallbacks"`
+	Models          []fileModel       `yaml:"models" toml:"models"`
+	BreakerFailures *int              `yaml:"breaker_failures" toml:"breaker_failures"`
+	BreakerCooldown string            `yaml:"breaker_cooldown" toml:"breaker_cooldown"`
+	ThinkingBudget  *int              `yaml:"thinking_budget" toml:"thinking_budget"`
+	ThinkingShow    *bool             `yaml:"thinking_show" toml:"thinking_show"`
+}
+
+type fileModel struct {
+	Alias string `yaml:"alias" toml:"alias"`
+	Model string `yaml:"model" toml:"model"`
+}
+
+type fileOpenAI struct {
+	APIKey  string `yaml:"api_key" toml:"api_key"`
+	BaseURL string `yaml:"base_url" toml:"base_url"`
+}
+
+type fileOllama struct {
+	URL string `yaml:"url" toml:"url"`
+}
+
+type fileStorage struct {
+	Backend           string    `yaml:"backend" toml:"backend"`
+	SessionBackend    string    `yaml:"session_backend" toml:"session_backend"`
+	MemoryMaxSessions *int      `yaml:"memory_max_sessions" toml:"memory_max_sessions"`
+	SQLitePath        string    `yaml:"sqlite_path" toml:"sqlite_path"`
+	PostgresDSN       string    `yaml:"postgres_dsn" toml:"postgres_dsn"`
+	EncryptionKeys    string    `yaml:"encryption_keys" toml:"encryption_keys"`
+	Redis             fileRedis `yaml:"redis" toml:"redis"`
+}
+
+type fileRedis struct {
+	Host             string       `yaml:"host" toml:"host"`
+	Port             string       `yaml:"port" toml:"port"`
+	Username         string       `yaml:"username" toml:"username"`
+	Password         string       `yaml:"password" toml:"password"`
+	DB               *int         `yaml:"db" toml:"db"`
+	Addrs            []string     `yaml:"addrs" toml:"addrs"`
+	SentinelMaster   string       `yaml:"sentinel_master" toml:"sentinel_master"`
+	SentinelUsername string       `yaml:"sentinel_username" toml:"sentinel_username"`
+	SentinelPassword string       `yaml:"sentinel_password" toml:"sentinel_password"`
+	Cluster          *bool        `yaml:"cluster" toml:"cluster"`
+	KeyPrefix        string       `yaml:"key_prefix" toml:"key_prefix"`
+	PoolSize         *int         `yaml:"pool_size" toml:"pool_size"`
+	MinIdleConns     *int         `yaml:"min_idle_conns" toml:"min_idle_conns"`
+	TLS              fileRedisTLS `yaml:"tls" toml:"tls"`
+}
+
+type fileRedisTLS struct {
+	Enabled            *bool  `yaml:"enabled" toml:"enabled"`
+	CAFile             string `yaml:"ca_file" toml:"ca_file"`
+	CertFile           string `yaml:"cert_file" toml:"cert_file"`
+	KeyFile            string `yaml:"key_file" toml:"key_file"`
+	ServerName         string `yaml:"server_name" toml:"server_name"`
+	InsecureSkipVerify *bool  `yaml:"insecure_skip_verify" toml:"insecure_skip_verify"`
+}
+
+type fileLimits struct {
+	SessionTTL           string `yaml:"session_ttl" toml:"session_ttl"`
+	SessionIdleTimeout   string `yaml:"session_idle_timeout" toml:"session_idle_timeout"`
+	SessionExpiryWarning string `yaml:"session_expiry_warning" toml:"session_expiry_warning"`
+	SessionMaxMessages   *int   `yaml:"session_max_messages" toml:"session_max_messages"`
+	MaxContextSize       *int   `yaml:"max_context_size" toml:"max_context_size"`
+	MaxResponseTokens    *int   `yaml:"max_response_tokens" toml:"max_response_tokens"`
+}
+
+type filePersonas struct {
+	// Default заменяет базовый системный промпт, Chats - промпт отдельных чатов
+	Default string            `yaml:"default" toml:"default"`
+	Chats   map[string]string `yaml:"chats" toml:"chats"`
+}
+
+// readConfigFile decodes a YAML or TOML file, chosen by its extension.
+// Unknown keys are rejected so typos do not go unnoticed.
+func readConfigFile(path string) (*fileConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return decodeConfigFile(data, filepath.Ext(path))
+}
+
+// decodeConfigFile decodes YAML or TOML by the file extension
+func decodeConfigFile(data []byte, ext string) (*fileConfig, error) {
+	var file fileConfig
+	switch strings.ToLower(ext) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		// Пустой файл допустим: всё берётся из окружения
+		if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+	case ".toml":
+		decoder := toml.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&file); err != nil {
+			var strictErr *toml.StrictMissingError
+			if errors.As(err, &strictErr) {
+				return nil, errors.New(strictErr.String())
+			}
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config format %q (expected .yaml, .yml or .toml)", ext)
+	}
+
+	return &file, nil
+}
+
+// values flattens the file into environment variable names, so the file and
+// the environment share the same parsing and validation
+func (f *fileConfig) values() map[string]string {
+	values := make(map[string]string)
+	set := func(name, value string) {
+		if value != "" {
+			values[name] = value
+		}
+	}
+	setInt := func(name string, value *int) {
+		if value != nil {
+			values[name] = strconv.Itoa(*value)
+		}
+	}
+	setBool := func(name string, value *bool) {
+		if value != nil {
+			values[name] = strconv.FormatBool(*value)
+		}
+	}
+
+	set("TELEGRAM_BOT_TOKEN", f.Bot.Token)
+	set("LOG_LEVEL", f.Bot.LogLevel)
+	set("HEALTH_CHECK_PORT", f.Bot.HealthCheckPort)
+	set("TIME_ZONE", f.Bot.TimeZone)
+	setBool("MEMORY_PROPOSALS", f.Bot.MemoryProposals)
+
+	set("ALLOWED_CHAT_IDS", joinIDs(f.Access.ChatIDs))
+	set("ALLOWED_USER_IDS", joinIDs(f.Access.UserIDs))
+
+	set("CLAUDE_API_KEY", f.Claude.APIKey)
+	set("LLM_MODEL", f.Claude.Model)
+	chatModels := make([]string, 0, len(f.Claude.ChatModels))
+	for chatID, model := range f.Claude.ChatModels {
+		chatModels = append(chatModels, chatID+"="+model)
+	}
+	set("CHAT_MODELS", strings.Join(chatModels, ","))
+	set("LLM_FALLBACKS", strings.Join(f.Claude.Fallbacks, ","))
+	models := make([]string, 0, len(f.Claude.Models))
+	for _, model := range f.Claude.Models {
+		models = append(models, model.Alias+"="+model.Model)
+	}
+	set("LLM_MODELS", strings.Join(models, ","))
+	setInt("LLM_BREAKER_FAILURES", f.Claude.BreakerFailures)
+	set("LLM_BREAKER_COOLDOWN", f.Claude.BreakerCooldown)
+	setInt("THINKING_BUDGET", f.Claude.ThinkingBudget)
+	setBool("THINKING_SHOW", f.Claude.ThinkingShow)
+
+	set("OPENAI_API_KEY", f.OpenAI.APIKey)
+	set("OPENAI_BASE_URL", f.OpenAI.BaseURL)
+	set("OLLAMA_URL", f.Ollama.URL)
+
+	set("STORAGE_BACKEND", f.Storage.Backend)
+	set("SESSION_BACKEND", f.Storage.SessionBackend)
+	setInt("MEMORY_MAX_SESSIONS", f.Storage.MemoryMaxSessions)
+	set("SQLITE_PATH", f.Storage.SQLitePath)
+	set("POSTGRES_DSN", f.Storage.PostgresDSN)
+	set("ENCRYPTION_KEYS", f.Storage.EncryptionKeys)
+
+	redis := f.Storage.Redis
+	set("REDIS_HOST", redis.Host)
+	set("REDIS_PORT", redis.Port)
+	set("REDIS_USERNAME", redis.Username)
+	set("REDIS_PASSWORD", redis.Password)
+	setInt("REDIS_DB", redis.DB)
+	set("REDIS_ADDRS", strings.Join(redis.Addrs, ","))
+	set("REDIS_SENTINEL_MASTER", redis.SentinelMaster)
+	set("REDIS_SENTINEL_USERNAME", redis.SentinelUsername)
+	set("REDIS_SENTINEL_PASSWORD", redis.SentinelPassword)
+	setBool("REDIS_CLUSTER", redis.Cluster)
+	set("REDIS_KEY_PREFIX", redis.KeyPrefix)
+	setInt("REDIS_POOL_SIZE", redis.PoolSize)
+	setInt("REDIS_MIN_IDLE_CONNS", redis.MinIdleConns)
+	setBool("REDIS_TLS", redis.TLS.Enabled)
+	set("REDIS_TLS_CA_FILE", redis.TLS.CAFile)
+	set("REDIS_TLS_CERT_FILE", redis.TLS.CertFile)
+	set("REDIS_TLS_KEY_FILE", redis.TLS.KeyFile)
+	set("REDIS_TLS_SERVER_NAME", redis.TLS.ServerName)
+	setBool("REDIS_TLS_INSECURE_SKIP_VERIFY", redis.TLS.InsecureSkipVerify)
+
+	set("SESSION_TTL", f.Limits.SessionTTL)
+	set("SESSION_IDLE_TIMEOUT", f.Limits.SessionIdleTimeout)
+	set("SESSION_EXPIRY_WARNING", f.Limits.SessionExpiryWarning)
+	setInt("SESSION_MAX_MESSAGES", f.Limits.SessionMaxMessages)
+	setInt("MAX_CONTEXT_SIZE", f.Limits.MaxContextSize)
+	setInt("MAX_RESPONSE_TOKENS", f.Limits.MaxResponseTokens)
+
+	set("SYSTEM_PROMPT", f.Personas.Default)
+
+	return values
+}
+
+// personas parses the per-chat system prompts
+func (f *fileConfig) personas() (map[int64]string, error) {
+	personas := make(map[int64]string, len(f.Personas.Chats))
+	for chatIDStr, prompt := range f.Personas.Chats {
+		chatID, err := strconv.ParseInt(strings.TrimSpace(chatIDStr), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid chat ID in personas: %s - %v", chatIDStr, err)
+		}
+		if prompt = strings.TrimSpace(prompt); prompt != "" {
+			personas[chatID] = prompt
+		}
+	}
+	return personas, nil
+}
+
+func joinIDs(ids []int64) string {
+	parts := make([]string, 0, len(ids))
+	for _, id := range ids {
+		parts = append(parts, strconv.FormatInt(id, 10))
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,188 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ReloadCheckInterval is how often the config file is checked for changes
+const ReloadCheckInterval = 5 * time.Second
+
+// Live holds the current config. Access lists, limits, prompts and the log
+// level are replaced when the config file changes; everything else keeps the
+// value the process started with.
+type Live struct {
+	current atomic.Pointer[Config]
+
+	mu          sync.Mutex
+	subscribers []func(*Config)
+}
+
+func NewLive(cfg *Config) *Live {
+	live := &Live{}
+	live.current.Store(cfg)
+	return live
+}
+
+// Get returns the current config. The returned value must not be modified.
+func (l *Live) Get() *Config {
+	return l.current.Load()
+}
+
+// Subscribe calls fn with the new config after every reload
+func (l *Live) Subscribe(fn func(*Config)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.subscribers = append(l.subscribers, fn)
+}
+
+// Reload reads the config file again and applies the fields that are safe to
+// change at runtime. It returns the changed fields that need a restart.
+func (l *Live) Reload() ([]string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	current := l.Get()
+	fresh, err := LoadFile(current.ConfigFile)
+	if err != nil {
+		return nil, err
+	}
+
+	next := *current
+	next.AllowedChatIDs = fresh.AllowedChatIDs
+	next.AllowedUserIDs = fresh.AllowedUserIDs
+	next.LogLevel = fresh.LogLevel
+	next.SessionIdleTimeout = fresh.SessionIdleTimeout
+	next.SessionExpiryWarning = fresh.SessionExpiryWarning
+	next.MaxContextSize = fresh.MaxContextSize
+	next.MaxResponseTokens = fresh.MaxResponseTokens
+	next.ThinkingBudget = fresh.ThinkingBudget
+	next.ThinkingShow = fresh.ThinkingShow
+	next.SystemPrompt = fresh.SystemPrompt
+	next.Personas = fresh.Personas
+
+	// Новые значения сочетаются с прежними, которые меняются только после
+	// перезапуска, поэтому проверяется именно рабочая конфигурация
+	if err := next.validateSessionTimeouts(); err != nil {
+		return nil, fmt.Errorf("config does not fit the running one: %w", err)
+	}
+
+	// Всё, что осталось отличаться, применится только после перезапуска
+	var restart []string
+	nextValue, freshValue := reflect.ValueOf(next), reflect.ValueOf(*fresh)
+	for i := 0; i < nextValue.NumField(); i++ {
+		if !reflect.DeepEqual(nextValue.Field(i).Interface(), freshValue.Field(i).Interface()) {
+			restart = append(restart, nextValue.Type().Field(i).Name)
+		}
+	}
+
+	l.current.Store(&next)
+	for _, fn := range l.subscribers {
+		fn(&next)
+	}
+	return restart, nil
+}
+
+func (l *Live) SystemPrompt(chatID int64) string {
+	cfg := l.Get()
+	if persona, ok := cfg.Personas[chatID]; ok {
+		return persona
+	}
+	return cfg.SystemPrompt
+}
+
+func (l *Live) MaxContextSize() int {
+	return l.Get().MaxContextSize
+}
+
+func (l *Live) MaxResponseTokens() int {
+	return l.Get().MaxResponseTokens
+}
+
+func (l *Live) ThinkingBudget() int {
+	return l.Get().ThinkingBudget
+}
+
+func (l *Live) SessionIdleTimeout() time.Duration {
+	return l.Get().SessionIdleTimeout
+}
+
+func (l *Live) SessionExpiryWarning() time.Duration {
+	return l.Get().SessionExpiryWarning
+}
+
+// Watcher reloads the config when its file changes. The file is polled, so
+// editors that replace the file instead of writing into it are handled too.
+type Watcher struct {
+	live   *Live
+	logger *zap.Logger
+}
+
+func NewWatcher(live *Live, logger *zap.Logger) *Watcher {
+	return &Watcher{
+		live:   live,
+		logger: logger,
+	}
+}
+
+// Start watches the config file until the context is cancelled
+func (w *Watcher) Start(ctx context.Context) error {
+	path := w.live.Get().ConfigFile
+	if path == "" {
+		// Без файла конфигурации перечитывать нечего
+		return nil
+	}
+
+	w.logger.Info("Watching config file", zap.String("path", path))
+	lastModified := w.modified(path)
+
+	ticker := time.NewTicker(ReloadCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			modified := w.modified(path)
+			if modified.IsZero() || modified.Equal(lastModified) {
+				continue
+			}
+			lastModified = modified
+			w.reload(path)
+		}
+	}
+}
+
+func (w *Watcher) reload(path string) {
+	restart, err := w.live.Reload()
+	if err != nil {
+		// Ошибка в файле не должна ронять бота: остаётся прежняя конфигурация
+		w.logger.Error("Failed to reload config, keeping the current one",
+			zap.String("path", path),
+			zap.Error(err))
+		return
+	}
+
+	w.logger.Info("Config reloaded", zap.String("path", path))
+	if len(restart) > 0 {
+		w.logger.Warn("Some config changes take effect only after a restart",
+			zap.Strings("fields", restart))
+	}
+}
+
+func (w *Watcher) modified(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		// Файл может ненадолго пропасть, пока редактор его пересохраняет
+		return time.Time{}
+	}
+	return info.ModTime()
+}
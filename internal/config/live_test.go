@@ -0,0 +1,70 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, path, ttl, idle string) {
+	t.Helper()
+	data := fmt.Sprintf(`bot:
+  token: "123:abc"
+access:
+  chat_ids: [-100]
+claude:
+  api_key: "key"
+limits:
+  session_ttl: %s
+  session_idle_timeout: %s
+`, ttl, idle)
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+}
+
+func TestLiveReloadSessionTimeouts(t *testing.T) {
+	tests := []struct {
+		name     string
+		ttl      string
+		idle     string
+		wantIdle time.Duration
+		wantErr  string
+	}{
+		{name: "shorter idle timeout", ttl: "24h", idle: "3h", wantIdle: 3 * time.Hour},
+		{name: "idle timeout above the running TTL", ttl: "48h", idle: "30h", wantIdle: 6 * time.Hour, wantErr: "SESSION_IDLE_TIMEOUT must not exceed SESSION_TTL"},
+		{name: "invalid file", ttl: "2h", idle: "3h", wantIdle: 6 * time.Hour, wantErr: "SESSION_IDLE_TIMEOUT must not exceed SESSION_TTL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			writeConfigFile(t, path, "24h", "6h")
+			cfg, err := LoadFile(path)
+			if err != nil {
+				t.Fatalf("load: %v", err)
+			}
+			live := NewLive(cfg)
+
+			writeConfigFile(t, path, tt.ttl, tt.idle)
+			_, err = live.Reload()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := live.SessionIdleTimeout(); got != tt.wantIdle {
+				t.Errorf("idle timeout = %v, want %v", got, tt.wantIdle)
+			}
+			if got := live.Get().SessionTTL; got != 24*time.Hour {
+				t.Errorf("TTL = %v, want the running 24h", got)
+			}
+		})
+	}
+}
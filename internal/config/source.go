@@ -0,0 +1,111 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// secretNames are the values that may also be read from a NAME_FILE file,
+// e.g. a Docker secret mounted into the container
+var secretNames = []string{
+	"TELEGRAM_BOT_TOKEN",
+	"CLAUDE_API_KEY",
+	"OPENAI_API_KEY",
+	"REDIS_PASSWORD",
+	"REDIS_SENTINEL_PASSWORD",
+	"POSTGRES_DSN",
+	"ENCRYPTION_KEYS",
+}
+
+// source looks up config values: the environment wins over secret files,
+// which win over the config file
+type source struct {
+	file    map[string]string
+	secrets map[string]string
+}
+
+// newSource reads the config file, if any, and the secret files
+func newSource(path string) (*source, *fileConfig, error) {
+	file := &fileConfig{}
+	if path != "" {
+		var err error
+		if file, err = readConfigFile(path); err != nil {
+			return nil, nil, fmt.Errorf("failed to read config file %s: %v", path, err)
+		}
+	}
+
+	secrets := make(map[string]string)
+	for _, name := range secretNames {
+		secretFile := os.Getenv(name + "_FILE")
+		if secretFile == "" {
+			continue
+		}
+		data, err := os.ReadFile(secretFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s_FILE: %v", name, err)
+		}
+		// Файлы секретов обычно заканчиваются переводом строки
+		secrets[name] = strings.TrimSpace(string(data))
+	}
+
+	return &source{file: file.values(), secrets: secrets}, file, nil
+}
+
+func (s *source) get(name string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	if value, ok := s.secrets[name]; ok {
+		return value
+	}
+	return s.file[name]
+}
+
+// duration reads a positive duration such as "30m" or "24h"
+func (s *source) duration(name string, defaultValue time.Duration) (time.Duration, error) {
+	value := s.get(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %v", name, err)
+	}
+	if duration <= 0 {
+		return 0, fmt.Errorf("invalid %s: must be positive", name)
+	}
+
+	return duration, nil
+}
+
+// flag reads an optional boolean flag
+func (s *source) flag(name string) (bool, error) {
+	value := s.get(name)
+	if value == "" {
+		return false, nil
+	}
+
+	result, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s: %v", name, err)
+	}
+	return result, nil
+}
+
+// nonNegativeInt reads an optional non-negative number
+func (s *source) nonNegativeInt(name string) (int, error) {
+	value := s.get(name)
+	if value == "" {
+		return 0, nil
+	}
+
+	result, err := strconv.Atoi(value)
+	if err != nil || result < 0 {
+		return 0, fmt.Errorf("invalid %s: %s", name, value)
+	}
+	return result, nil
+}
@@ -0,0 +1,43 @@
+package di
+
+import (
+	"fmt"
+	"telegram-chatbot/internal/application/scheduler"
+	"telegram-chatbot/internal/config"
+	"telegram-chatbot/internal/domain/repositories"
+	"telegram-chatbot/internal/infrastructure/healthcheck"
+	"telegram-chatbot/internal/infrastructure/telegram"
+)
+
+type Container struct {
+	Bot               *telegram.Bot
+	HealthCheck       *healthcheck.Service
+	ReminderScheduler *scheduler.ReminderScheduler
+	SessionSweeper    *scheduler.SessionSweeper
+	ConfigWatcher     *config.Watcher
+}
+
+// InitializeContainer builds the application with the storage backend chosen by STORAGE_BACKEND
+func InitializeContainer(cfg *config.Config) (*Container, func(), error) {
+	switch cfg.StorageBackend {
+	case "redis":
+		return InitializeRedisContainer(cfg)
+	case "memory":
+		return InitializeMemoryContainer(cfg)
+	default:
+		return nil, nil, fmt.Errorf("unknown storage backend %q", cfg.StorageBackend)
+	}
+}
+
+// InitializeSessionStore opens only the session storage, for managing
+// sessions from the command line
+func InitializeSessionStore(cfg *config.Config) (repositories.SessionRepository, func(), error) {
+	switch cfg.StorageBackend {
+	case "redis":
+		return InitializeRedisSessionStore(cfg)
+	case "memory":
+		return InitializeMemorySessionStore(cfg)
+	default:
+		return nil, nil, fmt.Errorf("unknown storage backend %q", cfg.StorageBackend)
+	}
+}
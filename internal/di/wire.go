@@ -5,61 +5,300 @@ package di
 
 import (
 	"telegram-chatbot/internal/application/handlers"
+	"telegram-chatbot/internal/application/scheduler"
 	"telegram-chatbot/internal/config"
+	"telegram-chatbot/internal/domain/entities"
+	"telegram-chatbot/internal/domain/redaction"
 	"telegram-chatbot/internal/domain/repositories"
 	"telegram-chatbot/internal/domain/services"
+	"telegram-chatbot/internal/domain/tools"
+	"telegram-chatbot/internal/infrastructure/encryption"
 	"telegram-chatbot/internal/infrastructure/healthcheck"
 	infraRepo "telegram-chatbot/internal/infrastructure/repositories"
 	infraServices "telegram-chatbot/internal/infrastructure/services"
 	"telegram-chatbot/internal/infrastructure/telegram"
+	infraTools "telegram-chatbot/internal/infrastructure/tools"
+	"time"
 
 	"github.com/google/wire"
+	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 )
 
-type Container struct {
-	Bot            *telegram.Bot
-	HealthCheck    *healthcheck.Service
-}
-
-func InitializeContainer(*config.Config) (*Container, func(), error) {
-	wire.Build(
-		NewLogger,
-		NewRedisSessionRepository,
-		NewClaudeAPIService,
-		handlers.NewCommandHandler,
-		telegram.NewBot,
-		NewHealthCheckService,
-		wire.Struct(new(Container), "*"),
-	)
+// CoreSet provides everything that does not depend on the storage backend
+var CoreSet = wire.NewSet(
+	config.NewLive,
+	wire.Bind(new(services.RuntimeSettings), new(*config.Live)),
+	config.NewWatcher,
+	NewLogger,
+	NewKeyring,
+	NewLocation,
+	NewToolRegistry,
+	NewRedactor,
+	NewModelRouter,
+	infraServices.NewUsageStats,
+	NewClaudeAPIService,
+	NewReminderParser,
+	handlers.NewCommandHandler,
+	handlers.NewReminderHandler,
+	handlers.NewListHandler,
+	handlers.NewMemoryHandler,
+	NewModelCatalog,
+	handlers.NewModelHandler,
+	telegram.NewBot,
+	wire.Bind(new(services.Notifier), new(*telegram.Bot)),
+	scheduler.NewReminderScheduler,
+	scheduler.NewSessionSweeper,
+	NewHealthCheckService,
+	wire.Struct(new(Container), "*"),
+)
+
+// RedisStorageSet keeps all bot data in Redis
+var RedisStorageSet = wire.NewSet(
+	NewRedisClient,
+	NewRedisSessionRepository,
+	NewRedisChatSettingsRepository,
+	NewRedisReminderRepository,
+	NewRedisListRepository,
+	NewRedisMemoryRepository,
+	NewRedisArchiveRepository,
+)
+
+// MemoryStorageSet keeps all bot data in process memory, for local runs
+// without Redis. Everything is lost on restart.
+var MemoryStorageSet = wire.NewSet(
+	NewMemorySessionRepository,
+	infraRepo.NewMemoryChatSettingsRepository,
+	infraRepo.NewMemoryReminderRepository,
+	infraRepo.NewMemoryListRepository,
+	infraRepo.NewMemoryMemoryRepository,
+	infraRepo.NewMemoryArchiveRepository,
+)
+
+func InitializeRedisContainer(*config.Config) (*Container, func(), error) {
+	wire.Build(CoreSet, RedisStorageSet)
+	return &Container{}, nil, nil
+}
+
+func InitializeMemoryContainer(*config.Config) (*Container, func(), error) {
+	wire.Build(CoreSet, MemoryStorageSet)
 	return &Container{}, nil, nil
 }
 
-func NewRedisSessionRepository(cfg *config.Config) repositories.SessionRepository {
-	return infraRepo.NewRedisSessionRepository(cfg)
+// InitializeSessionMigrator builds only what the migrate command needs
+func InitializeSessionMigrator(*config.Config) (repositories.SessionMigrator, func(), error) {
+	wire.Build(NewRedisClient, NewKeyring, NewRedisSessionMigrator)
+	return nil, nil, nil
 }
 
-func NewLogger(cfg *config.Config) (*zap.Logger, error) {
-	config := zap.NewProductionConfig()
+// InitializeArchiveReindexer builds only what the migrate command needs to reindex the archive
+func InitializeArchiveReindexer(*config.Config) (repositories.ArchiveReindexer, func(), error) {
+	wire.Build(NewRedisClient, NewKeyring, NewRedisArchiveReindexer)
+	return nil, nil, nil
+}
+
+// InitializeRedisSessionStore builds only the session repository for the CLI
+func InitializeRedisSessionStore(*config.Config) (repositories.SessionRepository, func(), error) {
+	wire.Build(NewRedisClient, NewKeyring, NewRedisSessionRepository)
+	return nil, nil, nil
+}
+
+// InitializeMemorySessionStore builds only the session repository for the CLI
+func InitializeMemorySessionStore(*config.Config) (repositories.SessionRepository, func(), error) {
+	wire.Build(NewKeyring, NewMemorySessionRepository)
+	return nil, nil, nil
+}
+
+func NewRedisClient(cfg *config.Config) (redis.UniversalClient, func(), error) {
+	client, err := infraRepo.NewRedisClient(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	return client, func() {
+		_ = client.Close()
+	}, nil
+}
+
+// NewKeyring parses the encryption keys for conversation data
+func NewKeyring(cfg *config.Config) (*encryption.Keyring, error) {
+	return encryption.NewKeyring(cfg.EncryptionKeys)
+}
+
+// NewRedisSessionRepository stores sessions in Redis unless SESSION_BACKEND selects SQL
+func NewRedisSessionRepository(
+	cfg *config.Config,
+	client redis.UniversalClient,
+	keyring *encryption.Keyring,
+) (repositories.SessionRepository, func(), error) {
+	if isSQLSessionBackend(cfg) {
+		return NewSQLSessionRepository(cfg, keyring)
+	}
+	return infraRepo.NewRedisSessionRepository(client, cfg.RedisKeyPrefix, cfg.SessionTTL, cfg.SessionMaxMessages, keyring), func() {}, nil
+}
+
+// NewRedisSessionMigrator upgrades sessions stored in Redis to the current format
+func NewRedisSessionMigrator(
+	cfg *config.Config,
+	client redis.UniversalClient,
+	keyring *encryption.Keyring,
+) repositories.SessionMigrator {
+	return infraRepo.NewRedisSessionMigrator(client, cfg.RedisKeyPrefix, cfg.SessionTTL, keyring)
+}
+
+// NewMemorySessionRepository stores sessions in memory unless SESSION_BACKEND selects SQL
+func NewMemorySessionRepository(cfg *config.Config, keyring *encryption.Keyring) (repositories.SessionRepository, func(), error) {
+	if isSQLSessionBackend(cfg) {
+		return NewSQLSessionRepository(cfg, keyring)
+	}
+	return infraRepo.NewMemorySessionRepository(cfg.SessionTTL, cfg.MemoryMaxSessions, cfg.SessionMaxMessages), func() {}, nil
+}
+
+func isSQLSessionBackend(cfg *config.Config) bool {
+	return cfg.SessionBackend == "sqlite" || cfg.SessionBackend == "postgres"
+}
+
+// NewSQLSessionRepository opens the SQLite or Postgres session store and migrates its schema
+func NewSQLSessionRepository(cfg *config.Config, keyring *encryption.Keyring) (repositories.SessionRepository, func(), error) {
+	dialect, dsn := infraRepo.SQLite, cfg.SQLitePath
+	if cfg.SessionBackend == "postgres" {
+		dialect, dsn = infraRepo.Postgres, cfg.PostgresDSN
+	}
+
+	db, err := infraRepo.OpenSQLDatabase(dialect, dsn)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	switch cfg.LogLevel {
+	return infraRepo.NewSQLSessionRepository(db, dialect, keyring), func() {
+		_ = db.Close()
+	}, nil
+}
+
+func NewRedisChatSettingsRepository(cfg *config.Config, client redis.UniversalClient) repositories.ChatSettingsRepository {
+	return infraRepo.NewRedisChatSettingsRepository(client, cfg.RedisKeyPrefix)
+}
+
+// NewLogger builds the logger; the level follows LOG_LEVEL when the config file is reloaded
+func NewLogger(live *config.Live) (*zap.Logger, error) {
+	zapConfig := zap.NewProductionConfig()
+	setLogLevel(zapConfig.Level, live.Get().LogLevel)
+
+	live.Subscribe(func(cfg *config.Config) {
+		setLogLevel(zapConfig.Level, cfg.LogLevel)
+	})
+
+	return zapConfig.Build()
+}
+
+// setLogLevel changes the level; unknown names leave it unchanged
+func setLogLevel(level zap.AtomicLevel, name string) {
+	switch name {
 	case "debug":
-		config.Level = zap.NewAtomicLevelAt(zap.DebugLevel)
+		level.SetLevel(zap.DebugLevel)
 	case "info":
-		config.Level = zap.NewAtomicLevelAt(zap.InfoLevel)
+		level.SetLevel(zap.InfoLevel)
 	case "warn":
-		config.Level = zap.NewAtomicLevelAt(zap.WarnLevel)
+		level.SetLevel(zap.WarnLevel)
 	case "error":
-		config.Level = zap.NewAtomicLevelAt(zap.ErrorLevel)
+		level.SetLevel(zap.ErrorLevel)
+	}
+}
+
+func NewRedisReminderRepository(cfg *config.Config, client redis.UniversalClient) repositories.ReminderRepository {
+	return infraRepo.NewRedisReminderRepository(client, cfg.RedisKeyPrefix)
+}
+
+// NewLocation loads the family's time zone
+func NewLocation(cfg *config.Config) (*time.Location, error) {
+	return time.LoadLocation(cfg.TimeZone)
+}
+
+func NewRedisListRepository(cfg *config.Config, client redis.UniversalClient) repositories.ListRepository {
+	return infraRepo.NewRedisListRepository(client, cfg.RedisKeyPrefix)
+}
+
+func NewRedisMemoryRepository(cfg *config.Config, client redis.UniversalClient) repositories.MemoryRepository {
+	return infraRepo.NewRedisMemoryRepository(client, cfg.RedisKeyPrefix)
+}
+
+func NewRedisArchiveRepository(
+	cfg *config.Config,
+	client redis.UniversalClient,
+	keyring *encryption.Keyring,
+) repositories.ArchiveRepository {
+	return infraRepo.NewRedisArchiveRepository(client, cfg.RedisKeyPrefix, keyring)
+}
+
+// NewRedisArchiveReindexer moves the archive search index to the current encryption key
+func NewRedisArchiveReindexer(
+	cfg *config.Config,
+	client redis.UniversalClient,
+	keyring *encryption.Keyring,
+) repositories.ArchiveReindexer {
+	return infraRepo.NewRedisArchiveReindexer(client, cfg.RedisKeyPrefix, keyring)
+}
+
+func NewToolRegistry(
+	cfg *config.Config,
+	location *time.Location,
+	listRepo repositories.ListRepository,
+	memoryRepo repositories.MemoryRepository,
+) *tools.Registry {
+	registry := tools.NewRegistry(infraTools.NewBuiltinTools(location)...)
+	for _, tool := range infraTools.NewListTools(listRepo) {
+		registry.Register(tool)
+	}
+	if cfg.MemoryProposals {
+		for _, tool := range infraTools.NewMemoryTools(memoryRepo) {
+			registry.Register(tool)
+		}
 	}
+	return registry
+}
+
+func NewReminderParser(claudeService services.ClaudeService, location *time.Location) services.ReminderParser {
+	return infraServices.NewClaudeReminderParser(claudeService, location)
+}
+
+// NewModelRouter connects the model providers and builds the fallback chain for each chat
+func NewModelRouter(cfg *config.Config, logger *zap.Logger) (*infraServices.ModelRouter, error) {
+	providers := map[string]infraServices.LLMProvider{
+		entities.ProviderAnthropic: infraServices.NewAnthropicProvider(cfg.ClaudeAPIKey),
+		entities.ProviderOpenAI:    infraServices.NewOpenAIProvider(cfg.OpenAIAPIKey, cfg.OpenAIBaseURL),
+		entities.ProviderOllama:    infraServices.NewOllamaProvider(cfg.OllamaURL),
+	}
+	return infraServices.NewModelRouter(providers, infraServices.ModelRouting{
+		Default:         cfg.LLMModel,
+		PerChat:         cfg.ChatModels,
+		Fallbacks:       cfg.LLMFallbacks,
+		BreakerFailures: cfg.LLMBreakerFailures,
+		BreakerCooldown: cfg.LLMBreakerCooldown,
+		Catalog:         cfg.LLMModels,
+	}, logger)
+}
+
+// NewModelCatalog provides the models chats may choose with /model
+func NewModelCatalog(cfg *config.Config) entities.ModelCatalog {
+	return cfg.LLMModels
+}
 
-	return config.Build()
+func NewClaudeAPIService(
+	router *infraServices.ModelRouter,
+	toolRegistry *tools.Registry,
+	memoryRepo repositories.MemoryRepository,
+	settingsRepo repositories.ChatSettingsRepository,
+	redactor *redaction.Redactor,
+	usage *infraServices.UsageStats,
+	settings services.RuntimeSettings,
+) services.ClaudeService {
+	return infraServices.NewClaudeAPIService(router, toolRegistry, memoryRepo, settingsRepo, redactor, usage, settings)
 }
 
-func NewClaudeAPIService(cfg *config.Config) services.ClaudeService {
-	return infraServices.NewClaudeAPIService(cfg.ClaudeAPIKey)
+// NewRedactor builds the personal data redactor from the built-in detectors
+func NewRedactor() *redaction.Redactor {
+	return redaction.NewRedactor(redaction.DefaultDetectors()...)
 }
 
-func NewHealthCheckService(cfg *config.Config, bot *telegram.Bot, logger *zap.Logger) *healthcheck.Service {
-	return healthcheck.NewHealthCheckService(bot, logger, cfg.HealthCheckPort)
+func NewHealthCheckService(cfg *config.Config, bot *telegram.Bot, usage *infraServices.UsageStats, logger *zap.Logger) *healthcheck.Service {
+	return healthcheck.NewHealthCheckService(bot, usage, logger, cfg.HealthCheckPort)
 }
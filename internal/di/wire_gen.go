@@ -7,76 +7,449 @@
 package di
 
 import (
+	"github.com/google/wire"
+	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 	"telegram-chatbot/internal/application/handlers"
+	"telegram-chatbot/internal/application/scheduler"
 	"telegram-chatbot/internal/config"
-	"telegram-chatbot/internal/domain/repositories"
-	"telegram-chatbot/internal/domain/services"
+	"telegram-chatbot/internal/domain/entities"
+	"telegram-chatbot/internal/domain/redaction"
+	repositories2 "telegram-chatbot/internal/domain/repositories"
+	services2 "telegram-chatbot/internal/domain/services"
+	"telegram-chatbot/internal/domain/tools"
+	"telegram-chatbot/internal/infrastructure/encryption"
 	"telegram-chatbot/internal/infrastructure/healthcheck"
-	repositories2 "telegram-chatbot/internal/infrastructure/repositories"
-	services2 "telegram-chatbot/internal/infrastructure/services"
+	"telegram-chatbot/internal/infrastructure/repositories"
+	"telegram-chatbot/internal/infrastructure/services"
 	"telegram-chatbot/internal/infrastructure/telegram"
+	tools2 "telegram-chatbot/internal/infrastructure/tools"
+	"time"
 )
 
 // Injectors from wire.go:
 
-func InitializeContainer(configConfig *config.Config) (*Container, func(), error) {
-	sessionRepository := NewRedisSessionRepository(configConfig)
-	claudeService := NewClaudeAPIService(configConfig)
-	logger, err := NewLogger(configConfig)
+func InitializeRedisContainer(configConfig *config.Config) (*Container, func(), error) {
+	live := config.NewLive(configConfig)
+	universalClient, cleanup, err := NewRedisClient(configConfig)
 	if err != nil {
 		return nil, nil, err
 	}
-	commandHandler := handlers.NewCommandHandler(sessionRepository, claudeService, logger)
-	bot, err := telegram.NewBot(configConfig, commandHandler, logger)
+	keyring, err := NewKeyring(configConfig)
 	if err != nil {
+		cleanup()
 		return nil, nil, err
 	}
-	service := NewHealthCheckService(configConfig, bot, logger)
+	sessionRepository, cleanup2, err := NewRedisSessionRepository(configConfig, universalClient, keyring)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	chatSettingsRepository := NewRedisChatSettingsRepository(configConfig, universalClient)
+	archiveRepository := NewRedisArchiveRepository(configConfig, universalClient, keyring)
+	logger, err := NewLogger(live)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	modelRouter, err := NewModelRouter(configConfig, logger)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	location, err := NewLocation(configConfig)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	listRepository := NewRedisListRepository(configConfig, universalClient)
+	memoryRepository := NewRedisMemoryRepository(configConfig, universalClient)
+	registry := NewToolRegistry(configConfig, location, listRepository, memoryRepository)
+	redactor := NewRedactor()
+	usageStats := services.NewUsageStats()
+	claudeService := NewClaudeAPIService(modelRouter, registry, memoryRepository, chatSettingsRepository, redactor, usageStats, live)
+	commandHandler := handlers.NewCommandHandler(sessionRepository, chatSettingsRepository, archiveRepository, claudeService, live, location, logger)
+	reminderRepository := NewRedisReminderRepository(configConfig, universalClient)
+	reminderParser := NewReminderParser(claudeService, location)
+	reminderHandler := handlers.NewReminderHandler(reminderRepository, reminderParser, location, logger)
+	listHandler := handlers.NewListHandler(listRepository, logger)
+	memoryHandler := handlers.NewMemoryHandler(memoryRepository, logger)
+	modelCatalog := NewModelCatalog(configConfig)
+	modelHandler := handlers.NewModelHandler(chatSettingsRepository, modelCatalog, logger)
+	bot, err := telegram.NewBot(live, commandHandler, reminderHandler, listHandler, memoryHandler, modelHandler, logger)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	service := NewHealthCheckService(configConfig, bot, usageStats, logger)
+	reminderScheduler := scheduler.NewReminderScheduler(reminderRepository, bot, logger)
+	sessionSweeper := scheduler.NewSessionSweeper(sessionRepository, archiveRepository, bot, live, logger)
+	watcher := config.NewWatcher(live, logger)
+	container := &Container{
+		Bot:               bot,
+		HealthCheck:       service,
+		ReminderScheduler: reminderScheduler,
+		SessionSweeper:    sessionSweeper,
+		ConfigWatcher:     watcher,
+	}
+	return container, func() {
+		cleanup2()
+		cleanup()
+	}, nil
+}
+
+func InitializeMemoryContainer(configConfig *config.Config) (*Container, func(), error) {
+	live := config.NewLive(configConfig)
+	keyring, err := NewKeyring(configConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	sessionRepository, cleanup, err := NewMemorySessionRepository(configConfig, keyring)
+	if err != nil {
+		return nil, nil, err
+	}
+	chatSettingsRepository := repositories.NewMemoryChatSettingsRepository()
+	archiveRepository := repositories.NewMemoryArchiveRepository()
+	logger, err := NewLogger(live)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	modelRouter, err := NewModelRouter(configConfig, logger)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	location, err := NewLocation(configConfig)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	listRepository := repositories.NewMemoryListRepository()
+	memoryRepository := repositories.NewMemoryMemoryRepository()
+	registry := NewToolRegistry(configConfig, location, listRepository, memoryRepository)
+	redactor := NewRedactor()
+	usageStats := services.NewUsageStats()
+	claudeService := NewClaudeAPIService(modelRouter, registry, memoryRepository, chatSettingsRepository, redactor, usageStats, live)
+	commandHandler := handlers.NewCommandHandler(sessionRepository, chatSettingsRepository, archiveRepository, claudeService, live, location, logger)
+	reminderRepository := repositories.NewMemoryReminderRepository()
+	reminderParser := NewReminderParser(claudeService, location)
+	reminderHandler := handlers.NewReminderHandler(reminderRepository, reminderParser, location, logger)
+	listHandler := handlers.NewListHandler(listRepository, logger)
+	memoryHandler := handlers.NewMemoryHandler(memoryRepository, logger)
+	modelCatalog := NewModelCatalog(configConfig)
+	modelHandler := handlers.NewModelHandler(chatSettingsRepository, modelCatalog, logger)
+	bot, err := telegram.NewBot(live, commandHandler, reminderHandler, listHandler, memoryHandler, modelHandler, logger)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	service := NewHealthCheckService(configConfig, bot, usageStats, logger)
+	reminderScheduler := scheduler.NewReminderScheduler(reminderRepository, bot, logger)
+	sessionSweeper := scheduler.NewSessionSweeper(sessionRepository, archiveRepository, bot, live, logger)
+	watcher := config.NewWatcher(live, logger)
 	container := &Container{
-		Bot:         bot,
-		HealthCheck: service,
+		Bot:               bot,
+		HealthCheck:       service,
+		ReminderScheduler: reminderScheduler,
+		SessionSweeper:    sessionSweeper,
+		ConfigWatcher:     watcher,
 	}
 	return container, func() {
+		cleanup()
+	}, nil
+}
+
+// InitializeSessionMigrator builds only what the migrate command needs
+func InitializeSessionMigrator(configConfig *config.Config) (repositories2.SessionMigrator, func(), error) {
+	universalClient, cleanup, err := NewRedisClient(configConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyring, err := NewKeyring(configConfig)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	sessionMigrator := NewRedisSessionMigrator(configConfig, universalClient, keyring)
+	return sessionMigrator, func() {
+		cleanup()
+	}, nil
+}
+
+// InitializeArchiveReindexer builds only what the migrate command needs to reindex the archive
+func InitializeArchiveReindexer(configConfig *config.Config) (repositories2.ArchiveReindexer, func(), error) {
+	universalClient, cleanup, err := NewRedisClient(configConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyring, err := NewKeyring(configConfig)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	archiveReindexer := NewRedisArchiveReindexer(configConfig, universalClient, keyring)
+	return archiveReindexer, func() {
+		cleanup()
+	}, nil
+}
+
+// InitializeRedisSessionStore builds only the session repository for the CLI
+func InitializeRedisSessionStore(configConfig *config.Config) (repositories2.SessionRepository, func(), error) {
+	universalClient, cleanup, err := NewRedisClient(configConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyring, err := NewKeyring(configConfig)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	sessionRepository, cleanup2, err := NewRedisSessionRepository(configConfig, universalClient, keyring)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return sessionRepository, func() {
+		cleanup2()
+		cleanup()
+	}, nil
+}
+
+// InitializeMemorySessionStore builds only the session repository for the CLI
+func InitializeMemorySessionStore(configConfig *config.Config) (repositories2.SessionRepository, func(), error) {
+	keyring, err := NewKeyring(configConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	sessionRepository, cleanup, err := NewMemorySessionRepository(configConfig, keyring)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sessionRepository, func() {
+		cleanup()
 	}, nil
 }
 
 // wire.go:
 
-type Container struct {
-	Bot         *telegram.Bot
-	HealthCheck *healthcheck.Service
+// CoreSet provides everything that does not depend on the storage backend
+var CoreSet = wire.NewSet(config.NewLive, wire.Bind(new(services2.RuntimeSettings), new(*config.Live)), config.NewWatcher, NewLogger,
+	NewKeyring,
+	NewLocation,
+	NewToolRegistry,
+	NewRedactor,
+	NewModelRouter, services.NewUsageStats, NewClaudeAPIService,
+	NewReminderParser, handlers.NewCommandHandler, handlers.NewReminderHandler, handlers.NewListHandler, handlers.NewMemoryHandler, NewModelCatalog, handlers.NewModelHandler, telegram.NewBot, wire.Bind(new(services2.Notifier), new(*telegram.Bot)), scheduler.NewReminderScheduler, scheduler.NewSessionSweeper, NewHealthCheckService, wire.Struct(new(Container), "*"),
+)
+
+// RedisStorageSet keeps all bot data in Redis
+var RedisStorageSet = wire.NewSet(
+	NewRedisClient,
+	NewRedisSessionRepository,
+	NewRedisChatSettingsRepository,
+	NewRedisReminderRepository,
+	NewRedisListRepository,
+	NewRedisMemoryRepository,
+	NewRedisArchiveRepository,
+)
+
+// MemoryStorageSet keeps all bot data in process memory, for local runs
+// without Redis. Everything is lost on restart.
+var MemoryStorageSet = wire.NewSet(
+	NewMemorySessionRepository, repositories.NewMemoryChatSettingsRepository, repositories.NewMemoryReminderRepository, repositories.NewMemoryListRepository, repositories.NewMemoryMemoryRepository, repositories.NewMemoryArchiveRepository,
+)
+
+func NewRedisClient(cfg *config.Config) (redis.UniversalClient, func(), error) {
+	client, err := repositories.NewRedisClient(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	return client, func() {
+		_ = client.Close()
+	}, nil
+}
+
+// NewKeyring parses the encryption keys for conversation data
+func NewKeyring(cfg *config.Config) (*encryption.Keyring, error) {
+	return encryption.NewKeyring(cfg.EncryptionKeys)
 }
 
-func NewRedisSessionRepository(cfg *config.Config) repositories.SessionRepository {
-	return repositories2.NewRedisSessionRepository(cfg)
+// NewRedisSessionRepository stores sessions in Redis unless SESSION_BACKEND selects SQL
+func NewRedisSessionRepository(
+	cfg *config.Config,
+	client redis.UniversalClient,
+	keyring *encryption.Keyring,
+) (repositories2.SessionRepository, func(), error) {
+	if isSQLSessionBackend(cfg) {
+		return NewSQLSessionRepository(cfg, keyring)
+	}
+	return repositories.NewRedisSessionRepository(client, cfg.RedisKeyPrefix, cfg.SessionTTL, cfg.SessionMaxMessages, keyring), func() {}, nil
 }
 
-func NewLogger(cfg *config.Config) (*zap.Logger, error) {
-	config2 := zap.NewProductionConfig()
+// NewRedisSessionMigrator upgrades sessions stored in Redis to the current format
+func NewRedisSessionMigrator(
+	cfg *config.Config,
+	client redis.UniversalClient,
+	keyring *encryption.Keyring,
+) repositories2.SessionMigrator {
+	return repositories.NewRedisSessionMigrator(client, cfg.RedisKeyPrefix, cfg.SessionTTL, keyring)
+}
 
-	switch cfg.LogLevel {
+// NewMemorySessionRepository stores sessions in memory unless SESSION_BACKEND selects SQL
+func NewMemorySessionRepository(cfg *config.Config, keyring *encryption.Keyring) (repositories2.SessionRepository, func(), error) {
+	if isSQLSessionBackend(cfg) {
+		return NewSQLSessionRepository(cfg, keyring)
+	}
+	return repositories.NewMemorySessionRepository(cfg.SessionTTL, cfg.MemoryMaxSessions, cfg.SessionMaxMessages), func() {}, nil
+}
+
+func isSQLSessionBackend(cfg *config.Config) bool {
+	return cfg.SessionBackend == "sqlite" || cfg.SessionBackend == "postgres"
+}
+
+// NewSQLSessionRepository opens the SQLite or Postgres session store and migrates its schema
+func NewSQLSessionRepository(cfg *config.Config, keyring *encryption.Keyring) (repositories2.SessionRepository, func(), error) {
+	dialect, dsn := repositories.SQLite, cfg.SQLitePath
+	if cfg.SessionBackend == "postgres" {
+		dialect, dsn = repositories.Postgres, cfg.PostgresDSN
+	}
+
+	db, err := repositories.OpenSQLDatabase(dialect, dsn)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return repositories.NewSQLSessionRepository(db, dialect, keyring), func() {
+		_ = db.Close()
+	}, nil
+}
+
+func NewRedisChatSettingsRepository(cfg *config.Config, client redis.UniversalClient) repositories2.ChatSettingsRepository {
+	return repositories.NewRedisChatSettingsRepository(client, cfg.RedisKeyPrefix)
+}
+
+// NewLogger builds the logger; the level follows LOG_LEVEL when the config file is reloaded
+func NewLogger(live *config.Live) (*zap.Logger, error) {
+	zapConfig := zap.NewProductionConfig()
+	setLogLevel(zapConfig.Level, live.Get().LogLevel)
+
+	live.Subscribe(func(cfg *config.Config) {
+		setLogLevel(zapConfig.Level, cfg.LogLevel)
+	})
+
+	return zapConfig.Build()
+}
+
+// setLogLevel changes the level; unknown names leave it unchanged
+func setLogLevel(level zap.AtomicLevel, name string) {
+	switch name {
 	case "debug":
-		config2.
-			Level = zap.NewAtomicLevelAt(zap.DebugLevel)
+		level.SetLevel(zap.DebugLevel)
 	case "info":
-		config2.
-			Level = zap.NewAtomicLevelAt(zap.InfoLevel)
+		level.SetLevel(zap.InfoLevel)
 	case "warn":
-		config2.
-			Level = zap.NewAtomicLevelAt(zap.WarnLevel)
+		level.SetLevel(zap.WarnLevel)
 	case "error":
-		config2.
-			Level = zap.NewAtomicLevelAt(zap.ErrorLevel)
+		level.SetLevel(zap.ErrorLevel)
 	}
+}
+
+func NewRedisReminderRepository(cfg *config.Config, client redis.UniversalClient) repositories2.ReminderRepository {
+	return repositories.NewRedisReminderRepository(client, cfg.RedisKeyPrefix)
+}
+
+// NewLocation loads the family's time zone
+func NewLocation(cfg *config.Config) (*time.Location, error) {
+	return time.LoadLocation(cfg.TimeZone)
+}
+
+func NewRedisListRepository(cfg *config.Config, client redis.UniversalClient) repositories2.ListRepository {
+	return repositories.NewRedisListRepository(client, cfg.RedisKeyPrefix)
+}
+
+func NewRedisMemoryRepository(cfg *config.Config, client redis.UniversalClient) repositories2.MemoryRepository {
+	return repositories.NewRedisMemoryRepository(client, cfg.RedisKeyPrefix)
+}
+
+func NewRedisArchiveRepository(
+	cfg *config.Config,
+	client redis.UniversalClient,
+	keyring *encryption.Keyring,
+) repositories2.ArchiveRepository {
+	return repositories.NewRedisArchiveRepository(client, cfg.RedisKeyPrefix, keyring)
+}
+
+// NewRedisArchiveReindexer moves the archive search index to the current encryption key
+func NewRedisArchiveReindexer(
+	cfg *config.Config,
+	client redis.UniversalClient,
+	keyring *encryption.Keyring,
+) repositories2.ArchiveReindexer {
+	return repositories.NewRedisArchiveReindexer(client, cfg.RedisKeyPrefix, keyring)
+}
+
+func NewToolRegistry(
+	cfg *config.Config,
+	location *time.Location,
+	listRepo repositories2.ListRepository,
+	memoryRepo repositories2.MemoryRepository,
+) *tools.Registry {
+	registry := tools.NewRegistry(tools2.NewBuiltinTools(location)...)
+	for _, tool := range tools2.NewListTools(listRepo) {
+		registry.Register(tool)
+	}
+	if cfg.MemoryProposals {
+		for _, tool := range tools2.NewMemoryTools(memoryRepo) {
+			registry.Register(tool)
+		}
+	}
+	return registry
+}
+
+func NewReminderParser(claudeService services2.ClaudeService, location *time.Location) services2.ReminderParser {
+	return services.NewClaudeReminderParser(claudeService, location)
+}
+
+// NewModelRouter connects the model providers and builds the fallback chain for each chat
+func NewModelRouter(cfg *config.Config, logger *zap.Logger) (*services.ModelRouter, error) {
+	providers := map[string]services.LLMProvider{entities.ProviderAnthropic: services.NewAnthropicProvider(cfg.ClaudeAPIKey), entities.ProviderOpenAI: services.NewOpenAIProvider(cfg.OpenAIAPIKey, cfg.OpenAIBaseURL), entities.ProviderOllama: services.NewOllamaProvider(cfg.OllamaURL)}
+	return services.NewModelRouter(providers, services.ModelRouting{
+		Default:         cfg.LLMModel,
+		PerChat:         cfg.ChatModels,
+		Fallbacks:       cfg.LLMFallbacks,
+		BreakerFailures: cfg.LLMBreakerFailures,
+		BreakerCooldown: cfg.LLMBreakerCooldown,
+		Catalog:         cfg.LLMModels,
+	}, logger)
+}
+
+// NewModelCatalog provides the models chats may choose with /model
+func NewModelCatalog(cfg *config.Config) entities.ModelCatalog {
+	return cfg.LLMModels
+}
 
-	return config2.Build()
+func NewClaudeAPIService(
+	router *services.ModelRouter,
+	toolRegistry *tools.Registry,
+	memoryRepo repositories2.MemoryRepository,
+	settingsRepo repositories2.ChatSettingsRepository,
+	redactor *redaction.Redactor,
+	usage *services.UsageStats,
+	settings services2.RuntimeSettings,
+) services2.ClaudeService {
+	return services.NewClaudeAPIService(router, toolRegistry, memoryRepo, settingsRepo, redactor, usage, settings)
 }
 
-func NewClaudeAPIService(cfg *config.Config) services.ClaudeService {
-	return services2.NewClaudeAPIService(cfg.ClaudeAPIKey)
+// NewRedactor builds the personal data redactor from the built-in detectors
+func NewRedactor() *redaction.Redactor {
+	return redaction.NewRedactor(redaction.DefaultDetectors()...)
 }
 
-func NewHealthCheckService(cfg *config.Config, bot *telegram.Bot, logger *zap.Logger) *healthcheck.Service {
-	return healthcheck.NewHealthCheckService(bot, logger, cfg.HealthCheckPort)
+func NewHealthCheckService(cfg *config.Config, bot *telegram.Bot, usage *services.UsageStats, logger *zap.Logger) *healthcheck.Service {
+	return healthcheck.NewHealthCheckService(bot, usage, logger, cfg.HealthCheckPort)
 }
@@ -1,8 +1,9 @@
 package commands
 
 type StartCommand struct {
-	ChatID int64
-	UserID int64
+	ChatID  int64
+	UserID  int64
+	IsAdmin bool
 }
 
 type HelpCommand struct {
@@ -11,13 +12,15 @@ type HelpCommand struct {
 }
 
 type StartBeginCommand struct {
-	ChatID int64
-	UserID int64
+	ChatID  int64
+	UserID  int64
+	IsAdmin bool
 }
 
 type EndChatCommand struct {
-	ChatID int64
-	UserID int64
+	ChatID  int64
+	UserID  int64
+	IsAdmin bool
 }
 
 type WhoAmICommand struct {
@@ -29,8 +32,170 @@ type WhoAmICommand struct {
 }
 
 type ProcessMessageCommand struct {
+	ChatID      int64
+	UserID      int64
+	MessageID   int
+	Message     string
+	Username    string
+	DisplayName string
+	Think       bool // ответить с размышлениями, даже если они выключены в чате
+}
+
+type PrivacyCommand struct {
+	ChatID  int64
+	UserID  int64
+	IsAdmin bool
+	IsGroup bool
+	Mode    string // "on", "off" или пусто для показа текущего режима
+}
+
+type ThinkCommand struct {
+	ChatID  int64
+	UserID  int64
+	IsAdmin bool
+	IsGroup bool
+	Mode    string // "on", "off" или пусто для показа текущего режима
+}
+
+type ModelCommand struct {
+	ChatID  int64
+	UserID  int64
+	IsAdmin bool
+	IsGroup bool
+	Alias   string // псевдоним модели, "default" или пусто для показа выбора
+}
+
+type GroupModeCommand struct {
+	ChatID  int64
+	UserID  int64
+	IsAdmin bool
+	IsGroup bool
+	Mode    string // "on", "off" или пусто для показа текущего режима
+}
+
+type RegenerateCommand struct {
+	ChatID    int64
+	UserID    int64
+	MessageID int // сообщение бота, на котором нажата кнопка
+}
+
+type ContinueCommand struct {
+	ChatID    int64
+	UserID    int64
+	MessageID int // сообщение бота, на котором нажата кнопка
+}
+
+type UndoCommand struct {
+	ChatID int64
+	UserID int64
+}
+
+type RetryCommand struct {
+	ChatID int64
+	UserID int64
+}
+
+type EditMessageCommand struct {
+	ChatID      int64
+	UserID      int64
+	MessageID   int
+	Message     string
+	DisplayName string
+}
+
+type RecordReplyCommand struct {
+	ChatID    int64
+	UserID    int64
+	MessageID int
+	Revision  int64 // ревизия сессии, в которой сохранён ответ
+}
+
+type InlineQueryCommand struct {
+	UserID int64
+	Query  string
+}
+
+type RemindCommand struct {
+	ChatID      int64
+	UserID      int64
+	DisplayName string
+	Request     string
+}
+
+type ListRemindersCommand struct {
+	ChatID int64
+	UserID int64
+}
+
+type CancelReminderCommand struct {
+	ChatID     int64
+	UserID     int64
+	ReminderID string
+}
+
+type ListCommand struct {
+	ChatID      int64
+	UserID      int64
+	DisplayName string
+	Args        string // "add молоко, хлеб", "show", "done 3", "дела add позвонить"
+}
+
+type ToggleListItemCommand struct {
+	ChatID   int64
+	UserID   int64
+	ListName string
+	Number   int
+}
+
+type ClearListCommand struct {
+	ChatID   int64
+	UserID   int64
+	ListName string
+}
+
+type RememberCommand struct {
+	ChatID int64
+	UserID int64
+	Fact   string // "всем ..." сохраняет факт для всего чата
+}
+
+type ListMemoriesCommand struct {
+	ChatID int64
+	UserID int64
+}
+
+type ForgetCommand struct {
+	ChatID int64
+	UserID int64
+	Args   string // номера фактов из /memories
+}
+
+type TakeMemoryProposalsCommand struct {
+	ChatID int64
+	UserID int64
+}
+
+type ConfirmMemoryCommand struct {
 	ChatID   int64
 	UserID   int64
-	Message  string
-	Username string
+	MemoryID string
+	Accept   bool
+}
+
+type SearchCommand struct {
+	ChatID int64
+	UserID int64
+	Query  string
+}
+
+type ReopenConversationCommand struct {
+	ChatID         int64
+	UserID         int64
+	IsAdmin        bool
+	ConversationID string
+}
+
+type KeepSessionCommand struct {
+	ChatID int64
+	UserID int64
 }
@@ -0,0 +1,18 @@
+package entities
+
+import (
+	"time"
+)
+
+// SharedSessionUserID is the user ID under which a chat-wide session is stored
+// when the chat runs in shared group mode.
+const SharedSessionUserID int64 = 0
+
+type ChatSettings struct {
+	ChatID     int64
+	SharedMode bool
+	RedactPII  bool   // маскировать личные данные перед отправкой в Claude
+	Thinking   bool   // Claude размышляет перед каждым ответом, включается командой /think
+	Model      string // псевдоним модели, выбранной командой /model; пусто - модель по умолчанию
+	UpdatedAt  time.Time
+}
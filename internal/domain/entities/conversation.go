@@ -0,0 +1,57 @@
+package entities
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrConversationNotFound = errors.New("conversation not found in archive")
+
+// Conversation is an ended session kept in the archive for search
+type Conversation struct {
+	ID        string
+	ChatID    int64
+	UserID    int64 // SharedSessionUserID для общей сессии группы
+	Messages  []Message
+	StartedAt time.Time
+	EndedAt   time.Time
+}
+
+// ConversationMatch is a conversation found by search with its relevance score
+type ConversationMatch struct {
+	Conversation Conversation
+	Score        float64
+}
+
+// NewConversation archives the messages of a session. A session that was
+// archived or reopened before keeps its conversation ID.
+func NewConversation(session *ChatSession) *Conversation {
+	id := session.ConversationID
+	if id == "" {
+		id = NewID()
+	}
+
+	conversation := &Conversation{
+		ID:       id,
+		ChatID:   session.ChatID,
+		UserID:   session.UserID,
+		Messages: append([]Message(nil), session.Messages...),
+		EndedAt:  time.Now(),
+	}
+	if len(session.Messages) > 0 {
+		conversation.StartedAt = session.Messages[0].Timestamp
+		conversation.EndedAt = session.Messages[len(session.Messages)-1].Timestamp
+	}
+
+	return conversation
+}
+
+// Text returns the whole conversation as a single document for indexing
+func (c *Conversation) Text() string {
+	parts := make([]string, 0, len(c.Messages))
+	for _, msg := range c.Messages {
+		parts = append(parts, msg.Content)
+	}
+	return strings.Join(parts, "\n")
+}
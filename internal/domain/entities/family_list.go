@@ -0,0 +1,130 @@
+package entities
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// DefaultListName is used when a command or tool does not name a list
+const DefaultListName = "покупки"
+
+// MaxListNameBytes keeps list names short enough for inline button data,
+// which Telegram limits to 64 bytes. It fits 20 Cyrillic letters.
+const MaxListNameBytes = 40
+
+var (
+	ErrListItemNotFound = errors.New("list item not found")
+	ErrListNameTooLong  = errors.New("list name is too long")
+)
+
+// FamilyList is a shared list (shopping, to-do) kept per chat
+type FamilyList struct {
+	ChatID    int64
+	Name      string
+	Items     []ListItem
+	UpdatedAt time.Time
+}
+
+type ListItem struct {
+	Text    string
+	Done    bool
+	AddedBy string
+	AddedAt time.Time
+}
+
+// NormalizeListName brings list names typed by people and Claude to one form
+func NormalizeListName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return DefaultListName
+	}
+	return name
+}
+
+// ValidateListName checks a normalized list name before it is stored
+func ValidateListName(name string) error {
+	if len(name) > MaxListNameBytes {
+		return ErrListNameTooLong
+	}
+	return nil
+}
+
+// AddItems appends items, skipping empty ones and ones already pending in the list
+func (l *FamilyList) AddItems(texts []string, addedBy string) int {
+	added := 0
+	for _, text := range texts {
+		text = strings.TrimSpace(text)
+		if text == "" || l.hasPending(text) {
+			continue
+		}
+
+		l.Items = append(l.Items, ListItem{
+			Text:    text,
+			AddedBy: addedBy,
+			AddedAt: time.Now(),
+		})
+		added++
+	}
+
+	if added > 0 {
+		l.UpdatedAt = time.Now()
+	}
+	return added
+}
+
+func (l *FamilyList) hasPending(text string) bool {
+	for _, item := range l.Items {
+		if !item.Done && strings.EqualFold(item.Text, text) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetDone marks the item with the given 1-based number as done or pending
+func (l *FamilyList) SetDone(number int, done bool) error {
+	if number < 1 || number > len(l.Items) {
+		return ErrListItemNotFound
+	}
+
+	l.Items[number-1].Done = done
+	l.UpdatedAt = time.Now()
+	return nil
+}
+
+// ToggleDone flips the done mark of the item with the given 1-based number
+func (l *FamilyList) ToggleDone(number int) error {
+	if number < 1 || number > len(l.Items) {
+		return ErrListItemNotFound
+	}
+	return l.SetDone(number, !l.Items[number-1].Done)
+}
+
+// FindItem returns the 1-based number of the first pending item matching the text
+func (l *FamilyList) FindItem(text string) (int, error) {
+	text = strings.TrimSpace(text)
+	for i, item := range l.Items {
+		if !item.Done && strings.EqualFold(item.Text, text) {
+			return i + 1, nil
+		}
+	}
+	return 0, ErrListItemNotFound
+}
+
+// RemoveDone deletes all completed items and returns how many were removed
+func (l *FamilyList) RemoveDone() int {
+	pending := l.Items[:0]
+	for _, item := range l.Items {
+		if !item.Done {
+			pending = append(pending, item)
+		}
+	}
+
+	removed := len(l.Items) - len(pending)
+	l.Items = pending
+	if removed > 0 {
+		l.UpdatedAt = time.Now()
+	}
+	return removed
+}
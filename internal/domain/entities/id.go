@@ -0,0 +1,13 @@
+package entities
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// NewID returns a random identifier for stored entities
+func NewID() string {
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
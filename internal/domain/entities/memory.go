@@ -0,0 +1,22 @@
+package entities
+
+import (
+	"time"
+)
+
+// Memory is a short fact the bot keeps across sessions. Facts with UserID
+// equal to SharedSessionUserID belong to the whole chat.
+type Memory struct {
+	ID        string
+	ChatID    int64
+	UserID    int64
+	Fact      string
+	Pending   bool // предложено Claude и ждёт подтверждения
+	Announced bool // пользователю уже показан запрос на подтверждение
+	CreatedAt time.Time
+}
+
+// IsChatWide reports whether the fact belongs to the whole chat
+func (m *Memory) IsChatWide() bool {
+	return m.UserID == SharedSessionUserID
+}
@@ -0,0 +1,68 @@
+package entities
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Поддерживаемые провайдеры языковых моделей
+const (
+	ProviderAnthropic = "anthropic"
+	ProviderOpenAI    = "openai"
+	ProviderOllama    = "ollama"
+)
+
+// ModelRef names a model together with the provider that serves it
+type ModelRef struct {
+	Provider string
+	Model    string
+}
+
+// ParseModelRef parses "provider:model". Only the first colon separates the
+// provider, so Ollama tags such as "ollama:llama3.1:8b" stay intact.
+func ParseModelRef(value string) (ModelRef, error) {
+	provider, model, found := strings.Cut(strings.TrimSpace(value), ":")
+	ref := ModelRef{
+		Provider: strings.ToLower(strings.TrimSpace(provider)),
+		Model:    strings.TrimSpace(model),
+	}
+
+	if !found || ref.Model == "" {
+		return ModelRef{}, fmt.Errorf("%q: expected provider:model", value)
+	}
+
+	switch ref.Provider {
+	case ProviderAnthropic, ProviderOpenAI, ProviderOllama:
+		return ref, nil
+	default:
+		return ModelRef{}, fmt.Errorf("%q: unknown provider %s (expected anthropic, openai or ollama)", value, ref.Provider)
+	}
+}
+
+func (m ModelRef) String() string {
+	return m.Provider + ":" + m.Model
+}
+
+// IsZero reports whether no model is set
+func (m ModelRef) IsZero() bool {
+	return m.Provider == "" && m.Model == ""
+}
+
+// ModelOption is a model chats may switch to with /model, under a short alias
+type ModelOption struct {
+	Alias string // например "fast" или "smart"
+	Model ModelRef
+}
+
+// ModelCatalog is the admin-approved list of models in display order
+type ModelCatalog []ModelOption
+
+// Find returns the option with the alias, ignoring case
+func (c ModelCatalog) Find(alias string) (ModelOption, bool) {
+	for _, option := range c {
+		if strings.EqualFold(option.Alias, alias) {
+			return option, true
+		}
+	}
+	return ModelOption{}, false
+}
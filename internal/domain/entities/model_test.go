@@ -0,0 +1,74 @@
+package entities
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseModelRef(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    ModelRef
+		wantErr string
+	}{
+		{name: "anthropic", value: "anthropic:claude-3-5-sonnet-20241022", want: ModelRef{Provider: "anthropic", Model: "claude-3-5-sonnet-20241022"}},
+		{name: "ollama tag keeps its colon", value: "ollama:llama3.1:8b", want: ModelRef{Provider: "ollama", Model: "llama3.1:8b"}},
+		{name: "spaces and case", value: "  OpenAI : gpt-4o-mini ", want: ModelRef{Provider: "openai", Model: "gpt-4o-mini"}},
+		{name: "no provider", value: "claude-3-5-sonnet", wantErr: "expected provider:model"},
+		{name: "no model", value: "anthropic:", wantErr: "expected provider:model"},
+		{name: "empty", value: "", wantErr: "expected provider:model"},
+		{name: "unknown provider", value: "gemini:pro", wantErr: "unknown provider gemini"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseModelRef(tt.value)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseModelRef() = %+v, want %+v", got, tt.want)
+			}
+			if parsed, _ := ParseModelRef(got.String()); parsed != got {
+				t.Errorf("String() %q does not parse back", got.String())
+			}
+		})
+	}
+}
+
+func TestModelCatalogFind(t *testing.T) {
+	catalog := ModelCatalog{
+		{Alias: "fast", Model: ModelRef{Provider: ProviderAnthropic, Model: "claude-3-5-haiku-20241022"}},
+		{Alias: "local", Model: ModelRef{Provider: ProviderOllama, Model: "llama3.1:8b"}},
+	}
+
+	tests := []struct {
+		alias  string
+		want   string
+		wantOK bool
+	}{
+		{alias: "fast", want: "anthropic:claude-3-5-haiku-20241022", wantOK: true},
+		{alias: "LOCAL", want: "ollama:llama3.1:8b", wantOK: true},
+		{alias: "smart", wantOK: false},
+		{alias: "", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.alias, func(t *testing.T) {
+			option, ok := catalog.Find(tt.alias)
+			if ok != tt.wantOK {
+				t.Fatalf("Find(%q) found = %v, want %v", tt.alias, ok, tt.wantOK)
+			}
+			if ok && option.Model.String() != tt.want {
+				t.Errorf("Find(%q) = %s, want %s", tt.alias, option.Model, tt.want)
+			}
+		})
+	}
+}
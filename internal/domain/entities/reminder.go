@@ -0,0 +1,16 @@
+package entities
+
+import (
+	"time"
+)
+
+type Reminder struct {
+	ID          string
+	ChatID      int64
+	UserID      int64
+	DisplayName string
+	Text        string
+	DueAt       time.Time
+	Attempts    int // неудачные попытки доставки
+	CreatedAt   time.Time
+}
@@ -1,22 +1,55 @@
 package entities
 
 import (
+	"errors"
 	"time"
 )
 
+var (
+	ErrNothingToUndo   = errors.New("no messages to undo")
+	ErrNothingToRetry  = errors.New("no user message to retry")
+	ErrMessageNotFound = errors.New("message not found in history")
+)
+
 type ChatSession struct {
 	ChatID    int64
 	UserID    int64
 	IsActive  bool
-	Messages  []Message
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	StartedBy int64 // пользователь, начавший сессию
+	// ConversationID - ID разговора в архиве, если он уже архивировался или был открыт заново
+	ConversationID string
+	Messages       []Message
+	// LastActivityAt - время последнего сообщения или продления, по нему сессия истекает
+	LastActivityAt time.Time
+	ExpiryWarned   bool // пользователь уже предупреждён о скором завершении
+	// Revision растёт с каждым сохранённым изменением. Запись, подготовленная
+	// по устаревшей копии, отклоняется, и запросы не затирают друг друга.
+	Revision int64
+	// HistoryRevision - ревизия, на которой история была переписана последний раз.
+	// Новые ходы дописываются только к той истории, по которой они готовились.
+	HistoryRevision int64
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
 }
 
 type Message struct {
 	Role      string // "user" or "assistant"
 	Content   string
-	Timestamp time.Time
+	Truncated bool // ответ оборван лимитом токенов и может быть продолжен
+	// TelegramMessageID связывает сообщение истории с сообщением в чате
+	TelegramMessageID int
+	ToolCalls         []ToolCall // инструменты, вызванные при подготовке ответа
+	Model             string     // модель, сгенерировавшая ответ ассистента
+	Thinking          string     // ход рассуждений модели перед ответом, в модель повторно не отправляется
+	Timestamp         time.Time
+}
+
+// ToolCall records a tool Claude used while preparing an answer
+type ToolCall struct {
+	Name    string
+	Input   string
+	Output  string
+	IsError bool
 }
 
 func (s *ChatSession) AddMessage(role, content string) {
@@ -26,6 +59,111 @@ func (s *ChatSession) AddMessage(role, content string) {
 		Timestamp: time.Now(),
 	})
 	s.UpdatedAt = time.Now()
+	s.Touch()
+}
+
+// Touch marks the session as used now, restarting the idle timeout
+func (s *ChatSession) Touch() {
+	s.LastActivityAt = time.Now()
+	s.ExpiryWarned = false
+}
+
+// LastActivity returns when the session was last used. Sessions stored before
+// activity was tracked fall back to their update time.
+func (s *ChatSession) LastActivity() time.Time {
+	if s.LastActivityAt.IsZero() {
+		return s.UpdatedAt
+	}
+	return s.LastActivityAt
+}
+
+// Clone returns a deep copy of the session, so stores can hand out sessions
+// without sharing the history with their callers
+func (s *ChatSession) Clone() *ChatSession {
+	clone := *s
+	clone.Messages = make([]Message, len(s.Messages))
+	for i, msg := range s.Messages {
+		msg.ToolCalls = append([]ToolCall(nil), msg.ToolCalls...)
+		clone.Messages[i] = msg
+	}
+	return &clone
+}
+
+// LastMessage returns the most recent message or nil if the history is empty
+func (s *ChatSession) LastMessage() *Message {
+	if len(s.Messages) == 0 {
+		return nil
+	}
+	return &s.Messages[len(s.Messages)-1]
+}
+
+// RemoveLastMessage drops the most recent message from the history
+func (s *ChatSession) RemoveLastMessage() {
+	if len(s.Messages) == 0 {
+		return
+	}
+	s.Messages = s.Messages[:len(s.Messages)-1]
+	s.UpdatedAt = time.Now()
+}
+
+// UndoLastExchange drops the last user/assistant pair from the history. A user
+// message left without an answer is dropped on its own. The history is either
+// fully updated or left untouched, so the roles keep alternating.
+func (s *ChatSession) UndoLastExchange() error {
+	n := len(s.Messages)
+	switch {
+	case n == 0:
+		return ErrNothingToUndo
+	case s.Messages[n-1].Role == "user":
+		s.Messages = s.Messages[:n-1]
+	case n >= 2 && s.Messages[n-2].Role == "user":
+		s.Messages = s.Messages[:n-2]
+	default:
+		return ErrNothingToUndo
+	}
+
+	s.UpdatedAt = time.Now()
+	return nil
+}
+
+// RewindToLastUserMessage drops the answer to the last user message so it can
+// be sent to Claude again, and returns that message.
+func (s *ChatSession) RewindToLastUserMessage() (*Message, error) {
+	n := len(s.Messages)
+	switch {
+	case n > 0 && s.Messages[n-1].Role == "user":
+		// Ответа ещё нет, повторяем как есть
+	case n >= 2 && s.Messages[n-2].Role == "user":
+		s.Messages = s.Messages[:n-1]
+		s.UpdatedAt = time.Now()
+	default:
+		return nil, ErrNothingToRetry
+	}
+
+	return &s.Messages[len(s.Messages)-1], nil
+}
+
+// RewindToMessage drops the user message with the given Telegram ID and
+// everything after it, so the conversation can continue from an edited
+// version. It returns the Telegram ID of the reply that answered the message,
+// or 0 if the reply is unknown.
+func (s *ChatSession) RewindToMessage(telegramMessageID int) (int, error) {
+	for i, msg := range s.Messages {
+		if msg.Role != "user" || msg.TelegramMessageID != telegramMessageID {
+			continue
+		}
+
+		replyID := 0
+		if i+1 < len(s.Messages) && s.Messages[i+1].Role == "assistant" {
+			replyID = s.Messages[i+1].TelegramMessageID
+		}
+
+		s.Messages = s.Messages[:i]
+		s.UpdatedAt = time.Now()
+		return replyID, nil
+	}
+
+	return 0, ErrMessageNotFound
 }
 
 func (s *ChatSession) GetContextSize() int {
@@ -38,5 +176,11 @@ func (s *ChatSession) GetContextSize() int {
 
 func (s *ChatSession) Reset() {
 	s.Messages = []Message{}
+	s.ConversationID = ""
 	s.UpdatedAt = time.Now()
 }
+
+// IsShared reports whether the session belongs to the whole chat rather than a single user.
+func (s *ChatSession) IsShared() bool {
+	return s.UserID == SharedSessionUserID
+}
@@ -0,0 +1,24 @@
+package entities
+
+// TokenUsage counts the tokens billed for model requests. Cached prompt
+// tokens are reported separately: reads are much cheaper than regular input,
+// writes slightly more expensive.
+type TokenUsage struct {
+	InputTokens      int `json:"input_tokens"`
+	OutputTokens     int `json:"output_tokens"`
+	CacheReadTokens  int `json:"cache_read_tokens"`
+	CacheWriteTokens int `json:"cache_write_tokens"`
+}
+
+// Add accumulates the usage of another request
+func (u *TokenUsage) Add(other TokenUsage) {
+	u.InputTokens += other.InputTokens
+	u.OutputTokens += other.OutputTokens
+	u.CacheReadTokens += other.CacheReadTokens
+	u.CacheWriteTokens += other.CacheWriteTokens
+}
+
+// PromptTokens is the whole prompt size, cached or not
+func (u TokenUsage) PromptTokens() int {
+	return u.InputTokens + u.CacheReadTokens + u.CacheWriteTokens
+}
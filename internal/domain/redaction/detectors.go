@@ -0,0 +1,125 @@
+package redaction
+
+import (
+	"regexp"
+	"unicode"
+)
+
+// Validator checks a match at text[start:end] and rejects false positives
+type Validator func(text string, start, end int) bool
+
+// RegexDetector finds data matching a regular expression. An optional
+// validator rejects false positives.
+type RegexDetector struct {
+	kind     string
+	pattern  *regexp.Regexp
+	validate Validator
+}
+
+func NewRegexDetector(kind string, pattern *regexp.Regexp, validate Validator) *RegexDetector {
+	return &RegexDetector{
+		kind:     kind,
+		pattern:  pattern,
+		validate: validate,
+	}
+}
+
+func (d *RegexDetector) Kind() string {
+	return d.kind
+}
+
+func (d *RegexDetector) Find(text string) [][]int {
+	matches := d.pattern.FindAllStringIndex(text, -1)
+	if d.validate == nil {
+		return matches
+	}
+
+	valid := matches[:0]
+	for _, match := range matches {
+		if d.validate(text, match[0], match[1]) {
+			valid = append(valid, match)
+		}
+	}
+	return valid
+}
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	cardPattern  = regexp.MustCompile(`\b\d(?:[ \-]?\d){12,18}\b`)
+	// Граница слова нужна только номеру без кода страны: в «+79...» её после «+7» нет
+	phonePattern = regexp.MustCompile(`(?:(?:\+\d{1,3}[\s\-]?|\b8[\s\-]?)(?:\(\d{2,4}\)|\d{2,4})|\(\d{2,4}\)|\b\d{2,4})[\s\-]?\d{2,4}[\s\-]?\d{2}[\s\-]?\d{2}\b`)
+	// Российские адреса: «ул. Ленина, д. 5, кв. 12», «проспект Мира 10»
+	ruAddressPattern = regexp.MustCompile(`(?i)(?:ул\.|улиц[аеуы]|пр-т|просп\.|проспект[аеу]?|пер\.|переул[оке]+|бульвар[аеу]?|б-р|шоссе|наб\.|набережн[аяойу]+|пл\.|площад[ьи])\s*[А-ЯЁA-Z0-9][^,\n]{0,40}?,?\s*(?:д\.|дом)?\s*\d+[а-яА-Я]?(?:\s*,?\s*(?:к\.|корп\.|корпус|стр\.)\s*\d+)?(?:\s*,?\s*(?:кв\.|квартира)\s*\d+)?`)
+	enAddressPattern = regexp.MustCompile(`\b\d{1,5}\s+(?:[A-Z][a-z]+\s+){1,3}(?:Street|St|Avenue|Ave|Road|Rd|Boulevard|Blvd|Lane|Ln|Drive|Dr|Court|Ct)\b\.?(?:,?\s*(?:Apt|Suite)\.?\s*\d+)?`)
+)
+
+// DefaultDetectors returns the built-in detectors. Cards run before phones so
+// long digit runs are classified by the Luhn check first.
+func DefaultDetectors() []Detector {
+	return []Detector{
+		NewRegexDetector("EMAIL", emailPattern, nil),
+		NewRegexDetector("CARD", cardPattern, isLuhnValid),
+		NewRegexDetector("PHONE", phonePattern, hasPhoneLength),
+		NewRegexDetector("ADDRESS", ruAddressPattern, nil),
+		NewRegexDetector("ADDRESS", enAddressPattern, nil),
+	}
+}
+
+// isLuhnValid checks the card number checksum
+func isLuhnValid(text string, start, end int) bool {
+	number := text[start:end]
+	sum, count := 0, 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		c := rune(number[i])
+		if !unicode.IsDigit(c) {
+			continue
+		}
+
+		digit := int(c - '0')
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+		count++
+	}
+	return count >= 13 && sum%10 == 0
+}
+
+// hasPhoneLength accepts numbers of 10 to 15 digits, so dates and sums stay
+// untouched. A match that is part of a longer digit sequence is rejected.
+func hasPhoneLength(text string, start, end int) bool {
+	if continuesWithDigits(text[end:]) || continuesWithDigits(reverse(text[:start])) {
+		return false
+	}
+
+	count := 0
+	for _, c := range text[start:end] {
+		if unicode.IsDigit(c) {
+			count++
+		}
+	}
+	return count >= 10 && count <= 15
+}
+
+// continuesWithDigits reports whether text starts with a digit, optionally
+// after a single space or dash
+func continuesWithDigits(text string) bool {
+	if len(text) > 0 && (text[0] == ' ' || text[0] == '-') {
+		text = text[1:]
+	}
+	return len(text) > 0 && text[0] >= '0' && text[0] <= '9'
+}
+
+// reverse returns text with its bytes reversed, to look at the bytes just before a match
+func reverse(text string) string {
+	buf := []byte(text)
+	for i, j := 0, len(buf)-1; i < j; i, j = i+1, j-1 {
+		buf[i], buf[j] = buf[j], buf[i]
+	}
+	return string(buf)
+}
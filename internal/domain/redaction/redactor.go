@@ -0,0 +1,119 @@
+// Package redaction masks personal data in text before it is sent to an
+// external model and restores it in the answers.
+package redaction
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Detector finds one kind of personal data in text
+type Detector interface {
+	// Kind names the data in placeholders, e.g. PHONE gives [PHONE_1]
+	Kind() string
+	// Find returns the [start, end) byte offsets of every match
+	Find(text string) [][]int
+}
+
+// Redactor runs its detectors in order and replaces matches with placeholders
+type Redactor struct {
+	detectors []Detector
+}
+
+func NewRedactor(detectors ...Detector) *Redactor {
+	return &Redactor{
+		detectors: detectors,
+	}
+}
+
+// Redact replaces the personal data found in text with placeholders recorded in the vault
+func (r *Redactor) Redact(text string, vault *Vault) string {
+	for _, detector := range r.detectors {
+		matches := detector.Find(text)
+		if len(matches) == 0 {
+			continue
+		}
+
+		var sb strings.Builder
+		last := 0
+		for _, match := range matches {
+			if match[0] < last {
+				continue
+			}
+			sb.WriteString(text[last:match[0]])
+			sb.WriteString(vault.placeholder(detector.Kind(), text[match[0]:match[1]]))
+			last = match[1]
+		}
+		sb.WriteString(text[last:])
+		text = sb.String()
+	}
+
+	return text
+}
+
+// Vault maps placeholders to the original values within one request. The same
+// value always gets the same placeholder, so Claude can refer to it consistently.
+type Vault struct {
+	originals    map[string]string // метка -> исходное значение
+	placeholders map[string]string // исходное значение -> метка
+	counters     map[string]int
+}
+
+func NewVault() *Vault {
+	return &Vault{
+		originals:    make(map[string]string),
+		placeholders: make(map[string]string),
+		counters:     make(map[string]int),
+	}
+}
+
+// Len returns the number of masked values
+func (v *Vault) Len() int {
+	return len(v.originals)
+}
+
+func (v *Vault) placeholder(kind, original string) string {
+	if placeholder, exists := v.placeholders[original]; exists {
+		return placeholder
+	}
+
+	v.counters[kind]++
+	placeholder := fmt.Sprintf("[%s_%d]", kind, v.counters[kind])
+	v.placeholders[original] = placeholder
+	v.originals[placeholder] = original
+	return placeholder
+}
+
+// Restore puts the original values back in place of the placeholders
+func (v *Vault) Restore(text string) string {
+	return v.replacer(func(original string) string { return original }).Replace(text)
+}
+
+// RestoreJSON puts the original values back into a JSON document, escaping
+// them as string content
+func (v *Vault) RestoreJSON(data json.RawMessage) json.RawMessage {
+	restored := v.replacer(func(original string) string {
+		encoded, _ := json.Marshal(original)
+		return string(encoded[1 : len(encoded)-1])
+	}).Replace(string(data))
+	return json.RawMessage(restored)
+}
+
+func (v *Vault) replacer(encode func(string) string) *strings.Replacer {
+	// Длинные метки первыми, чтобы [PHONE_1] не заменялась внутри [PHONE_10]
+	placeholders := make([]string, 0, len(v.originals))
+	for placeholder := range v.originals {
+		placeholders = append(placeholders, placeholder)
+	}
+	sort.Slice(placeholders, func(i, j int) bool {
+		return len(placeholders[i]) > len(placeholders[j])
+	})
+
+	pairs := make([]string, 0, 2*len(placeholders))
+	for _, placeholder := range placeholders {
+		pairs = append(pairs, placeholder, encode(v.originals[placeholder]))
+	}
+	return strings.NewReplacer(pairs...)
+}
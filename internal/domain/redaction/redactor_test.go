@@ -0,0 +1,153 @@
+package redaction
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+)
+
+func TestRedactRestore(t *testing.T) {
+	redactor := NewRedactor(DefaultDetectors()...)
+
+	tests := []struct {
+		name     string
+		text     string
+		redacted string
+	}{
+		{
+			name:     "phone",
+			text:     "Позвони бабушке +7 912 345-67-89 вечером",
+			redacted: "Позвони бабушке [PHONE_1] вечером",
+		},
+		{
+			name:     "phone starting with 8",
+			text:     "Номер 8 (912) 345-67-89",
+			redacted: "Номер [PHONE_1]",
+		},
+		{
+			name:     "phone with country code and no spaces",
+			text:     "Мой номер +79123456789.",
+			redacted: "Мой номер [PHONE_1].",
+		},
+		{
+			name:     "phone with area code in brackets",
+			text:     "Звони (912) 345-67-89",
+			redacted: "Звони [PHONE_1]",
+		},
+		{
+			name:     "long digit run is not a phone",
+			text:     "Трек 12345678901234567890",
+			redacted: "Трек 12345678901234567890",
+		},
+		{
+			name:     "email",
+			text:     "Пиши на mama.ivanova@example.ru",
+			redacted: "Пиши на [EMAIL_1]",
+		},
+		{
+			name:     "card with valid checksum",
+			text:     "Карта 4111 1111 1111 1111",
+			redacted: "Карта [CARD_1]",
+		},
+		{
+			name:     "digits failing the checksum",
+			text:     "Заказ 4111 1111 1111 1112",
+			redacted: "Заказ 4111 1111 1111 1112",
+		},
+		{
+			name:     "russian address",
+			text:     "Живём на ул. Ленина, д. 5, кв. 12",
+			redacted: "Живём на [ADDRESS_1]",
+		},
+		{
+			name:     "english address",
+			text:     "Ship to 221 Baker Street, Apt 2",
+			redacted: "Ship to [ADDRESS_1]",
+		},
+		{
+			name:     "dates and sums stay",
+			text:     "12.03.2024 потратили 15000 рублей",
+			redacted: "12.03.2024 потратили 15000 рублей",
+		},
+		{
+			name:     "same value same placeholder",
+			text:     "+79123456789 или +79123456789, а ещё +79001112233",
+			redacted: "[PHONE_1] или [PHONE_1], а ещё [PHONE_2]",
+		},
+		{
+			name:     "several kinds",
+			text:     "a@b.co, +79123456789",
+			redacted: "[EMAIL_1], [PHONE_1]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vault := NewVault()
+			redacted := redactor.Redact(tt.text, vault)
+			if redacted != tt.redacted {
+				t.Fatalf("Redact() = %q, want %q", redacted, tt.redacted)
+			}
+			if restored := vault.Restore(redacted); restored != tt.text {
+				t.Errorf("Restore() = %q, want %q", restored, tt.text)
+			}
+		})
+	}
+}
+
+func TestVaultRestore(t *testing.T) {
+	vault := NewVault()
+	for i := 1; i <= 10; i++ {
+		vault.placeholder("PHONE", "+7900000000"+string(rune('0'+i%10)))
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "no placeholders", text: "привет", want: "привет"},
+		{name: "longer placeholder first", text: "[PHONE_10] и [PHONE_1]", want: "+79000000000 и +79000000001"},
+		{name: "unknown placeholder stays", text: "[PHONE_11]", want: "[PHONE_11]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := vault.Restore(tt.text); got != tt.want {
+				t.Errorf("Restore() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVaultRestoreJSON(t *testing.T) {
+	// Значение с кавычками и обратной косой чертой должно остаться корректной строкой JSON
+	redactor := NewRedactor(NewRegexDetector("NAME", regexp.MustCompile(`Иван "Ваня" \\ Петров`), nil))
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "plain JSON", input: `{"text":"без меток"}`, want: "без меток"},
+		{name: "escaped value", input: `{"text":"позвонить [NAME_1]"}`, want: `позвонить Иван "Ваня" \ Петров`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vault := NewVault()
+			redactor.Redact(`Иван "Ваня" \ Петров`, vault)
+
+			restored := vault.RestoreJSON(json.RawMessage(tt.input))
+			var decoded struct {
+				Text string `json:"text"`
+			}
+			if err := json.Unmarshal(restored, &decoded); err != nil {
+				t.Fatalf("restored JSON %s is invalid: %v", restored, err)
+			}
+			if decoded.Text != tt.want {
+				t.Errorf("text = %q, want %q", decoded.Text, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package repositories
+
+import (
+	"telegram-chatbot/internal/domain/entities"
+)
+
+type ArchiveRepository interface {
+	// SaveConversation stores the conversation and (re)indexes it for search
+	SaveConversation(conversation *entities.Conversation) error
+	GetConversation(chatID int64, conversationID string) (*entities.Conversation, error)
+	// Search ranks the user's and the chat-wide conversations by BM25, best first
+	Search(chatID, userID int64, query string, limit int) ([]entities.ConversationMatch, error)
+}
+
+// ArchiveReindexer rewrites the search index of the archive under the current
+// encryption key
+type ArchiveReindexer interface {
+	// ReindexArchive replaces index entries stored in plain text or under an
+	// older key. With dryRun the outdated conversations are only counted.
+	ReindexArchive(dryRun bool) (*ArchiveReindexReport, error)
+}
+
+// ArchiveReindexReport sums up an archive reindex
+type ArchiveReindexReport struct {
+	Scanned   int
+	Reindexed int
+	Failed    []string // разговоры, которые не удалось прочитать или сохранить
+}
@@ -0,0 +1,10 @@
+package repositories
+
+import (
+	"telegram-chatbot/internal/domain/entities"
+)
+
+type ChatSettingsRepository interface {
+	GetSettings(chatID int64) (*entities.ChatSettings, error)
+	SaveSettings(settings *entities.ChatSettings) error
+}
@@ -0,0 +1,11 @@
+package repositories
+
+import (
+	"telegram-chatbot/internal/domain/entities"
+)
+
+type ListRepository interface {
+	GetList(chatID int64, name string) (*entities.FamilyList, error)
+	SaveList(list *entities.FamilyList) error
+	ListNames(chatID int64) ([]string, error)
+}
@@ -0,0 +1,12 @@
+package repositories
+
+import (
+	"telegram-chatbot/internal/domain/entities"
+)
+
+type MemoryRepository interface {
+	SaveMemory(memory *entities.Memory) error
+	// ListMemories returns the user's facts and the chat-wide facts, oldest first
+	ListMemories(chatID, userID int64) ([]entities.Memory, error)
+	DeleteMemory(chatID int64, memoryID string) (bool, error)
+}
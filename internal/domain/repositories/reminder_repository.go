@@ -0,0 +1,15 @@
+package repositories
+
+import (
+	"telegram-chatbot/internal/domain/entities"
+	"time"
+)
+
+type ReminderRepository interface {
+	SaveReminder(reminder *entities.Reminder) error
+	ListReminders(chatID, userID int64) ([]entities.Reminder, error)
+	DeleteReminder(chatID, userID int64, reminderID string) (bool, error)
+	// PopDueReminders removes and returns reminders due at or before now.
+	// Each reminder is returned to exactly one caller.
+	PopDueReminders(now time.Time) ([]entities.Reminder, error)
+}
@@ -1,12 +1,49 @@
 package repositories
 
 import (
+	"errors"
 	"telegram-chatbot/internal/domain/entities"
+	"time"
 )
 
+// ErrSessionChanged means another request stored the session after it was read
+var ErrSessionChanged = errors.New("session was changed by another request")
+
+// SessionRepository stores chat sessions. Writes are conditional on the
+// session revision: a write made from an outdated copy fails with
+// ErrSessionChanged instead of overwriting newer changes. Successful writes
+// update the revisions of the passed session.
 type SessionRepository interface {
 	GetSession(chatID, userID int64) (*entities.ChatSession, error)
+	// SaveSession stores the whole session, rewriting the history
 	SaveSession(session *entities.ChatSession) error
+	// AppendMessage adds messages to the end of the stored history and records
+	// the session activity. The rest of the history is not rewritten, so
+	// concurrent turns do not overwrite each other's messages. It fails if the
+	// session was ended or its history rewritten since it was read.
+	AppendMessage(session *entities.ChatSession, messages ...entities.Message) error
+	// UpdateLastMessage stores changes to the last message of the history
+	UpdateLastMessage(session *entities.ChatSession) error
+	// UpdateActivity stores the last activity time and the expiry warning flag
+	// without touching the history
+	UpdateActivity(session *entities.ChatSession) error
 	DeleteSession(chatID, userID int64) error
 	IsSessionActive(chatID, userID int64) bool
+	// ListIdleSessions returns active sessions last used before idleSince
+	ListIdleSessions(idleSince time.Time) ([]*entities.ChatSession, error)
+}
+
+// SessionMigrator rewrites stored sessions in the current storage format
+type SessionMigrator interface {
+	// MigrateSessions upgrades every stored session. With dryRun the sessions
+	// are only checked and counted.
+	MigrateSessions(dryRun bool) (*SessionMigrationReport, error)
+}
+
+// SessionMigrationReport sums up a bulk session migration
+type SessionMigrationReport struct {
+	Scanned  int
+	Migrated int
+	Current  int      // уже в текущем формате
+	Failed   []string // ключи, которые не удалось прочитать или сохранить
 }
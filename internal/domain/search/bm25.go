@@ -0,0 +1,229 @@
+// Package search implements the text analysis and BM25 ranking used to search
+// archived conversations without an external search service.
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// k1 and b are the usual BM25 parameters
+	k1 = 1.2
+	b  = 0.75
+
+	// stemLength truncates words to a common prefix, a cheap stemmer that
+	// works well enough for Russian and English word forms
+	stemLength = 6
+	minTermLen = 2
+)
+
+var stopWords = map[string]bool{
+	"и": true, "в": true, "во": true, "не": true, "что": true, "он": true, "на": true, "я": true,
+	"с": true, "со": true, "как": true, "а": true, "то": true, "все": true, "она": true, "так": true,
+	"его": true, "но": true, "да": true, "ты": true, "к": true, "у": true, "же": true, "вы": true,
+	"за": true, "бы": true, "по": true, "только": true, "ее": true, "мне": true, "было": true,
+	"вот": true, "от": true, "меня": true, "еще": true, "нет": true, "о": true, "из": true, "ему": true,
+	"это": true, "для": true, "мы": true, "или": true, "ли": true, "если": true, "уже": true,
+	"the": true, "a": true, "an": true, "and": true, "or": true, "of": true, "to": true, "in": true,
+	"is": true, "it": true, "for": true, "on": true, "with": true, "that": true, "this": true,
+}
+
+// Tokenize splits text into normalized search terms
+func Tokenize(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		word = strings.ReplaceAll(word, "ё", "е")
+		if stopWords[word] {
+			continue
+		}
+
+		runes := []rune(word)
+		if len(runes) < minTermLen {
+			continue
+		}
+		if len(runes) > stemLength {
+			runes = runes[:stemLength]
+		}
+		terms = append(terms, string(runes))
+	}
+
+	return terms
+}
+
+// TermFrequencies counts the terms of a document and returns its length in terms
+func TermFrequencies(text string) (map[string]int, int) {
+	terms := Tokenize(text)
+	frequencies := make(map[string]int, len(terms))
+	for _, term := range terms {
+		frequencies[term]++
+	}
+	return frequencies, len(terms)
+}
+
+// Score returns the BM25 contribution of a single query term to a document.
+// tf is the term frequency in the document, df the number of documents with
+// the term, n the number of documents.
+func Score(tf, df, docLen int, avgDocLen float64, n int) float64 {
+	if tf == 0 || df == 0 || n == 0 {
+		return 0
+	}
+	if avgDocLen == 0 {
+		avgDocLen = 1
+	}
+
+	idf := math.Log(1 + (float64(n)-float64(df)+0.5)/(float64(df)+0.5))
+	norm := float64(tf) * (k1 + 1) / (float64(tf) + k1*(1-b+b*float64(docLen)/avgDocLen))
+	return idf * norm
+}
+
+// Snippet returns a fragment of text around the first occurrence of any of the
+// query terms, at most maxLen characters long
+func Snippet(text string, queryTerms []string, maxLen int) string {
+	runes := []rune(text)
+	if len(runes) <= maxLen {
+		return text
+	}
+
+	lower := []rune(strings.ReplaceAll(strings.ToLower(text), "ё", "е"))
+	start := 0
+	for _, term := range queryTerms {
+		if idx := indexRunes(lower, []rune(term)); idx >= 0 {
+			start = idx
+			break
+		}
+	}
+
+	// Показываем немного текста перед найденным словом
+	start -= maxLen / 4
+	if start < 0 {
+		start = 0
+	}
+	end := start + maxLen
+	if end > len(runes) {
+		end = len(runes)
+		start = end - maxLen
+	}
+
+	// Не режем слова на краях фрагмента, если в нём есть пробелы
+	wordStart, wordEnd := start, end
+	for wordStart > 0 && wordStart < wordEnd && !unicode.IsSpace(runes[wordStart-1]) {
+		wordStart++
+	}
+	for wordEnd < len(runes) && wordEnd > wordStart && !unicode.IsSpace(runes[wordEnd]) {
+		wordEnd--
+	}
+	if wordStart < wordEnd {
+		start, end = wordStart, wordEnd
+	}
+
+	snippet := strings.TrimSpace(string(runes[start:end]))
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(runes) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+func indexRunes(haystack, needle []rune) int {
+	for i := 0; i+len(needle) <= len(haystack); i++ {
+		match := true
+		for j := range needle {
+			if haystack[i+j] != needle[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return i
+		}
+	}
+	return -1
+}
+
+// Result is a ranked document
+type Result struct {
+	DocID string
+	Score float64
+}
+
+// Rank scores documents against the query by BM25, best first. postings maps
+// every query term to its frequency per document, docLens holds the length of
+// every indexed document.
+func Rank(postings map[string]map[string]int, docLens map[string]int) []Result {
+	if len(docLens) == 0 {
+		return nil
+	}
+
+	total := 0
+	for _, length := range docLens {
+		total += length
+	}
+	avgDocLen := float64(total) / float64(len(docLens))
+
+	scores := make(map[string]float64)
+	for _, docs := range postings {
+		for docID, tf := range docs {
+			scores[docID] += Score(tf, len(docs), docLens[docID], avgDocLen, len(docLens))
+		}
+	}
+
+	results := make([]Result, 0, len(scores))
+	for docID, score := range scores {
+		results = append(results, Result{DocID: docID, Score: score})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].DocID < results[j].DocID
+	})
+
+	return results
+}
+
+// Restrict keeps only the documents the reader may see. Ranking runs on the
+// result, so document frequencies and the average length are not computed
+// over documents the reader has no access to.
+func Restrict(postings map[string]map[string]int, docLens map[string]int, visible func(docID string) bool) (map[string]map[string]int, map[string]int) {
+	lengths := make(map[string]int, len(docLens))
+	for docID, length := range docLens {
+		if visible(docID) {
+			lengths[docID] = length
+		}
+	}
+
+	restricted := make(map[string]map[string]int, len(postings))
+	for term, docs := range postings {
+		kept := make(map[string]int, len(docs))
+		for docID, tf := range docs {
+			if _, ok := lengths[docID]; ok {
+				kept[docID] = tf
+			}
+		}
+		restricted[term] = kept
+	}
+
+	return restricted, lengths
+}
+
+// UniqueTerms tokenizes a query and drops repeated terms
+func UniqueTerms(query string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, term := range Tokenize(query) {
+		if !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
@@ -0,0 +1,198 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{name: "stop words and short words", text: "Я и ты в парке", want: []string{"парке"}},
+		{name: "stems word forms", text: "Покупки, покупками и ПОКУПКА", want: []string{"покупк", "покупк", "покупк"}},
+		{name: "yo is e", text: "Ёлка и елка", want: []string{"елка", "елка"}},
+		{name: "english and digits", text: "The shopping list for 2024", want: []string{"shoppi", "list", "2024"}},
+		{name: "punctuation only", text: "?!…", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Tokenize(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Tokenize() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		name   string
+		higher [5]float64 // tf, df, docLen, avgDocLen, n
+		lower  [5]float64
+	}{
+		{name: "more occurrences", higher: [5]float64{3, 1, 10, 10, 5}, lower: [5]float64{1, 1, 10, 10, 5}},
+		{name: "rarer term", higher: [5]float64{1, 1, 10, 10, 5}, lower: [5]float64{1, 4, 10, 10, 5}},
+		{name: "shorter document", higher: [5]float64{1, 1, 5, 10, 5}, lower: [5]float64{1, 1, 20, 10, 5}},
+	}
+
+	score := func(args [5]float64) float64 {
+		return Score(int(args[0]), int(args[1]), int(args[2]), args[3], int(args[4]))
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if high, low := score(tt.higher), score(tt.lower); high <= low {
+				t.Errorf("score %v = %f, not above %v = %f", tt.higher, high, tt.lower, low)
+			}
+		})
+	}
+
+	for _, zero := range [][5]float64{{0, 1, 10, 10, 5}, {1, 0, 10, 10, 5}, {1, 1, 10, 10, 0}} {
+		if got := score(zero); got != 0 {
+			t.Errorf("Score(%v) = %f, want 0", zero, got)
+		}
+	}
+}
+
+// index builds postings and lengths for the query over the documents
+func index(docs map[string]string, query string) (map[string]map[string]int, map[string]int) {
+	postings := make(map[string]map[string]int)
+	docLens := make(map[string]int)
+	terms := UniqueTerms(query)
+	for _, term := range terms {
+		postings[term] = make(map[string]int)
+	}
+	for id, text := range docs {
+		frequencies, length := TermFrequencies(text)
+		docLens[id] = length
+		for _, term := range terms {
+			if tf := frequencies[term]; tf > 0 {
+				postings[term][id] = tf
+			}
+		}
+	}
+	return postings, docLens
+}
+
+func TestRank(t *testing.T) {
+	docs := map[string]string{
+		"milk":    "Купить молоко, хлеб и сыр",
+		"milk2":   "Молоко молоко молоко: сравнили цены на молоко",
+		"trip":    "Планируем поездку на дачу в субботу",
+		"recipes": "Рецепт блинов: молоко, мука, яйца, сахар, соль, масло, сода и немного терпения",
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{name: "frequent term first", query: "молоко", want: []string{"milk2", "milk", "recipes"}},
+		{name: "word form", query: "поездки", want: []string{"trip"}},
+		{name: "several terms", query: "молоко хлеб", want: []string{"milk", "milk2", "recipes"}},
+		{name: "nothing found", query: "самолёт", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			postings, docLens := index(docs, tt.query)
+			got := []string{}
+			for _, result := range Rank(postings, docLens) {
+				got = append(got, result.DocID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Rank() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if results := Rank(map[string]map[string]int{"x": {}}, nil); results != nil {
+		t.Errorf("Rank() over an empty index = %v, want nil", results)
+	}
+}
+
+func TestRestrict(t *testing.T) {
+	docs := map[string]string{
+		"mine":    "Подарок маме на день рождения",
+		"shared":  "Подарок учителю, подарок тренеру",
+		"private": "Подарок подарок подарок жене",
+	}
+	postings, docLens := index(docs, "подарок")
+
+	tests := []struct {
+		name        string
+		visible     map[string]bool
+		wantRanked  []string
+		wantLengths map[string]int
+	}{
+		{
+			name:        "all visible",
+			visible:     map[string]bool{"mine": true, "shared": true, "private": true},
+			wantRanked:  []string{"private", "shared", "mine"},
+			wantLengths: docLens,
+		},
+		{
+			name:        "hidden document is not ranked",
+			visible:     map[string]bool{"mine": true, "shared": true},
+			wantRanked:  []string{"shared", "mine"},
+			wantLengths: map[string]int{"mine": docLens["mine"], "shared": docLens["shared"]},
+		},
+		{
+			name:        "nothing visible",
+			visible:     map[string]bool{},
+			wantRanked:  []string{},
+			wantLengths: map[string]int{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restricted, lengths := Restrict(postings, docLens, func(docID string) bool { return tt.visible[docID] })
+			if !reflect.DeepEqual(lengths, tt.wantLengths) {
+				t.Errorf("lengths = %v, want %v", lengths, tt.wantLengths)
+			}
+
+			got := []string{}
+			for _, result := range Rank(restricted, lengths) {
+				got = append(got, result.DocID)
+			}
+			if !reflect.DeepEqual(got, tt.wantRanked) {
+				t.Errorf("ranked = %q, want %q", got, tt.wantRanked)
+			}
+		})
+	}
+
+	// Скрытые документы не влияют на оценку видимых: df считается без них
+	restricted, lengths := Restrict(postings, docLens, func(docID string) bool { return docID == "mine" })
+	only, _ := index(map[string]string{"mine": docs["mine"]}, "подарок")
+	want := Rank(only, map[string]int{"mine": docLens["mine"]})
+	if got := Rank(restricted, lengths); !reflect.DeepEqual(got, want) {
+		t.Errorf("restricted score = %v, want %v as if the others did not exist", got, want)
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	long := "Вчера обсуждали, что подарить бабушке на юбилей, и решили купить плед и большой торт со свечами"
+
+	tests := []struct {
+		name   string
+		text   string
+		terms  []string
+		maxLen int
+		want   string
+	}{
+		{name: "short text as is", text: "Купить хлеб", terms: []string{"хлеб"}, maxLen: 50, want: "Купить хлеб"},
+		{name: "around the term", text: long, terms: []string{"плед"}, maxLen: 30, want: "…купить плед и большой торт со…"},
+		{name: "start when not found", text: long, terms: []string{"самолет"}, maxLen: 20, want: "Вчера обсуждали, что…"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Snippet(tt.text, tt.terms, tt.maxLen); got != tt.want {
+				t.Errorf("Snippet() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
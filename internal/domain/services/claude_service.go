@@ -1,9 +1,55 @@
 package services
 
 import (
+	"context"
 	"telegram-chatbot/internal/domain/entities"
 )
 
+// StopReasonMaxTokens is reported when the reply was cut off by the token limit
+const StopReasonMaxTokens = "max_tokens"
+
+// Response is a reply generated by Claude
+type Response struct {
+	Text       string
+	StopReason string
+	ToolCalls  []entities.ToolCall
+	// Model is the "provider:model" that produced the answer
+	Model string
+	// Usage sums the tokens of all requests made for the answer
+	Usage entities.TokenUsage
+	// Thinking is the model's reasoning before the answer, if it was enabled
+	Thinking string
+	// Continuable is set when the model can continue the reply from where it
+	// stopped. Models without assistant prefill restate it instead.
+	Continuable bool
+}
+
+// Truncated reports whether the reply was cut off and can be continued
+func (r *Response) Truncated() bool {
+	return r.StopReason == StopReasonMaxTokens && r.Continuable
+}
+
 type ClaudeService interface {
-	GenerateResponse(messages []entities.Message) (string, error)
+	// GenerateResponse generates the next assistant turn. If the last message
+	// is an assistant one, Claude continues it instead of starting a new turn.
+	GenerateResponse(ctx context.Context, messages []entities.Message) (*Response, error)
+	// GenerateOneShot answers a single prompt without tools, persona, memories
+	// or thinking, so parsing a request has no side effects
+	GenerateOneShot(ctx context.Context, prompt string) (*Response, error)
+	// CanThink reports whether the chat's model supports extended thinking
+	CanThink(chatID int64) bool
+}
+
+type extendedThinkingKey struct{}
+
+// WithExtendedThinking asks for extended thinking on this answer, regardless
+// of the chat setting
+func WithExtendedThinking(ctx context.Context) context.Context {
+	return context.WithValue(ctx, extendedThinkingKey{}, true)
+}
+
+// ExtendedThinkingRequested reports whether the answer was asked to think first
+func ExtendedThinkingRequested(ctx context.Context) bool {
+	requested, _ := ctx.Value(extendedThinkingKey{}).(bool)
+	return requested
 }
@@ -0,0 +1,15 @@
+package services
+
+import (
+	"context"
+	"telegram-chatbot/internal/domain/entities"
+	"time"
+)
+
+// Notifier delivers messages that are not replies to a user action
+type Notifier interface {
+	SendReminder(ctx context.Context, reminder entities.Reminder) error
+	// SendSessionExpiryWarning offers to keep a session that will end at expiresAt
+	SendSessionExpiryWarning(ctx context.Context, session entities.ChatSession, expiresAt time.Time) error
+	SendSessionExpired(ctx context.Context, session entities.ChatSession) error
+}
@@ -0,0 +1,21 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrReminderNotUnderstood is returned when the request has no recognizable time
+var ErrReminderNotUnderstood = errors.New("reminder time not understood")
+
+// ParsedReminder is a reminder extracted from a natural-language request
+type ParsedReminder struct {
+	Text  string
+	DueAt time.Time
+}
+
+type ReminderParser interface {
+	// ParseReminder extracts what to remind about and when, relative to now
+	ParseReminder(ctx context.Context, request string, now time.Time) (*ParsedReminder, error)
+}
@@ -0,0 +1,16 @@
+package services
+
+import "time"
+
+// RuntimeSettings are the limits and prompts that can change while the bot
+// is running, so they are read on every use instead of once at startup
+type RuntimeSettings interface {
+	// SystemPrompt returns the persona configured for the chat, or an empty
+	// string for the built-in prompt
+	SystemPrompt(chatID int64) string
+	MaxContextSize() int
+	MaxResponseTokens() int
+	ThinkingBudget() int
+	SessionIdleTimeout() time.Duration
+	SessionExpiryWarning() time.Duration
+}
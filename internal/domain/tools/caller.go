@@ -0,0 +1,24 @@
+package tools
+
+import (
+	"context"
+)
+
+// Caller identifies the chat and user a tool is called for
+type Caller struct {
+	ChatID int64
+	UserID int64
+}
+
+type callerKey struct{}
+
+// WithCaller attaches the caller to the context passed to tool handlers
+func WithCaller(ctx context.Context, caller Caller) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// CallerFromContext returns the caller, if the tool is called within a chat
+func CallerFromContext(ctx context.Context) (Caller, bool) {
+	caller, ok := ctx.Value(callerKey{}).(Caller)
+	return caller, ok
+}
@@ -0,0 +1,73 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// Handler executes a tool call. input is the JSON object produced by Claude
+// according to the tool's schema, the returned text is sent back as the result.
+type Handler func(ctx context.Context, input json.RawMessage) (string, error)
+
+// Tool is a function Claude can call while answering
+type Tool struct {
+	Name        string
+	Description string
+	InputSchema json.RawMessage // JSON Schema of the input object
+	Handler     Handler
+}
+
+// Registry holds the tools available to Claude
+type Registry struct {
+	tools map[string]Tool
+	order []string
+	mutex sync.RWMutex
+}
+
+func NewRegistry(tools ...Tool) *Registry {
+	registry := &Registry{
+		tools: make(map[string]Tool),
+	}
+	for _, tool := range tools {
+		registry.Register(tool)
+	}
+	return registry
+}
+
+// Register adds a tool or replaces a tool with the same name
+func (r *Registry) Register(tool Tool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.tools[tool.Name]; !exists {
+		r.order = append(r.order, tool.Name)
+	}
+	r.tools[tool.Name] = tool
+}
+
+// List returns the registered tools in registration order
+func (r *Registry) List() []Tool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	tools := make([]Tool, 0, len(r.order))
+	for _, name := range r.order {
+		tools = append(tools, r.tools[name])
+	}
+	return tools
+}
+
+// Execute runs the named tool
+func (r *Registry) Execute(ctx context.Context, name string, input json.RawMessage) (string, error) {
+	r.mutex.RLock()
+	tool, exists := r.tools[name]
+	r.mutex.RUnlock()
+
+	if !exists {
+		return "", fmt.Errorf("unknown tool: %s", name)
+	}
+
+	return tool.Handler(ctx, input)
+}
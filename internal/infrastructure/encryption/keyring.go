@@ -0,0 +1,187 @@
+// Package encryption encrypts stored records with AES-GCM.
+package encryption
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// recordPrefix marks encrypted records: "enc:<key id>:" followed by nonce and ciphertext
+const recordPrefix = "enc:"
+
+// indexContext separates the search index key from the encryption key
+const indexContext = "search-index"
+
+// indexTokenSize is the length of the MAC kept in an index token
+const indexTokenSize = 16
+
+var ErrUnknownKey = errors.New("record is encrypted with an unknown key")
+
+// Keyring holds the keys used to encrypt and decrypt records. New records are
+// encrypted with the first key; the others are kept to read older records
+// after a key rotation. An empty keyring stores records as plain text.
+type Keyring struct {
+	currentID string
+	ciphers   map[string]cipher.AEAD
+	// Ключи индекса поиска в порядке списка, текущий первый
+	indexKeys []indexKey
+}
+
+type indexKey struct {
+	id  string
+	key []byte
+}
+
+// NewKeyring parses a key list in the form "id:base64key", one key per line or
+// comma-separated, the current key first. Keys must be 32 bytes (AES-256).
+func NewKeyring(spec string) (*Keyring, error) {
+	keyring := &Keyring{
+		ciphers: make(map[string]cipher.AEAD),
+	}
+
+	entries := strings.FieldsFunc(spec, func(r rune) bool {
+		return r == ',' || r == '\n' || r == '\r'
+	})
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+
+		id, encodedKey, ok := strings.Cut(entry, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("invalid encryption key entry: expected id:base64key")
+		}
+		if _, exists := keyring.ciphers[id]; exists {
+			return nil, fmt.Errorf("duplicate encryption key id %q", id)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encodedKey))
+		if err != nil {
+			return nil, fmt.Errorf("invalid encryption key %q: %w", id, err)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("invalid encryption key %q: must be 32 bytes, got %d", id, len(key))
+		}
+
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid encryption key %q: %w", id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("invalid encryption key %q: %w", id, err)
+		}
+
+		if keyring.currentID == "" {
+			keyring.currentID = id
+		}
+		keyring.ciphers[id] = aead
+
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(indexContext))
+		keyring.indexKeys = append(keyring.indexKeys, indexKey{id: id, key: mac.Sum(nil)})
+	}
+
+	return keyring, nil
+}
+
+// Enabled reports whether new records are encrypted
+func (k *Keyring) Enabled() bool {
+	return k != nil && k.currentID != ""
+}
+
+// Encrypt seals the record with the current key. associatedData binds the
+// ciphertext to its location, so a record copied under another key fails to decrypt.
+func (k *Keyring) Encrypt(plaintext, associatedData []byte) ([]byte, error) {
+	if !k.Enabled() {
+		return plaintext, nil
+	}
+
+	aead := k.ciphers[k.currentID]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	record := make([]byte, 0, len(recordPrefix)+len(k.currentID)+1+len(nonce)+len(plaintext)+aead.Overhead())
+	record = append(record, recordPrefix...)
+	record = append(record, k.currentID...)
+	record = append(record, ':')
+	record = append(record, nonce...)
+	return aead.Seal(record, nonce, plaintext, associatedData), nil
+}
+
+// Decrypt opens a record sealed by Encrypt. Records written before encryption
+// was enabled are returned unchanged.
+func (k *Keyring) Decrypt(record, associatedData []byte) ([]byte, error) {
+	if !bytes.HasPrefix(record, []byte(recordPrefix)) {
+		return record, nil
+	}
+
+	rest := record[len(recordPrefix):]
+	separator := bytes.IndexByte(rest, ':')
+	if separator < 0 {
+		return nil, fmt.Errorf("malformed encrypted record")
+	}
+
+	id := string(rest[:separator])
+	var aead cipher.AEAD
+	if k != nil {
+		aead = k.ciphers[id]
+	}
+	if aead == nil {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, id)
+	}
+
+	sealed := rest[separator+1:]
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("malformed encrypted record")
+	}
+
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], associatedData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt record with key %q: %w", id, err)
+	}
+
+	return plaintext, nil
+}
+
+// IndexToken blinds a search term with the current key, so the index can be
+// looked up by term without storing the term itself. An empty keyring
+// returns the term unchanged.
+func (k *Keyring) IndexToken(term string) string {
+	if !k.Enabled() {
+		return term
+	}
+	return k.indexKeys[0].token(term)
+}
+
+// IndexTokens returns the tokens a term may be indexed under: one per key,
+// the current first, followed by the plain term written before encryption
+// was enabled.
+func (k *Keyring) IndexTokens(term string) []string {
+	if k == nil {
+		return []string{term}
+	}
+
+	tokens := make([]string, 0, len(k.indexKeys)+1)
+	for _, key := range k.indexKeys {
+		tokens = append(tokens, key.token(term))
+	}
+	return append(tokens, term)
+}
+
+func (k indexKey) token(term string) string {
+	mac := hmac.New(sha256.New, k.key)
+	mac.Write([]byte(term))
+	return k.id + ":" + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:indexTokenSize])
+}
@@ -0,0 +1,259 @@
+package encryption
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const (
+	keyA = "a:MDEyMzQ1Njc4OTAxMjM0NTY3ODkwMTIzNDU2Nzg5MDE="
+	keyB = "b:YWJjZGVmYWJjZGVmYWJjZGVmYWJjZGVmYWJjZGVmYWI="
+)
+
+func mustKeyring(t *testing.T, spec string) *Keyring {
+	t.Helper()
+	keyring, err := NewKeyring(spec)
+	if err != nil {
+		t.Fatalf("NewKeyring(%q): %v", spec, err)
+	}
+	return keyring
+}
+
+func TestNewKeyring(t *testing.T) {
+	tests := []struct {
+		name        string
+		spec        string
+		wantEnabled bool
+		wantErr     string
+	}{
+		{name: "empty", spec: "", wantEnabled: false},
+		{name: "comments only", spec: "# ключи\n\n", wantEnabled: false},
+		{name: "one key", spec: keyA, wantEnabled: true},
+		{name: "comma separated", spec: keyB + "," + keyA, wantEnabled: true},
+		{name: "one per line", spec: keyB + "\n# старый\n" + keyA + "\n", wantEnabled: true},
+		{name: "missing id", spec: ":MDEyMzQ1Njc4OTAxMjM0NTY3ODkwMTIzNDU2Nzg5MDE=", wantErr: "expected id:base64key"},
+		{name: "not base64", spec: "a:!!!", wantErr: "invalid encryption key"},
+		{name: "short key", spec: "a:c2hvcnQ=", wantErr: "must be 32 bytes"},
+		{name: "duplicate id", spec: keyA + "," + keyA, wantErr: "duplicate encryption key id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyring, err := NewKeyring(tt.spec)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if keyring.Enabled() != tt.wantEnabled {
+				t.Errorf("Enabled() = %v, want %v", keyring.Enabled(), tt.wantEnabled)
+			}
+		})
+	}
+}
+
+func TestKeyringDecrypt(t *testing.T) {
+	plaintext := []byte(`{"content":"секрет"}`)
+	associatedData := []byte("session:1:2:messages")
+
+	sealedA, err := mustKeyring(t, keyA).Encrypt(plaintext, associatedData)
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	if !bytes.HasPrefix(sealedA, []byte("enc:a:")) || bytes.Contains(sealedA, plaintext) {
+		t.Fatalf("record %q is not sealed with key a", sealedA)
+	}
+
+	tests := []struct {
+		name           string
+		keyring        *Keyring
+		record         []byte
+		associatedData []byte
+		want           []byte
+		wantErr        error
+		wantErrText    string
+	}{
+		{
+			name:           "same key",
+			keyring:        mustKeyring(t, keyA),
+			record:         sealedA,
+			associatedData: associatedData,
+			want:           plaintext,
+		},
+		{
+			name:           "old key after rotation",
+			keyring:        mustKeyring(t, keyB+","+keyA),
+			record:         sealedA,
+			associatedData: associatedData,
+			want:           plaintext,
+		},
+		{
+			name:           "legacy plain record",
+			keyring:        mustKeyring(t, keyA),
+			record:         plaintext,
+			associatedData: associatedData,
+			want:           plaintext,
+		},
+		{
+			name:           "legacy plain record without keys",
+			keyring:        mustKeyring(t, ""),
+			record:         plaintext,
+			associatedData: associatedData,
+			want:           plaintext,
+		},
+		{
+			name:           "key removed from the list",
+			keyring:        mustKeyring(t, keyB),
+			record:         sealedA,
+			associatedData: associatedData,
+			wantErr:        ErrUnknownKey,
+		},
+		{
+			name:           "no keys",
+			keyring:        nil,
+			record:         sealedA,
+			associatedData: associatedData,
+			wantErr:        ErrUnknownKey,
+		},
+		{
+			name:           "record moved to another session",
+			keyring:        mustKeyring(t, keyA),
+			record:         sealedA,
+			associatedData: []byte("session:1:3:messages"),
+			wantErrText:    "failed to decrypt record",
+		},
+		{
+			name:           "malformed record",
+			keyring:        mustKeyring(t, keyA),
+			record:         []byte("enc:a"),
+			associatedData: associatedData,
+			wantErrText:    "malformed encrypted record",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.keyring.Decrypt(tt.record, tt.associatedData)
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.wantErrText != "":
+				if err == nil || !strings.Contains(err.Error(), tt.wantErrText) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErrText)
+				}
+			case err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case !bytes.Equal(got, tt.want):
+				t.Errorf("Decrypt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyringEncrypt(t *testing.T) {
+	plaintext := []byte("привет")
+
+	tests := []struct {
+		name       string
+		spec       string
+		wantPrefix string
+	}{
+		{name: "no keys stores plain text", spec: "", wantPrefix: "привет"},
+		{name: "first key is current", spec: keyB + "," + keyA, wantPrefix: "enc:b:"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyring := mustKeyring(t, tt.spec)
+			record, err := keyring.Encrypt(plaintext, nil)
+			if err != nil {
+				t.Fatalf("encrypt: %v", err)
+			}
+			if !bytes.HasPrefix(record, []byte(tt.wantPrefix)) {
+				t.Errorf("record = %q, want prefix %q", record, tt.wantPrefix)
+			}
+
+			opened, err := keyring.Decrypt(record, nil)
+			if err != nil || !bytes.Equal(opened, plaintext) {
+				t.Errorf("Decrypt() = %q, %v, want %q", opened, err, plaintext)
+			}
+		})
+	}
+
+	// Случайный nonce: одинаковые записи не совпадают
+	keyring := mustKeyring(t, keyA)
+	first, _ := keyring.Encrypt(plaintext, nil)
+	second, _ := keyring.Encrypt(plaintext, nil)
+	if bytes.Equal(first, second) {
+		t.Error("two encryptions of the same record are equal")
+	}
+}
+
+func TestKeyringIndexTokens(t *testing.T) {
+	plain := mustKeyring(t, "")
+	current := mustKeyring(t, keyA)
+	rotated := mustKeyring(t, keyB+","+keyA)
+
+	tests := []struct {
+		name       string
+		keyring    *Keyring
+		wantToken  string
+		wantTokens []string
+	}{
+		{
+			name:       "no keys",
+			keyring:    plain,
+			wantToken:  "молок",
+			wantTokens: []string{"молок"},
+		},
+		{
+			name:       "nil keyring",
+			keyring:    nil,
+			wantToken:  "молок",
+			wantTokens: []string{"молок"},
+		},
+		{
+			name:       "one key",
+			keyring:    current,
+			wantToken:  current.IndexToken("молок"),
+			wantTokens: []string{current.IndexToken("молок"), "молок"},
+		},
+		{
+			name:       "after rotation",
+			keyring:    rotated,
+			wantToken:  rotated.IndexToken("молок"),
+			wantTokens: []string{rotated.IndexToken("молок"), current.IndexToken("молок"), "молок"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.keyring.IndexToken("молок"); got != tt.wantToken {
+				t.Errorf("IndexToken() = %q, want %q", got, tt.wantToken)
+			}
+			got := tt.keyring.IndexTokens("молок")
+			if strings.Join(got, " ") != strings.Join(tt.wantTokens, " ") {
+				t.Errorf("IndexTokens() = %q, want %q", got, tt.wantTokens)
+			}
+		})
+	}
+
+	token := current.IndexToken("молок")
+	if !strings.HasPrefix(token, "a:") || strings.Contains(token, "молок") {
+		t.Errorf("token %q does not hide the term", token)
+	}
+	if token == current.IndexToken("хлеб") {
+		t.Error("different terms have the same token")
+	}
+	if token == rotated.IndexToken("молок") {
+		t.Error("different keys give the same token")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"sync/atomic"
+	"telegram-chatbot/internal/infrastructure/services"
 	"telegram-chatbot/internal/infrastructure/telegram"
 	"time"
 
@@ -18,13 +19,14 @@ import (
 type Service struct {
 	router *gin.Engine
 	bot    *telegram.Bot
+	usage  *services.UsageStats
 	logger *zap.Logger
 	port   string
 	ready  atomic.Bool
 }
 
 // NewHealthCheckService creates a new health check service
-func NewHealthCheckService(bot *telegram.Bot, logger *zap.Logger, port string) *Service {
+func NewHealthCheckService(bot *telegram.Bot, usage *services.UsageStats, logger *zap.Logger, port string) *Service {
 	gin.SetMode(gin.ReleaseMode)
 	router := gin.New()
 	router.Use(gin.Recovery())
@@ -32,6 +34,7 @@ func NewHealthCheckService(bot *telegram.Bot, logger *zap.Logger, port string) *
 	service := &Service{
 		router: router,
 		bot:    bot,
+		usage:  usage,
 		logger: logger,
 		port:   port,
 	}
@@ -42,6 +45,7 @@ func NewHealthCheckService(bot *telegram.Bot, logger *zap.Logger, port string) *
 	// Register routes
 	router.GET("/health/liveness", service.livenessHandler)
 	router.GET("/health/readiness", service.readinessHandler)
+	router.GET("/stats/usage", service.usageHandler)
 	router.GET("/docs", ginSwagger.WrapHandler(swaggerFiles.Handler))
 	router.GET("/docs/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
@@ -121,3 +125,14 @@ func (s *Service) readinessHandler(c *gin.Context) {
 		})
 	}
 }
+
+// usageHandler reports token usage per model since the start
+// @Summary Token usage
+// @Description Tokens spent per model since the bot started, including prompt cache reads and writes
+// @Tags stats
+// @Produce json
+// @Success 200 {object} services.UsageSnapshot
+// @Router /stats/usage [get]
+func (s *Service) usageHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, s.usage.Snapshot())
+}
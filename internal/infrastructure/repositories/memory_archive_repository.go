@@ -0,0 +1,117 @@
+package repositories
+
+import (
+	"sync"
+	"telegram-chatbot/internal/domain/entities"
+	"telegram-chatbot/internal/domain/repositories"
+	"telegram-chatbot/internal/domain/search"
+)
+
+// chatArchive is the archive and inverted index of a single chat
+type chatArchive struct {
+	conversations map[string]entities.Conversation
+	lengths       map[string]int
+	postings      map[string]map[string]int
+}
+
+type MemoryArchiveRepository struct {
+	archives map[int64]*chatArchive
+	mutex    sync.RWMutex
+}
+
+func NewMemoryArchiveRepository() repositories.ArchiveRepository {
+	return &MemoryArchiveRepository{
+		archives: make(map[int64]*chatArchive),
+	}
+}
+
+func (r *MemoryArchiveRepository) SaveConversation(conversation *entities.Conversation) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	archive, exists := r.archives[conversation.ChatID]
+	if !exists {
+		archive = &chatArchive{
+			conversations: make(map[string]entities.Conversation),
+			lengths:       make(map[string]int),
+			postings:      make(map[string]map[string]int),
+		}
+		r.archives[conversation.ChatID] = archive
+	}
+
+	if previous, exists := archive.conversations[conversation.ID]; exists {
+		oldFrequencies, _ := search.TermFrequencies(previous.Text())
+		for term := range oldFrequencies {
+			delete(archive.postings[term], conversation.ID)
+		}
+	}
+
+	frequencies, length := search.TermFrequencies(conversation.Text())
+	for term, tf := range frequencies {
+		if archive.postings[term] == nil {
+			archive.postings[term] = make(map[string]int)
+		}
+		archive.postings[term][conversation.ID] = tf
+	}
+
+	stored := *conversation
+	stored.Messages = append([]entities.Message(nil), conversation.Messages...)
+	archive.conversations[conversation.ID] = stored
+	archive.lengths[conversation.ID] = length
+	return nil
+}
+
+func (r *MemoryArchiveRepository) GetConversation(chatID int64, conversationID string) (*entities.Conversation, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	archive, exists := r.archives[chatID]
+	if !exists {
+		return nil, entities.ErrConversationNotFound
+	}
+
+	conversation, exists := archive.conversations[conversationID]
+	if !exists {
+		return nil, entities.ErrConversationNotFound
+	}
+
+	conversation.Messages = append([]entities.Message(nil), conversation.Messages...)
+	return &conversation, nil
+}
+
+func (r *MemoryArchiveRepository) Search(chatID, userID int64, query string, limit int) ([]entities.ConversationMatch, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	archive, exists := r.archives[chatID]
+	if !exists {
+		return nil, nil
+	}
+
+	postings := make(map[string]map[string]int)
+	for _, term := range search.UniqueTerms(query) {
+		postings[term] = archive.postings[term]
+	}
+
+	// Чужие личные разговоры не участвуют в ранжировании
+	postings, lengths := search.Restrict(postings, archive.lengths, func(docID string) bool {
+		owner := archive.conversations[docID].UserID
+		return owner == userID || owner == entities.SharedSessionUserID
+	})
+
+	matches := []entities.ConversationMatch{}
+	for _, result := range search.Rank(postings, lengths) {
+		conversation := archive.conversations[result.DocID]
+		conversation.Messages = append([]entities.Message(nil), conversation.Messages...)
+
+		matches = append(matches, entities.ConversationMatch{
+			Conversation: conversation,
+			Score:        result.Score,
+		})
+		if len(matches) == limit {
+			break
+		}
+	}
+
+	return matches, nil
+}
@@ -0,0 +1,43 @@
+package repositories
+
+import (
+	"sync"
+	"telegram-chatbot/internal/domain/entities"
+	"telegram-chatbot/internal/domain/repositories"
+	"time"
+)
+
+type MemoryChatSettingsRepository struct {
+	settings map[int64]entities.ChatSettings
+	mutex    sync.RWMutex
+}
+
+func NewMemoryChatSettingsRepository() repositories.ChatSettingsRepository {
+	return &MemoryChatSettingsRepository{
+		settings: make(map[int64]entities.ChatSettings),
+	}
+}
+
+func (r *MemoryChatSettingsRepository) GetSettings(chatID int64) (*entities.ChatSettings, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	settings, exists := r.settings[chatID]
+	if !exists {
+		return &entities.ChatSettings{
+			ChatID:    chatID,
+			UpdatedAt: time.Now(),
+		}, nil
+	}
+
+	return &settings, nil
+}
+
+func (r *MemoryChatSettingsRepository) SaveSettings(settings *entities.ChatSettings) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	settings.UpdatedAt = time.Now()
+	r.settings[settings.ChatID] = *settings
+	return nil
+}
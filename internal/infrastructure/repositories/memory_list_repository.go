@@ -0,0 +1,65 @@
+package repositories
+
+import (
+	"sort"
+	"sync"
+	"telegram-chatbot/internal/domain/entities"
+	"telegram-chatbot/internal/domain/repositories"
+	"time"
+)
+
+type MemoryListRepository struct {
+	lists map[int64]map[string]entities.FamilyList
+	mutex sync.RWMutex
+}
+
+func NewMemoryListRepository() repositories.ListRepository {
+	return &MemoryListRepository{
+		lists: make(map[int64]map[string]entities.FamilyList),
+	}
+}
+
+func (r *MemoryListRepository) GetList(chatID int64, name string) (*entities.FamilyList, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	list, exists := r.lists[chatID][name]
+	if !exists {
+		return &entities.FamilyList{
+			ChatID:    chatID,
+			Name:      name,
+			Items:     []entities.ListItem{},
+			UpdatedAt: time.Now(),
+		}, nil
+	}
+
+	list.Items = append([]entities.ListItem{}, list.Items...)
+	return &list, nil
+}
+
+func (r *MemoryListRepository) SaveList(list *entities.FamilyList) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.lists[list.ChatID] == nil {
+		r.lists[list.ChatID] = make(map[string]entities.FamilyList)
+	}
+
+	stored := *list
+	stored.Items = append([]entities.ListItem{}, list.Items...)
+	r.lists[list.ChatID][list.Name] = stored
+	return nil
+}
+
+func (r *MemoryListRepository) ListNames(chatID int64) ([]string, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	names := make([]string, 0, len(r.lists[chatID]))
+	for name := range r.lists[chatID] {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
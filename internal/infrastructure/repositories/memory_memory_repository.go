@@ -0,0 +1,59 @@
+package repositories
+
+import (
+	"sort"
+	"sync"
+	"telegram-chatbot/internal/domain/entities"
+	"telegram-chatbot/internal/domain/repositories"
+)
+
+type MemoryMemoryRepository struct {
+	memories map[int64]map[string]entities.Memory
+	mutex    sync.RWMutex
+}
+
+func NewMemoryMemoryRepository() repositories.MemoryRepository {
+	return &MemoryMemoryRepository{
+		memories: make(map[int64]map[string]entities.Memory),
+	}
+}
+
+func (r *MemoryMemoryRepository) SaveMemory(memory *entities.Memory) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.memories[memory.ChatID] == nil {
+		r.memories[memory.ChatID] = make(map[string]entities.Memory)
+	}
+	r.memories[memory.ChatID][memory.ID] = *memory
+	return nil
+}
+
+func (r *MemoryMemoryRepository) ListMemories(chatID, userID int64) ([]entities.Memory, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	memories := []entities.Memory{}
+	for _, memory := range r.memories[chatID] {
+		if memory.UserID == userID || memory.IsChatWide() {
+			memories = append(memories, memory)
+		}
+	}
+
+	sort.Slice(memories, func(i, j int) bool {
+		return memories[i].CreatedAt.Before(memories[j].CreatedAt)
+	})
+
+	return memories, nil
+}
+
+func (r *MemoryMemoryRepository) DeleteMemory(chatID int64, memoryID string) (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.memories[chatID][memoryID]; !exists {
+		return false, nil
+	}
+	delete(r.memories[chatID], memoryID)
+	return true, nil
+}
@@ -0,0 +1,74 @@
+package repositories
+
+import (
+	"sort"
+	"sync"
+	"telegram-chatbot/internal/domain/entities"
+	"telegram-chatbot/internal/domain/repositories"
+	"time"
+)
+
+type MemoryReminderRepository struct {
+	reminders map[string]entities.Reminder
+	mutex     sync.Mutex
+}
+
+func NewMemoryReminderRepository() repositories.ReminderRepository {
+	return &MemoryReminderRepository{
+		reminders: make(map[string]entities.Reminder),
+	}
+}
+
+func (r *MemoryReminderRepository) SaveReminder(reminder *entities.Reminder) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.reminders[reminder.ID] = *reminder
+	return nil
+}
+
+func (r *MemoryReminderRepository) ListReminders(chatID, userID int64) ([]entities.Reminder, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	reminders := []entities.Reminder{}
+	for _, reminder := range r.reminders {
+		if reminder.ChatID == chatID && reminder.UserID == userID {
+			reminders = append(reminders, reminder)
+		}
+	}
+
+	sort.Slice(reminders, func(i, j int) bool {
+		return reminders[i].DueAt.Before(reminders[j].DueAt)
+	})
+
+	return reminders, nil
+}
+
+func (r *MemoryReminderRepository) DeleteReminder(chatID, userID int64, reminderID string) (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	reminder, exists := r.reminders[reminderID]
+	if !exists || reminder.ChatID != chatID || reminder.UserID != userID {
+		return false, nil
+	}
+
+	delete(r.reminders, reminderID)
+	return true, nil
+}
+
+func (r *MemoryReminderRepository) PopDueReminders(now time.Time) ([]entities.Reminder, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	due := []entities.Reminder{}
+	for id, reminder := range r.reminders {
+		if !reminder.DueAt.After(now) {
+			due = append(due, reminder)
+			delete(r.reminders, id)
+		}
+	}
+
+	return due, nil
+}
@@ -1,6 +1,7 @@
 package repositories
 
 import (
+	"container/list"
 	"fmt"
 	"sync"
 	"telegram-chatbot/internal/domain/entities"
@@ -8,14 +9,34 @@ import (
 	"time"
 )
 
+// memorySessionEntry is a stored session with its expiry time
+type memorySessionEntry struct {
+	key       string
+	session   *entities.ChatSession
+	expiresAt time.Time
+}
+
+// MemorySessionRepository keeps sessions in process memory. Sessions are
+// copied on read and write, expire after the TTL like in Redis, and the least
+// recently used ones are evicted once maxSessions is reached.
 type MemorySessionRepository struct {
-	sessions map[string]*entities.ChatSession
-	mutex    sync.RWMutex
+	sessions    map[string]*list.Element
+	lru         *list.List // от недавно использованных к давно использованным
+	ttl         time.Duration
+	maxSessions int
+	maxMessages int
+	mutex       sync.Mutex
 }
 
-func NewMemorySessionRepository() repositories.SessionRepository {
+// NewMemorySessionRepository creates the store. A zero ttl, maxSessions or
+// maxMessages disables expiry, the size bound or history trimming.
+func NewMemorySessionRepository(ttl time.Duration, maxSessions, maxMessages int) repositories.SessionRepository {
 	return &MemorySessionRepository{
-		sessions: make(map[string]*entities.ChatSession),
+		sessions:    make(map[string]*list.Element),
+		lru:         list.New(),
+		ttl:         ttl,
+		maxSessions: maxSessions,
+		maxMessages: maxMessages,
 	}
 }
 
@@ -23,13 +44,31 @@ func (r *MemorySessionRepository) getKey(chatID, userID int64) string {
 	return fmt.Sprintf("%d:%d", chatID, userID)
 }
 
+// lookup returns the live entry for the key, dropping it if it has expired.
+// The caller must hold the mutex.
+func (r *MemorySessionRepository) lookup(key string, now time.Time) *memorySessionEntry {
+	element, exists := r.sessions[key]
+	if !exists {
+		return nil
+	}
+
+	entry := element.Value.(*memorySessionEntry)
+	if !entry.expiresAt.IsZero() && !now.Before(entry.expiresAt) {
+		r.lru.Remove(element)
+		delete(r.sessions, key)
+		return nil
+	}
+
+	r.lru.MoveToFront(element)
+	return entry
+}
+
 func (r *MemorySessionRepository) GetSession(chatID, userID int64) (*entities.ChatSession, error) {
-	r.mutex.RLock()
-	defer r.mutex.RUnlock()
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
 
-	key := r.getKey(chatID, userID)
-	session, exists := r.sessions[key]
-	if !exists {
+	entry := r.lookup(r.getKey(chatID, userID), time.Now())
+	if entry == nil {
 		return &entities.ChatSession{
 			ChatID:    chatID,
 			UserID:    userID,
@@ -40,14 +79,154 @@ func (r *MemorySessionRepository) GetSession(chatID, userID int64) (*entities.Ch
 		}, nil
 	}
 
-	return session, nil
+	return entry.session.Clone(), nil
 }
 
 func (r *MemorySessionRepository) SaveSession(session *entities.ChatSession) error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
-	key := r.getKey(session.ChatID, session.UserID)
-	r.sessions[key] = session
+
+	now := time.Now()
+	if r.storedRevision(session, now) != session.Revision {
+		return repositories.ErrSessionChanged
+	}
+
+	session.UpdatedAt = now
+	session.Revision++
+	session.HistoryRevision = session.Revision
+
+	r.store(session.Clone(), now)
+	return nil
+}
+
+// storedRevision returns the revision of the stored session, zero if there is
+// none. The caller must hold the mutex.
+func (r *MemorySessionRepository) storedRevision(session *entities.ChatSession, now time.Time) int64 {
+	entry := r.lookup(r.getKey(session.ChatID, session.UserID), now)
+	if entry == nil {
+		return 0
+	}
+	return entry.session.Revision
+}
+
+// store puts the session copy into the cache. The caller must hold the mutex.
+func (r *MemorySessionRepository) store(session *entities.ChatSession, now time.Time) {
+	session.Messages = r.trimHistory(session.Messages)
+
+	entry := &memorySessionEntry{
+		key:     r.getKey(session.ChatID, session.UserID),
+		session: session,
+	}
+	r.extend(entry, now)
+
+	if element, exists := r.sessions[entry.key]; exists {
+		element.Value = entry
+		r.lru.MoveToFront(element)
+		return
+	}
+
+	r.sessions[entry.key] = r.lru.PushFront(entry)
+
+	// Вытесняем давно не использованные сессии сверх лимита
+	for r.maxSessions > 0 && r.lru.Len() > r.maxSessions {
+		oldest := r.lru.Back()
+		r.lru.Remove(oldest)
+		delete(r.sessions, oldest.Value.(*memorySessionEntry).key)
+	}
+}
+
+// extend restarts the TTL of the entry, as Redis does on every write
+func (r *MemorySessionRepository) extend(entry *memorySessionEntry, now time.Time) {
+	if r.ttl > 0 {
+		entry.expiresAt = now.Add(r.ttl)
+	}
+}
+
+// trimHistory keeps the last maxMessages messages, starting with a user message
+func (r *MemorySessionRepository) trimHistory(messages []entities.Message) []entities.Message {
+	if r.maxMessages > 0 && len(messages) > r.maxMessages {
+		messages = append([]entities.Message(nil), messages[len(messages)-r.maxMessages:]...)
+	}
+	for len(messages) > 0 && messages[0].Role != "user" {
+		messages = messages[1:]
+	}
+	return messages
+}
+
+func (r *MemorySessionRepository) AppendMessage(session *entities.ChatSession, messages ...entities.Message) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := time.Now()
+	entry := r.lookup(r.getKey(session.ChatID, session.UserID), now)
+	// Сессия истекла, завершена или начата заново, пока готовился ответ
+	if entry == nil || !entry.session.IsActive || entry.session.HistoryRevision != session.HistoryRevision {
+		return repositories.ErrSessionChanged
+	}
+
+	// Сообщения, добавленные тем временем другими запросами, сохраняются
+	stored := entry.session.Clone()
+	for _, msg := range messages {
+		msg.ToolCalls = append([]entities.ToolCall(nil), msg.ToolCalls...)
+		stored.Messages = append(stored.Messages, msg)
+	}
+	stored.LastActivityAt = session.LastActivityAt
+	stored.ExpiryWarned = session.ExpiryWarned
+	stored.UpdatedAt = now
+	stored.Revision++
+
+	session.UpdatedAt = now
+	session.Revision = stored.Revision
+
+	r.store(stored, now)
+	return nil
+}
+
+func (r *MemorySessionRepository) UpdateLastMessage(session *entities.ChatSession) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	last := session.LastMessage()
+	if last == nil {
+		return nil
+	}
+
+	now := time.Now()
+	entry := r.lookup(r.getKey(session.ChatID, session.UserID), now)
+	if entry == nil || entry.session.Revision != session.Revision {
+		return repositories.ErrSessionChanged
+	}
+
+	stored := *last
+	stored.ToolCalls = append([]entities.ToolCall(nil), last.ToolCalls...)
+	entry.session.Messages[len(entry.session.Messages)-1] = stored
+	entry.session.UpdatedAt = now
+	entry.session.Revision++
+	r.extend(entry, now)
+
+	session.UpdatedAt = now
+	session.Revision = entry.session.Revision
+	return nil
+}
+
+func (r *MemorySessionRepository) UpdateActivity(session *entities.ChatSession) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := time.Now()
+	entry := r.lookup(r.getKey(session.ChatID, session.UserID), now)
+	if entry == nil || entry.session.Revision != session.Revision {
+		return repositories.ErrSessionChanged
+	}
+
+	entry.session.LastActivityAt = session.LastActivityAt
+	entry.session.ExpiryWarned = session.ExpiryWarned
+	entry.session.UpdatedAt = now
+	entry.session.Revision++
+	r.extend(entry, now)
+
+	session.UpdatedAt = now
+	session.Revision = entry.session.Revision
 	return nil
 }
 
@@ -56,15 +235,40 @@ func (r *MemorySessionRepository) DeleteSession(chatID, userID int64) error {
 	defer r.mutex.Unlock()
 
 	key := r.getKey(chatID, userID)
-	delete(r.sessions, key)
+	if element, exists := r.sessions[key]; exists {
+		r.lru.Remove(element)
+		delete(r.sessions, key)
+	}
 	return nil
 }
 
 func (r *MemorySessionRepository) IsSessionActive(chatID, userID int64) bool {
-	r.mutex.RLock()
-	defer r.mutex.RUnlock()
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
 
-	key := r.getKey(chatID, userID)
-	session, exists := r.sessions[key]
-	return exists && session.IsActive
+	entry := r.lookup(r.getKey(chatID, userID), time.Now())
+	return entry != nil && entry.session.IsActive
+}
+
+func (r *MemorySessionRepository) ListIdleSessions(idleSince time.Time) ([]*entities.ChatSession, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := time.Now()
+	sessions := []*entities.ChatSession{}
+	for element := r.lru.Front(); element != nil; {
+		next := element.Next()
+		entry := element.Value.(*memorySessionEntry)
+
+		switch {
+		case !entry.expiresAt.IsZero() && !now.Before(entry.expiresAt):
+			r.lru.Remove(element)
+			delete(r.sessions, entry.key)
+		case entry.session.IsActive && entry.session.LastActivity().Before(idleSince):
+			sessions = append(sessions, entry.session.Clone())
+		}
+
+		element = next
+	}
+	return sessions, nil
 }
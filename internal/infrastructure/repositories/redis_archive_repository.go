@@ -0,0 +1,388 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"telegram-chatbot/internal/domain/entities"
+	"telegram-chatbot/internal/domain/repositories"
+	"telegram-chatbot/internal/domain/search"
+	"telegram-chatbot/internal/infrastructure/encryption"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisArchiveRepository keeps archived conversations together with an
+// inverted index: one hash per term maps conversation IDs to term frequencies.
+type RedisArchiveRepository struct {
+	client  redis.UniversalClient
+	prefix  string
+	keyring *encryption.Keyring
+}
+
+func NewRedisArchiveRepository(client redis.UniversalClient, keyPrefix string, keyring *encryption.Keyring) repositories.ArchiveRepository {
+	return &RedisArchiveRepository{
+		client:  client,
+		prefix:  keyPrefix,
+		keyring: keyring,
+	}
+}
+
+// NewRedisArchiveReindexer moves the search index of the archive to the current key
+func NewRedisArchiveReindexer(client redis.UniversalClient, keyPrefix string, keyring *encryption.Keyring) repositories.ArchiveReindexer {
+	return &RedisArchiveRepository{
+		client:  client,
+		prefix:  keyPrefix,
+		keyring: keyring,
+	}
+}
+
+// getKey returns the hash holding all archived conversations of a chat
+func (r *RedisArchiveRepository) getKey(chatID int64) string {
+	return r.prefix + fmt.Sprintf("archive:%d", chatID)
+}
+
+// getLengthsKey returns the hash with the length in terms of every conversation
+func (r *RedisArchiveRepository) getLengthsKey(chatID int64) string {
+	return r.prefix + fmt.Sprintf("archive:%d:lengths", chatID)
+}
+
+// getOwnersKey returns the hash with the user every conversation belongs to
+func (r *RedisArchiveRepository) getOwnersKey(chatID int64) string {
+	return r.prefix + fmt.Sprintf("archive:%d:owners", chatID)
+}
+
+// associatedData binds an encrypted conversation to its chat and ID,
+// independently of the key prefix
+func (r *RedisArchiveRepository) associatedData(chatID int64, conversationID string) []byte {
+	return []byte(fmt.Sprintf("archive:%d:%s", chatID, conversationID))
+}
+
+// decode decrypts and unmarshals a stored conversation
+func (r *RedisArchiveRepository) decode(chatID int64, conversationID, value string) (*entities.Conversation, error) {
+	data, err := r.keyring.Decrypt([]byte(value), r.associatedData(chatID, conversationID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt conversation: %w", err)
+	}
+
+	var conversation entities.Conversation
+	if err := json.Unmarshal(data, &conversation); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal conversation: %w", err)
+	}
+
+	return &conversation, nil
+}
+
+// getTermKey returns the posting list of an index token. With encryption
+// enabled the token is an HMAC of the term, so the keys do not reveal the words.
+func (r *RedisArchiveRepository) getTermKey(chatID int64, token string) string {
+	return r.prefix + fmt.Sprintf("archive:%d:term:%s", chatID, token)
+}
+
+func (r *RedisArchiveRepository) SaveConversation(conversation *entities.Conversation) error {
+	ctx := context.Background()
+
+	data, err := json.Marshal(conversation)
+	if err != nil {
+		return fmt.Errorf("failed to marshal conversation: %w", err)
+	}
+
+	data, err = r.keyring.Encrypt(data, r.associatedData(conversation.ChatID, conversation.ID))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt conversation: %w", err)
+	}
+
+	// Открытый заново разговор индексируется повторно, старые термины удаляем
+	previous, err := r.GetConversation(conversation.ChatID, conversation.ID)
+	if err != nil && !errors.Is(err, entities.ErrConversationNotFound) {
+		return err
+	}
+
+	frequencies, length := search.TermFrequencies(conversation.Text())
+
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if previous != nil {
+			oldFrequencies, _ := search.TermFrequencies(previous.Text())
+			for term := range oldFrequencies {
+				// Термин мог попасть в индекс под прежним ключом или без шифрования
+				for _, token := range r.keyring.IndexTokens(term) {
+					pipe.HDel(ctx, r.getTermKey(conversation.ChatID, token), conversation.ID)
+				}
+			}
+		}
+
+		pipe.HSet(ctx, r.getKey(conversation.ChatID), conversation.ID, data)
+		pipe.HSet(ctx, r.getLengthsKey(conversation.ChatID), conversation.ID, length)
+		pipe.HSet(ctx, r.getOwnersKey(conversation.ChatID), conversation.ID, conversation.UserID)
+		for term, tf := range frequencies {
+			pipe.HSet(ctx, r.getTermKey(conversation.ChatID, r.keyring.IndexToken(term)), conversation.ID, tf)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save conversation to Redis: %w", err)
+	}
+
+	return nil
+}
+
+func (r *RedisArchiveRepository) GetConversation(chatID int64, conversationID string) (*entities.Conversation, error) {
+	ctx := context.Background()
+
+	data, err := r.client.HGet(ctx, r.getKey(chatID), conversationID).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, entities.ErrConversationNotFound
+		}
+		return nil, fmt.Errorf("failed to get conversation from Redis: %w", err)
+	}
+
+	return r.decode(chatID, conversationID, data)
+}
+
+func (r *RedisArchiveRepository) Search(chatID, userID int64, query string, limit int) ([]entities.ConversationMatch, error) {
+	ctx := context.Background()
+
+	terms := search.UniqueTerms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	rawLengths, err := r.client.HGetAll(ctx, r.getLengthsKey(chatID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get archive index from Redis: %w", err)
+	}
+	docLens := make(map[string]int, len(rawLengths))
+	for id, value := range rawLengths {
+		docLens[id], _ = strconv.Atoi(value)
+	}
+
+	postings, err := r.postings(ctx, chatID, terms)
+	if err != nil {
+		return nil, err
+	}
+
+	owners, err := r.owners(ctx, chatID, docLens)
+	if err != nil {
+		return nil, err
+	}
+
+	// Чужие личные разговоры не участвуют в ранжировании и не загружаются
+	postings, docLens = search.Restrict(postings, docLens, func(docID string) bool {
+		owner, ok := owners[docID]
+		return ok && (owner == userID || owner == entities.SharedSessionUserID)
+	})
+
+	results := search.Rank(postings, docLens)
+	if len(results) == 0 {
+		return nil, nil
+	}
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+
+	ids := make([]string, 0, len(results))
+	for _, result := range results {
+		ids = append(ids, result.DocID)
+	}
+	values, err := r.client.HMGet(ctx, r.getKey(chatID), ids...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversations from Redis: %w", err)
+	}
+
+	matches := make([]entities.ConversationMatch, 0, len(results))
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+
+		conversation, err := r.decode(chatID, ids[i], data)
+		if err != nil {
+			return nil, err
+		}
+
+		matches = append(matches, entities.ConversationMatch{
+			Conversation: *conversation,
+			Score:        results[i].Score,
+		})
+	}
+
+	return matches, nil
+}
+
+// postings reads the posting lists of the query terms. Until the archive is
+// reindexed a term may still be stored under an older key or in plain text,
+// so all its tokens are read and merged.
+func (r *RedisArchiveRepository) postings(ctx context.Context, chatID int64, terms []string) (map[string]map[string]int, error) {
+	commands := make(map[string][]*redis.MapStringStringCmd, len(terms))
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, term := range terms {
+			for _, token := range r.keyring.IndexTokens(term) {
+				commands[term] = append(commands[term], pipe.HGetAll(ctx, r.getTermKey(chatID, token)))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get archive index from Redis: %w", err)
+	}
+
+	postings := make(map[string]map[string]int, len(terms))
+	for term, termCommands := range commands {
+		docs := make(map[string]int)
+		for _, cmd := range termCommands {
+			for id, value := range cmd.Val() {
+				if _, ok := docs[id]; !ok {
+					docs[id], _ = strconv.Atoi(value)
+				}
+			}
+		}
+		postings[term] = docs
+	}
+
+	return postings, nil
+}
+
+// ReindexArchive moves index entries written in plain text or under an older
+// key to the current key. With dryRun the conversations are only counted.
+func (r *RedisArchiveRepository) ReindexArchive(dryRun bool) (*repositories.ArchiveReindexReport, error) {
+	ctx := context.Background()
+	report := &repositories.ArchiveReindexReport{}
+
+	var chatIDs []int64
+	err := scanKeys(ctx, r.client, escapePattern(r.prefix)+"archive:*", func(key string) {
+		var chatID int64
+		_, err := fmt.Sscanf(strings.TrimPrefix(key, r.prefix), "archive:%d", &chatID)
+		// Индексы, длины и владельцы лежат под тем же шаблоном
+		if err == nil && key == r.getKey(chatID) {
+			chatIDs = append(chatIDs, chatID)
+		}
+	})
+	if err != nil {
+		return report, fmt.Errorf("failed to scan archive in Redis: %w", err)
+	}
+
+	for _, chatID := range chatIDs {
+		values, err := r.client.HGetAll(ctx, r.getKey(chatID)).Result()
+		if err != nil {
+			return report, fmt.Errorf("failed to get conversations from Redis: %w", err)
+		}
+
+		for id, value := range values {
+			report.Scanned++
+			reindexed, err := r.reindexConversation(ctx, chatID, id, value, dryRun)
+			switch {
+			case err != nil:
+				report.Failed = append(report.Failed, fmt.Sprintf("%s/%s: %v", r.getKey(chatID), id, err))
+			case reindexed:
+				report.Reindexed++
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// reindexConversation rewrites the index entries of one conversation and
+// reports whether any of them were outdated
+func (r *RedisArchiveRepository) reindexConversation(ctx context.Context, chatID int64, conversationID, value string, dryRun bool) (bool, error) {
+	conversation, err := r.decode(chatID, conversationID, value)
+	if err != nil {
+		return false, err
+	}
+
+	frequencies, _ := search.TermFrequencies(conversation.Text())
+	var stale []*redis.BoolCmd
+	_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for term := range frequencies {
+			// Первый токен - текущий ключ, остальные устарели
+			for _, token := range r.keyring.IndexTokens(term)[1:] {
+				stale = append(stale, pipe.HExists(ctx, r.getTermKey(chatID, token), conversationID))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to check archive index: %w", err)
+	}
+
+	outdated := false
+	for _, cmd := range stale {
+		outdated = outdated || cmd.Val()
+	}
+	if !outdated || dryRun {
+		return outdated, nil
+	}
+
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for term, tf := range frequencies {
+			tokens := r.keyring.IndexTokens(term)
+			pipe.HSet(ctx, r.getTermKey(chatID, tokens[0]), conversationID, tf)
+			for _, token := range tokens[1:] {
+				pipe.HDel(ctx, r.getTermKey(chatID, token), conversationID)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to reindex conversation: %w", err)
+	}
+
+	return true, nil
+}
+
+// owners returns the user every indexed conversation belongs to.
+// Conversations archived before owners were indexed are read once and added.
+func (r *RedisArchiveRepository) owners(ctx context.Context, chatID int64, docLens map[string]int) (map[string]int64, error) {
+	rawOwners, err := r.client.HGetAll(ctx, r.getOwnersKey(chatID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get archive owners from Redis: %w", err)
+	}
+
+	owners := make(map[string]int64, len(rawOwners))
+	for id, value := range rawOwners {
+		owners[id], _ = strconv.ParseInt(value, 10, 64)
+	}
+
+	var missing []string
+	for id := range docLens {
+		if _, ok := owners[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return owners, nil
+	}
+
+	values, err := r.client.HMGet(ctx, r.getKey(chatID), missing...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversations from Redis: %w", err)
+	}
+
+	found := make(map[string]interface{}, len(missing))
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+
+		conversation, err := r.decode(chatID, missing[i], data)
+		if err != nil {
+			return nil, err
+		}
+		owners[missing[i]] = conversation.UserID
+		found[missing[i]] = conversation.UserID
+	}
+
+	if len(found) > 0 {
+		if err := r.client.HSet(ctx, r.getOwnersKey(chatID), found).Err(); err != nil {
+			return nil, fmt.Errorf("failed to index archive owners in Redis: %w", err)
+		}
+	}
+
+	return owners, nil
+}
@@ -0,0 +1,68 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"telegram-chatbot/internal/domain/entities"
+	"telegram-chatbot/internal/domain/repositories"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type RedisChatSettingsRepository struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+func NewRedisChatSettingsRepository(client redis.UniversalClient, keyPrefix string) repositories.ChatSettingsRepository {
+	return &RedisChatSettingsRepository{
+		client: client,
+		prefix: keyPrefix,
+	}
+}
+
+func (r *RedisChatSettingsRepository) getKey(chatID int64) string {
+	return r.prefix + fmt.Sprintf("settings:%d", chatID)
+}
+
+func (r *RedisChatSettingsRepository) GetSettings(chatID int64) (*entities.ChatSettings, error) {
+	ctx := context.Background()
+
+	data, err := r.client.Get(ctx, r.getKey(chatID)).Bytes()
+	if err == redis.Nil {
+		// Settings were never changed, use defaults
+		return &entities.ChatSettings{
+			ChatID:    chatID,
+			UpdatedAt: time.Now(),
+		}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get chat settings from Redis: %w", err)
+	}
+
+	var settings entities.ChatSettings
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal chat settings: %w", err)
+	}
+
+	return &settings, nil
+}
+
+func (r *RedisChatSettingsRepository) SaveSettings(settings *entities.ChatSettings) error {
+	ctx := context.Background()
+
+	settings.UpdatedAt = time.Now()
+
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return fmt.Errorf("failed to marshal chat settings: %w", err)
+	}
+
+	// Settings do not expire
+	if err := r.client.Set(ctx, r.getKey(settings.ChatID), data, 0).Err(); err != nil {
+		return fmt.Errorf("failed to save chat settings to Redis: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package repositories
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"telegram-chatbot/internal/config"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// NewRedisClient creates a Redis client shared by all Redis-backed
+// repositories. Depending on the config it talks to a single node, a
+// Sentinel-managed master or a cluster.
+func NewRedisClient(cfg *config.Config) (redis.UniversalClient, error) {
+	options := &redis.UniversalOptions{
+		Addrs:            cfg.RedisAddrs,
+		Username:         cfg.RedisUsername,
+		Password:         cfg.RedisPassword,
+		DB:               cfg.RedisDB,
+		MasterName:       cfg.RedisMasterName,
+		SentinelUsername: cfg.RedisSentinelUsername,
+		SentinelPassword: cfg.RedisSentinelPassword,
+		IsClusterMode:    cfg.RedisCluster,
+		PoolSize:         cfg.RedisPoolSize,
+		MinIdleConns:     cfg.RedisMinIdleConns,
+	}
+
+	if cfg.RedisTLS {
+		tlsConfig, err := newRedisTLSConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		options.TLSConfig = tlsConfig
+	}
+
+	// Несколько адресов без Sentinel go-redis тоже считает кластером
+	return redis.NewUniversalClient(options), nil
+}
+
+func newRedisTLSConfig(cfg *config.Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         cfg.RedisTLSServerName,
+		InsecureSkipVerify: cfg.RedisTLSInsecure,
+	}
+
+	if cfg.RedisTLSCAFile != "" {
+		caCert, err := os.ReadFile(cfg.RedisTLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read REDIS_TLS_CA_FILE: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("no certificates found in REDIS_TLS_CA_FILE")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.RedisTLSCertFile != "" {
+		certificate, err := tls.LoadX509KeyPair(cfg.RedisTLSCertFile, cfg.RedisTLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load Redis client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
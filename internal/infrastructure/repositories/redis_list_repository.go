@@ -0,0 +1,80 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"telegram-chatbot/internal/domain/entities"
+	"telegram-chatbot/internal/domain/repositories"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type RedisListRepository struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+func NewRedisListRepository(client redis.UniversalClient, keyPrefix string) repositories.ListRepository {
+	return &RedisListRepository{
+		client: client,
+		prefix: keyPrefix,
+	}
+}
+
+// getKey returns the hash holding all lists of a chat, one field per list
+func (r *RedisListRepository) getKey(chatID int64) string {
+	return r.prefix + fmt.Sprintf("lists:%d", chatID)
+}
+
+func (r *RedisListRepository) GetList(chatID int64, name string) (*entities.FamilyList, error) {
+	ctx := context.Background()
+
+	data, err := r.client.HGet(ctx, r.getKey(chatID), name).Bytes()
+	if err == redis.Nil {
+		return &entities.FamilyList{
+			ChatID:    chatID,
+			Name:      name,
+			Items:     []entities.ListItem{},
+			UpdatedAt: time.Now(),
+		}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get list from Redis: %w", err)
+	}
+
+	var list entities.FamilyList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal list: %w", err)
+	}
+
+	return &list, nil
+}
+
+func (r *RedisListRepository) SaveList(list *entities.FamilyList) error {
+	ctx := context.Background()
+
+	data, err := json.Marshal(list)
+	if err != nil {
+		return fmt.Errorf("failed to marshal list: %w", err)
+	}
+
+	if err := r.client.HSet(ctx, r.getKey(list.ChatID), list.Name, data).Err(); err != nil {
+		return fmt.Errorf("failed to save list to Redis: %w", err)
+	}
+
+	return nil
+}
+
+func (r *RedisListRepository) ListNames(chatID int64) ([]string, error) {
+	ctx := context.Background()
+
+	names, err := r.client.HKeys(ctx, r.getKey(chatID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get list names from Redis: %w", err)
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
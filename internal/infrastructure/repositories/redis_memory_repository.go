@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"telegram-chatbot/internal/domain/entities"
+	"telegram-chatbot/internal/domain/repositories"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type RedisMemoryRepository struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+func NewRedisMemoryRepository(client redis.UniversalClient, keyPrefix string) repositories.MemoryRepository {
+	return &RedisMemoryRepository{
+		client: client,
+		prefix: keyPrefix,
+	}
+}
+
+// getKey returns the hash holding all facts of a chat, one field per fact
+func (r *RedisMemoryRepository) getKey(chatID int64) string {
+	return r.prefix + fmt.Sprintf("memories:%d", chatID)
+}
+
+func (r *RedisMemoryRepository) SaveMemory(memory *entities.Memory) error {
+	ctx := context.Background()
+
+	data, err := json.Marshal(memory)
+	if err != nil {
+		return fmt.Errorf("failed to marshal memory: %w", err)
+	}
+
+	if err := r.client.HSet(ctx, r.getKey(memory.ChatID), memory.ID, data).Err(); err != nil {
+		return fmt.Errorf("failed to save memory to Redis: %w", err)
+	}
+
+	return nil
+}
+
+func (r *RedisMemoryRepository) ListMemories(chatID, userID int64) ([]entities.Memory, error) {
+	ctx := context.Background()
+
+	values, err := r.client.HVals(ctx, r.getKey(chatID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get memories from Redis: %w", err)
+	}
+
+	memories := make([]entities.Memory, 0, len(values))
+	for _, value := range values {
+		var memory entities.Memory
+		if err := json.Unmarshal([]byte(value), &memory); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal memory: %w", err)
+		}
+		if memory.UserID == userID || memory.IsChatWide() {
+			memories = append(memories, memory)
+		}
+	}
+
+	sort.Slice(memories, func(i, j int) bool {
+		return memories[i].CreatedAt.Before(memories[j].CreatedAt)
+	})
+
+	return memories, nil
+}
+
+func (r *RedisMemoryRepository) DeleteMemory(chatID int64, memoryID string) (bool, error) {
+	ctx := context.Background()
+
+	removed, err := r.client.HDel(ctx, r.getKey(chatID), memoryID).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete memory from Redis: %w", err)
+	}
+
+	return removed > 0, nil
+}
@@ -0,0 +1,170 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"telegram-chatbot/internal/domain/entities"
+	"telegram-chatbot/internal/domain/repositories"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const popDueBatchSize = 100
+
+type RedisReminderRepository struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+func NewRedisReminderRepository(client redis.UniversalClient, keyPrefix string) repositories.ReminderRepository {
+	return &RedisReminderRepository{
+		client: client,
+		prefix: keyPrefix,
+	}
+}
+
+// getDueKey returns the sorted set of reminder IDs scored by due time
+func (r *RedisReminderRepository) getDueKey() string {
+	return r.prefix + "reminders:due"
+}
+
+// getDataKey returns the hash of reminder IDs to reminder JSON
+func (r *RedisReminderRepository) getDataKey() string {
+	return r.prefix + "reminders:data"
+}
+
+func (r *RedisReminderRepository) getUserKey(chatID, userID int64) string {
+	return r.prefix + fmt.Sprintf("reminders:user:%d:%d", chatID, userID)
+}
+
+func (r *RedisReminderRepository) SaveReminder(reminder *entities.Reminder) error {
+	ctx := context.Background()
+
+	data, err := json.Marshal(reminder)
+	if err != nil {
+		return fmt.Errorf("failed to marshal reminder: %w", err)
+	}
+
+	pipe := r.client.TxPipeline()
+	pipe.HSet(ctx, r.getDataKey(), reminder.ID, data)
+	pipe.ZAdd(ctx, r.getDueKey(), redis.Z{Score: float64(reminder.DueAt.Unix()), Member: reminder.ID})
+	pipe.SAdd(ctx, r.getUserKey(reminder.ChatID, reminder.UserID), reminder.ID)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to save reminder to Redis: %w", err)
+	}
+
+	return nil
+}
+
+func (r *RedisReminderRepository) ListReminders(chatID, userID int64) ([]entities.Reminder, error) {
+	ctx := context.Background()
+
+	ids, err := r.client.SMembers(ctx, r.getUserKey(chatID, userID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reminders from Redis: %w", err)
+	}
+	if len(ids) == 0 {
+		return []entities.Reminder{}, nil
+	}
+
+	values, err := r.client.HMGet(ctx, r.getDataKey(), ids...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reminders from Redis: %w", err)
+	}
+
+	reminders := make([]entities.Reminder, 0, len(values))
+	for _, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue // напоминание уже доставлено
+		}
+
+		var reminder entities.Reminder
+		if err := json.Unmarshal([]byte(data), &reminder); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal reminder: %w", err)
+		}
+		reminders = append(reminders, reminder)
+	}
+
+	sort.Slice(reminders, func(i, j int) bool {
+		return reminders[i].DueAt.Before(reminders[j].DueAt)
+	})
+
+	return reminders, nil
+}
+
+func (r *RedisReminderRepository) DeleteReminder(chatID, userID int64, reminderID string) (bool, error) {
+	ctx := context.Background()
+	userKey := r.getUserKey(chatID, userID)
+
+	removed, err := r.client.SRem(ctx, userKey, reminderID).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete reminder from Redis: %w", err)
+	}
+	if removed == 0 {
+		return false, nil
+	}
+
+	pipe := r.client.TxPipeline()
+	pipe.ZRem(ctx, r.getDueKey(), reminderID)
+	pipe.HDel(ctx, r.getDataKey(), reminderID)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, fmt.Errorf("failed to delete reminder from Redis: %w", err)
+	}
+
+	return true, nil
+}
+
+func (r *RedisReminderRepository) PopDueReminders(now time.Time) ([]entities.Reminder, error) {
+	ctx := context.Background()
+
+	ids, err := r.client.ZRangeByScore(ctx, r.getDueKey(), &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(now.Unix(), 10),
+		Count: popDueBatchSize,
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get due reminders from Redis: %w", err)
+	}
+
+	reminders := make([]entities.Reminder, 0, len(ids))
+	for _, id := range ids {
+		// ZREM успешен только у одного экземпляра бота, он и доставляет напоминание
+		claimed, err := r.client.ZRem(ctx, r.getDueKey(), id).Result()
+		if err != nil {
+			return reminders, fmt.Errorf("failed to claim reminder: %w", err)
+		}
+		if claimed == 0 {
+			continue
+		}
+
+		data, err := r.client.HGet(ctx, r.getDataKey(), id).Bytes()
+		if err == redis.Nil {
+			continue
+		} else if err != nil {
+			return reminders, fmt.Errorf("failed to get reminder from Redis: %w", err)
+		}
+
+		var reminder entities.Reminder
+		if err := json.Unmarshal(data, &reminder); err != nil {
+			return reminders, fmt.Errorf("failed to unmarshal reminder: %w", err)
+		}
+
+		pipe := r.client.TxPipeline()
+		pipe.HDel(ctx, r.getDataKey(), id)
+		pipe.SRem(ctx, r.getUserKey(reminder.ChatID, reminder.UserID), id)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return reminders, fmt.Errorf("failed to remove delivered reminder: %w", err)
+		}
+
+		reminders = append(reminders, reminder)
+	}
+
+	return reminders, nil
+}
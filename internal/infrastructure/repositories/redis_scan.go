@@ -0,0 +1,44 @@
+package repositories
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// scanBatchSize is the COUNT hint for SCAN
+const scanBatchSize = 500
+
+// scanKeys calls fn for every key matching the pattern. In cluster mode all
+// masters are scanned in parallel, but calls to fn never overlap.
+func scanKeys(ctx context.Context, client redis.UniversalClient, pattern string, fn func(key string)) error {
+	cluster, ok := client.(*redis.ClusterClient)
+	if !ok {
+		return scanNode(ctx, client, pattern, fn)
+	}
+
+	var mu sync.Mutex
+	return cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+		return scanNode(ctx, node, pattern, func(key string) {
+			mu.Lock()
+			defer mu.Unlock()
+			fn(key)
+		})
+	})
+}
+
+func scanNode(ctx context.Context, client redis.Cmdable, pattern string, fn func(key string)) error {
+	iter := client.Scan(ctx, 0, pattern, scanBatchSize).Iterator()
+	for iter.Next(ctx) {
+		fn(iter.Val())
+	}
+	return iter.Err()
+}
+
+// escapePattern escapes glob characters so a key prefix matches literally
+func escapePattern(prefix string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
+	return replacer.Replace(prefix)
+}
@@ -2,43 +2,160 @@ package repositories
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"telegram-chatbot/internal/config"
+	"strconv"
+	"strings"
 	"telegram-chatbot/internal/domain/entities"
 	"telegram-chatbot/internal/domain/repositories"
+	"telegram-chatbot/internal/infrastructure/encryption"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
+// RedisSessionRepository keeps the session metadata in a hash and the history
+// in a list, so a new message is appended instead of rewriting the session.
 type RedisSessionRepository struct {
-	client *redis.Client
+	client      redis.UniversalClient
+	prefix      string
+	ttl         time.Duration
+	maxMessages int
+	keyring     *encryption.Keyring
 }
 
-func NewRedisSessionRepository(cfg *config.Config) repositories.SessionRepository {
-	client := redis.NewClient(&redis.Options{
-		Addr:     fmt.Sprintf("%s:%s", cfg.RedisHost, cfg.RedisPort),
-		Username: cfg.RedisUsername,
-		Password: cfg.RedisPassword,
-		DB:       cfg.RedisDB,
-	})
+// NewRedisSessionRepository creates the store. History longer than
+// maxMessages is trimmed from the start, zero keeps it whole.
+func NewRedisSessionRepository(
+	client redis.UniversalClient,
+	keyPrefix string,
+	ttl time.Duration,
+	maxMessages int,
+	keyring *encryption.Keyring,
+) repositories.SessionRepository {
+	return &RedisSessionRepository{
+		client:      client,
+		prefix:      keyPrefix,
+		ttl:         ttl,
+		maxMessages: maxMessages,
+		keyring:     keyring,
+	}
+}
 
+// NewRedisSessionMigrator upgrades sessions stored by RedisSessionRepository
+func NewRedisSessionMigrator(client redis.UniversalClient, keyPrefix string, ttl time.Duration, keyring *encryption.Keyring) repositories.SessionMigrator {
 	return &RedisSessionRepository{
-		client: client,
+		client:  client,
+		prefix:  keyPrefix,
+		ttl:     ttl,
+		keyring: keyring,
 	}
 }
 
+// getKey returns the metadata hash. The hash tag keeps it in one cluster slot
+// with the history, so both are updated in one transaction.
 func (r *RedisSessionRepository) getKey(chatID, userID int64) string {
-	return fmt.Sprintf("session:%d:%d", chatID, userID)
+	return r.prefix + fmt.Sprintf("session:{%d:%d}", chatID, userID)
+}
+
+// getMessagesKey returns the list with the session history
+func (r *RedisSessionRepository) getMessagesKey(chatID, userID int64) string {
+	return r.prefix + fmt.Sprintf("session:{%d:%d}:messages", chatID, userID)
+}
+
+// getLegacyKey returns the single JSON value used before version 3
+func (r *RedisSessionRepository) getLegacyKey(chatID, userID int64) string {
+	return r.prefix + fmt.Sprintf("session:%d:%d", chatID, userID)
+}
+
+// getActiveSessionsKey returns the sorted set of active sessions scored by last activity
+func (r *RedisSessionRepository) getActiveSessionsKey() string {
+	return r.prefix + "sessions:active"
+}
+
+// associatedData binds an encrypted legacy session to its chat and user. It
+// does not include the key prefix, so records stay readable if the prefix changes.
+func (r *RedisSessionRepository) associatedData(chatID, userID int64) []byte {
+	return []byte(fmt.Sprintf("session:%d:%d", chatID, userID))
+}
+
+// messageAssociatedData binds an encrypted history entry to its session
+func (r *RedisSessionRepository) messageAssociatedData(chatID, userID int64) []byte {
+	return []byte(fmt.Sprintf("session:%d:%d:messages", chatID, userID))
+}
+
+// getMember identifies a session in the set of active sessions
+func (r *RedisSessionRepository) getMember(chatID, userID int64) string {
+	return fmt.Sprintf("%d:%d", chatID, userID)
 }
 
 func (r *RedisSessionRepository) GetSession(chatID, userID int64) (*entities.ChatSession, error) {
 	ctx := context.Background()
-	key := r.getKey(chatID, userID)
 
-	data, err := r.client.Get(ctx, key).Bytes()
-	if err == redis.Nil {
+	var metaCmd *redis.MapStringStringCmd
+	var messagesCmd *redis.StringSliceCmd
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		metaCmd = pipe.HGetAll(ctx, r.getKey(chatID, userID))
+		messagesCmd = pipe.LRange(ctx, r.getMessagesKey(chatID, userID), 0, -1)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session from Redis: %w", err)
+	}
+
+	meta := metaCmd.Val()
+	if len(meta) == 0 {
+		// Сессии в старом формате переносятся в новый при первом чтении
+		return r.loadLegacySession(ctx, chatID, userID)
+	}
+
+	session := &entities.ChatSession{
+		ChatID: chatID,
+		UserID: userID,
+	}
+	if err := decodeSessionMeta(meta, session); err != nil {
+		return nil, fmt.Errorf("failed to decode session metadata: %w", err)
+	}
+
+	if session.Messages, err = r.decodeMessages(chatID, userID, messagesCmd.Val()); err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+func (r *RedisSessionRepository) decodeMessages(chatID, userID int64, values []string) ([]entities.Message, error) {
+	messages := make([]entities.Message, 0, len(values))
+	for _, value := range values {
+		data, err := r.keyring.Decrypt([]byte(value), r.messageAssociatedData(chatID, userID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt session message: %w", err)
+		}
+
+		msg, err := decodeMessage(data)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+
+	// После обрезки история должна начинаться с вопроса пользователя
+	for len(messages) > 0 && messages[0].Role != "user" {
+		messages = messages[1:]
+	}
+
+	return messages, nil
+}
+
+// loadLegacySession reads a session stored as a single JSON value and moves it
+// to the hash and list layout. A missing session is returned as a new one.
+func (r *RedisSessionRepository) loadLegacySession(ctx context.Context, chatID, userID int64) (*entities.ChatSession, error) {
+	session, _, err := r.readLegacySession(ctx, chatID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if session == nil {
 		// Session doesn't exist, create a new one
 		return &entities.ChatSession{
 			ChatID:    chatID,
@@ -48,43 +165,326 @@ func (r *RedisSessionRepository) GetSession(chatID, userID int64) (*entities.Cha
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
 		}, nil
+	}
+
+	if err := r.convertLegacySession(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// readLegacySession returns nil if there is no session in the old format
+func (r *RedisSessionRepository) readLegacySession(ctx context.Context, chatID, userID int64) (*entities.ChatSession, int, error) {
+	data, err := r.client.Get(ctx, r.getLegacyKey(chatID, userID)).Bytes()
+	if err == redis.Nil {
+		return nil, 0, nil
 	} else if err != nil {
-		return nil, fmt.Errorf("failed to get session from Redis: %w", err)
+		return nil, 0, fmt.Errorf("failed to get session from Redis: %w", err)
+	}
+
+	// Связанные данные не дают подложить запись другому пользователю
+	data, err = r.keyring.Decrypt(data, r.associatedData(chatID, userID))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to decrypt session data: %w", err)
+	}
+
+	return decodeSessionBlob(data)
+}
+
+// convertLegacySession writes the session in the current layout and removes the old value
+func (r *RedisSessionRepository) convertLegacySession(ctx context.Context, session *entities.ChatSession) error {
+	entries, err := r.encodeMessages(session.ChatID, session.UserID, session.Messages)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		r.writeSession(ctx, pipe, session, entries, r.ttl)
+		r.updateActiveSessions(ctx, pipe, session)
+		pipe.Del(ctx, r.getLegacyKey(session.ChatID, session.UserID))
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to convert session in Redis: %w", err)
+	}
+
+	return nil
+}
+
+func (r *RedisSessionRepository) encodeMessages(chatID, userID int64, messages []entities.Message) ([]interface{}, error) {
+	entries := make([]interface{}, 0, len(messages))
+	for _, msg := range messages {
+		data, err := encodeMessage(msg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal session message: %w", err)
+		}
+
+		data, err = r.keyring.Encrypt(data, r.messageAssociatedData(chatID, userID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt session message: %w", err)
+		}
+		entries = append(entries, data)
+	}
+	return entries, nil
+}
+
+// writeSession queues a full rewrite of the metadata and the history. Both
+// keys share a cluster slot, so this is safe inside WATCH.
+func (r *RedisSessionRepository) writeSession(ctx context.Context, pipe redis.Pipeliner, session *entities.ChatSession, entries []interface{}, ttl time.Duration) {
+	key := r.getKey(session.ChatID, session.UserID)
+	messagesKey := r.getMessagesKey(session.ChatID, session.UserID)
+
+	pipe.HSet(ctx, key, encodeSessionMeta(session))
+	pipe.Del(ctx, messagesKey)
+	if len(entries) > 0 {
+		pipe.RPush(ctx, messagesKey, entries...)
+		r.trimMessages(ctx, pipe, messagesKey)
+	}
+	r.expire(ctx, pipe, ttl, key, messagesKey)
+}
+
+func (r *RedisSessionRepository) trimMessages(ctx context.Context, pipe redis.Pipeliner, messagesKey string) {
+	if r.maxMessages > 0 {
+		pipe.LTrim(ctx, messagesKey, int64(-r.maxMessages), -1)
+	}
+}
+
+func (r *RedisSessionRepository) expire(ctx context.Context, pipe redis.Pipeliner, ttl time.Duration, keys ...string) {
+	if ttl <= 0 {
+		return
+	}
+	for _, key := range keys {
+		pipe.Expire(ctx, key, ttl)
+	}
+}
+
+func (r *RedisSessionRepository) updateActiveSessions(ctx context.Context, pipe redis.Pipeliner, session *entities.ChatSession) {
+	member := r.getMember(session.ChatID, session.UserID)
+	if session.IsActive {
+		pipe.ZAdd(ctx, r.getActiveSessionsKey(), redis.Z{
+			Score:  float64(session.LastActivity().Unix()),
+			Member: member,
+		})
+	} else {
+		pipe.ZRem(ctx, r.getActiveSessionsKey(), member)
+	}
+}
+
+// maxAppendAttempts bounds how often an append is retried when other turns
+// are appended between its check and its write
+const maxAppendAttempts = 5
+
+// writeIfUnchanged runs the writes queued by write in a transaction that only
+// commits if the stored revision still equals the session's. WATCH on the
+// metadata hash catches changes made between the check and the write; every
+// session write touches the hash.
+func (r *RedisSessionRepository) writeIfUnchanged(ctx context.Context, session *entities.ChatSession, write func(pipe redis.Pipeliner)) error {
+	key := r.getKey(session.ChatID, session.UserID)
+
+	err := r.client.Watch(ctx, func(tx *redis.Tx) error {
+		revision, err := tx.HGet(ctx, key, metaRevision).Int64()
+		if err != nil && err != redis.Nil {
+			return err
+		}
+		if revision != session.Revision {
+			return repositories.ErrSessionChanged
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			write(pipe)
+			return nil
+		})
+		return err
+	}, key)
+	if err == redis.TxFailedErr {
+		return repositories.ErrSessionChanged
 	}
+	return err
+}
 
-	var session entities.ChatSession
-	if err := json.Unmarshal(data, &session); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal session data: %w", err)
+// syncActiveSessions updates the set of active sessions after a transaction.
+// The set lives in another cluster slot and cannot be written inside WATCH.
+func (r *RedisSessionRepository) syncActiveSessions(ctx context.Context, session *entities.ChatSession) error {
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		r.updateActiveSessions(ctx, pipe, session)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update active sessions in Redis: %w", err)
 	}
+	return nil
+}
 
-	return &session, nil
+// metaNumber reads an integer field returned by HMGET, zero if it is missing
+func metaNumber(value interface{}) int64 {
+	text, _ := value.(string)
+	number, _ := strconv.ParseInt(text, 10, 64)
+	return number
 }
 
 func (r *RedisSessionRepository) SaveSession(session *entities.ChatSession) error {
 	ctx := context.Background()
+
+	entries, err := r.encodeMessages(session.ChatID, session.UserID, session.Messages)
+	if err != nil {
+		return err
+	}
+
+	saved := *session
+	saved.UpdatedAt = time.Now()
+	saved.Revision++
+	saved.HistoryRevision = saved.Revision
+
+	err = r.writeIfUnchanged(ctx, session, func(pipe redis.Pipeliner) {
+		r.writeSession(ctx, pipe, &saved, entries, r.ttl)
+	})
+	if errors.Is(err, repositories.ErrSessionChanged) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save session to Redis: %w", err)
+	}
+
+	session.UpdatedAt = saved.UpdatedAt
+	session.Revision = saved.Revision
+	session.HistoryRevision = saved.HistoryRevision
+
+	return r.syncActiveSessions(ctx, session)
+}
+
+func (r *RedisSessionRepository) AppendMessage(session *entities.ChatSession, messages ...entities.Message) error {
+	ctx := context.Background()
 	key := r.getKey(session.ChatID, session.UserID)
+	messagesKey := r.getMessagesKey(session.ChatID, session.UserID)
+
+	entries, err := r.encodeMessages(session.ChatID, session.UserID, messages)
+	if err != nil {
+		return err
+	}
+
+	appended := *session
+	appended.UpdatedAt = time.Now()
 
-	// Update the timestamp
-	session.UpdatedAt = time.Now()
+	for attempt := 1; ; attempt++ {
+		err = r.client.Watch(ctx, func(tx *redis.Tx) error {
+			values, err := tx.HMGet(ctx, key, metaIsActive, metaHistoryRevision, metaRevision).Result()
+			if err != nil {
+				return err
+			}
+			// Сессию завершили или начали заново, пока готовился ответ
+			if values[0] != "true" || metaNumber(values[1]) != session.HistoryRevision {
+				return repositories.ErrSessionChanged
+			}
+			appended.Revision = metaNumber(values[2]) + 1
 
-	data, err := json.Marshal(session)
+			// Остальные поля не трогаем, их меняют только условные записи
+			fields := encodeSessionActivity(&appended)
+			fields[metaRevision] = appended.Revision
+
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.HSet(ctx, key, fields)
+				if len(entries) > 0 {
+					pipe.RPush(ctx, messagesKey, entries...)
+					r.trimMessages(ctx, pipe, messagesKey)
+				}
+				r.expire(ctx, pipe, r.ttl, key, messagesKey)
+				return nil
+			})
+			return err
+		}, key)
+		// Между проверкой и записью другой ход успел дописаться, проверяем снова
+		if err != redis.TxFailedErr || attempt == maxAppendAttempts {
+			break
+		}
+	}
+	if err == redis.TxFailedErr || errors.Is(err, repositories.ErrSessionChanged) {
+		return repositories.ErrSessionChanged
+	}
 	if err != nil {
-		return fmt.Errorf("failed to marshal session data: %w", err)
+		return fmt.Errorf("failed to append session message to Redis: %w", err)
 	}
 
-	// Set with an expiration time (e.g., 24 hours)
-	if err := r.client.Set(ctx, key, data, 24*time.Hour).Err(); err != nil {
-		return fmt.Errorf("failed to save session to Redis: %w", err)
+	session.UpdatedAt = appended.UpdatedAt
+	session.Revision = appended.Revision
+
+	return r.syncActiveSessions(ctx, session)
+}
+
+func (r *RedisSessionRepository) UpdateLastMessage(session *entities.ChatSession) error {
+	ctx := context.Background()
+
+	last := session.LastMessage()
+	if last == nil {
+		return nil
+	}
+
+	entries, err := r.encodeMessages(session.ChatID, session.UserID, []entities.Message{*last})
+	if err != nil {
+		return err
+	}
+
+	updatedAt := time.Now()
+	revision := session.Revision + 1
+
+	// Раз ревизия не изменилась, последний элемент списка - то самое сообщение
+	err = r.writeIfUnchanged(ctx, session, func(pipe redis.Pipeliner) {
+		pipe.LSet(ctx, r.getMessagesKey(session.ChatID, session.UserID), -1, entries[0])
+		pipe.HSet(ctx, r.getKey(session.ChatID, session.UserID),
+			metaUpdatedAt, updatedAt.Format(time.RFC3339Nano),
+			metaRevision, revision)
+	})
+	if errors.Is(err, repositories.ErrSessionChanged) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update session message in Redis: %w", err)
 	}
 
+	session.UpdatedAt = updatedAt
+	session.Revision = revision
 	return nil
 }
 
+func (r *RedisSessionRepository) UpdateActivity(session *entities.ChatSession) error {
+	ctx := context.Background()
+	key := r.getKey(session.ChatID, session.UserID)
+
+	updated := *session
+	updated.UpdatedAt = time.Now()
+	updated.Revision++
+
+	fields := encodeSessionActivity(&updated)
+	fields[metaRevision] = updated.Revision
+
+	err := r.writeIfUnchanged(ctx, session, func(pipe redis.Pipeliner) {
+		pipe.HSet(ctx, key, fields)
+		r.expire(ctx, pipe, r.ttl, key, r.getMessagesKey(session.ChatID, session.UserID))
+	})
+	if errors.Is(err, repositories.ErrSessionChanged) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update session activity in Redis: %w", err)
+	}
+
+	session.UpdatedAt = updated.UpdatedAt
+	session.Revision = updated.Revision
+
+	return r.syncActiveSessions(ctx, session)
+}
+
 func (r *RedisSessionRepository) DeleteSession(chatID, userID int64) error {
 	ctx := context.Background()
-	key := r.getKey(chatID, userID)
 
-	if err := r.client.Del(ctx, key).Err(); err != nil {
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, r.getKey(chatID, userID))
+		pipe.Del(ctx, r.getMessagesKey(chatID, userID))
+		pipe.Del(ctx, r.getLegacyKey(chatID, userID))
+		pipe.ZRem(ctx, r.getActiveSessionsKey(), r.getMember(chatID, userID))
+		return nil
+	})
+	if err != nil {
 		return fmt.Errorf("failed to delete session from Redis: %w", err)
 	}
 
@@ -92,6 +492,14 @@ func (r *RedisSessionRepository) DeleteSession(chatID, userID int64) error {
 }
 
 func (r *RedisSessionRepository) IsSessionActive(chatID, userID int64) bool {
+	ctx := context.Background()
+
+	active, err := r.client.HGet(ctx, r.getKey(chatID, userID), metaIsActive).Result()
+	if err == nil {
+		return active == "true"
+	}
+
+	// Сессия ещё может храниться в старом формате
 	session, err := r.GetSession(chatID, userID)
 	if err != nil {
 		return false
@@ -99,3 +507,145 @@ func (r *RedisSessionRepository) IsSessionActive(chatID, userID int64) bool {
 
 	return session.IsActive
 }
+
+func (r *RedisSessionRepository) ListIdleSessions(idleSince time.Time) ([]*entities.ChatSession, error) {
+	ctx := context.Background()
+
+	members, err := r.client.ZRangeByScore(ctx, r.getActiveSessionsKey(), &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(idleSince.Unix(), 10),
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get idle sessions from Redis: %w", err)
+	}
+
+	sessions := make([]*entities.ChatSession, 0, len(members))
+	for _, member := range members {
+		var chatID, userID int64
+		if _, err := fmt.Sscanf(member, "%d:%d", &chatID, &userID); err != nil {
+			return nil, fmt.Errorf("invalid active session %q: %w", member, err)
+		}
+
+		session, err := r.GetSession(chatID, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		// Сессия истекла по TTL или уже завершена - убираем её из списка
+		if !session.IsActive {
+			if err := r.client.ZRem(ctx, r.getActiveSessionsKey(), member).Err(); err != nil {
+				return nil, fmt.Errorf("failed to remove stale session from Redis: %w", err)
+			}
+			continue
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	return sessions, nil
+}
+
+// MigrateSessions moves sessions stored as a single JSON value to the hash
+// and list layout. The remaining TTL of every session is kept.
+func (r *RedisSessionRepository) MigrateSessions(dryRun bool) (*repositories.SessionMigrationReport, error) {
+	ctx := context.Background()
+	report := &repositories.SessionMigrationReport{}
+
+	err := scanKeys(ctx, r.client, escapePattern(r.prefix)+"session:*", func(key string) {
+		var chatID, userID int64
+		name := strings.TrimPrefix(key, r.prefix)
+
+		switch {
+		case scanSessionKey(name, "session:{%d:%d}", &chatID, &userID) && key == r.getKey(chatID, userID):
+			report.Scanned++
+			report.Current++
+		case scanSessionKey(name, "session:%d:%d", &chatID, &userID) && key == r.getLegacyKey(chatID, userID):
+			report.Scanned++
+			migrated, err := r.migrateSession(ctx, chatID, userID, dryRun)
+			switch {
+			case err != nil:
+				report.Failed = append(report.Failed, fmt.Sprintf("%s: %v", key, err))
+			case migrated:
+				report.Migrated++
+			default:
+				report.Current++
+			}
+		}
+		// Списки сообщений и чужие ключи под шаблоном пропускаем
+	})
+	if err != nil {
+		return report, fmt.Errorf("failed to scan sessions in Redis: %w", err)
+	}
+
+	return report, nil
+}
+
+func scanSessionKey(name, format string, chatID, userID *int64) bool {
+	_, err := fmt.Sscanf(name, format, chatID, userID)
+	return err == nil
+}
+
+// migrateSession converts a single legacy session and reports whether it was
+// converted. WATCH on the new hash keeps a conversion done by the bot at the
+// same time from being overwritten with the old history.
+func (r *RedisSessionRepository) migrateSession(ctx context.Context, chatID, userID int64, dryRun bool) (bool, error) {
+	key := r.getKey(chatID, userID)
+	legacyKey := r.getLegacyKey(chatID, userID)
+	var session *entities.ChatSession
+
+	err := r.client.Watch(ctx, func(tx *redis.Tx) error {
+		exists, err := tx.Exists(ctx, key).Result()
+		if err != nil || exists > 0 {
+			// Сессия уже в новом формате, старое значение осталось лишним
+			return err
+		}
+
+		session, _, err = r.readLegacySession(ctx, chatID, userID)
+		if err != nil || session == nil || dryRun {
+			return err
+		}
+
+		// Оставшийся TTL переносится, время обновления не трогаем
+		ttl, err := r.client.PTTL(ctx, legacyKey).Result()
+		if err != nil {
+			return err
+		}
+		if ttl <= 0 {
+			ttl = r.ttl
+		}
+
+		entries, err := r.encodeMessages(chatID, userID, session.Messages)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			r.writeSession(ctx, pipe, session, entries, ttl)
+			return nil
+		})
+		return err
+	}, key)
+	if err == redis.TxFailedErr {
+		// Бот успел перенести сессию сам
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if dryRun {
+		return session != nil, nil
+	}
+
+	_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		if session != nil {
+			r.updateActiveSessions(ctx, pipe, session)
+		}
+		pipe.Del(ctx, legacyKey)
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return session != nil, nil
+}
@@ -0,0 +1,329 @@
+package repositories
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"telegram-chatbot/internal/domain/entities"
+	"time"
+)
+
+const (
+	// currentSessionVersion keeps the metadata in a hash and the messages in a list
+	currentSessionVersion = 3
+	// blobSessionVersion is the last format with the whole session in one JSON value
+	blobSessionVersion = 2
+)
+
+// ErrUnsupportedSessionVersion means the session was written by a newer build
+var ErrUnsupportedSessionVersion = errors.New("session was stored in a newer format")
+
+// sessionUpgrades turns a session blob stored in version N into version N+1.
+// Every blob format change added an entry here, so old sessions keep loading.
+var sessionUpgrades = map[int]func(data []byte) ([]byte, error){
+	1: upgradeSessionV1,
+}
+
+// sessionRecord is the session blob of version 2. Field names are fixed by
+// tags, so renaming entity fields does not change the stored format.
+type sessionRecord struct {
+	Version        int             `json:"version"`
+	ChatID         int64           `json:"chat_id"`
+	UserID         int64           `json:"user_id"`
+	IsActive       bool            `json:"is_active"`
+	StartedBy      int64           `json:"started_by,omitempty"`
+	ConversationID string          `json:"conversation_id,omitempty"`
+	Messages       []messageRecord `json:"messages"`
+	LastActivityAt time.Time       `json:"last_activity_at"`
+	ExpiryWarned   bool            `json:"expiry_warned,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+type messageRecord struct {
+	Role              string           `json:"role"`
+	Content           string           `json:"content"`
+	Truncated         bool             `json:"truncated,omitempty"`
+	TelegramMessageID int              `json:"telegram_message_id,omitempty"`
+	ToolCalls         []toolCallRecord `json:"tool_calls,omitempty"`
+	Model             string           `json:"model,omitempty"`
+	Thinking          string           `json:"thinking,omitempty"`
+	Timestamp         time.Time        `json:"timestamp"`
+}
+
+type toolCallRecord struct {
+	Name    string `json:"name"`
+	Input   string `json:"input"`
+	Output  string `json:"output"`
+	IsError bool   `json:"is_error,omitempty"`
+}
+
+// encodeMessage serializes a history entry. Messages are stored one by one
+// since version 3.
+func encodeMessage(msg entities.Message) ([]byte, error) {
+	stored := messageRecord{
+		Role:              msg.Role,
+		Content:           msg.Content,
+		Truncated:         msg.Truncated,
+		TelegramMessageID: msg.TelegramMessageID,
+		Model:             msg.Model,
+		Thinking:          msg.Thinking,
+		Timestamp:         msg.Timestamp,
+	}
+	for _, call := range msg.ToolCalls {
+		stored.ToolCalls = append(stored.ToolCalls, toolCallRecord(call))
+	}
+
+	return json.Marshal(stored)
+}
+
+func decodeMessage(data []byte) (entities.Message, error) {
+	var stored messageRecord
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return entities.Message{}, fmt.Errorf("failed to unmarshal message: %w", err)
+	}
+
+	return stored.message(), nil
+}
+
+func (stored messageRecord) message() entities.Message {
+	msg := entities.Message{
+		Role:              stored.Role,
+		Content:           stored.Content,
+		Truncated:         stored.Truncated,
+		TelegramMessageID: stored.TelegramMessageID,
+		Model:             stored.Model,
+		Thinking:          stored.Thinking,
+		Timestamp:         stored.Timestamp,
+	}
+	for _, call := range stored.ToolCalls {
+		msg.ToolCalls = append(msg.ToolCalls, entities.ToolCall(call))
+	}
+	return msg
+}
+
+// Поля хэша с метаданными сессии, версия 3
+const (
+	metaVersion        = "version"
+	metaIsActive       = "is_active"
+	metaStartedBy      = "started_by"
+	metaConversationID = "conversation_id"
+	metaLastActivityAt = "last_activity_at"
+	metaExpiryWarned   = "expiry_warned"
+	metaCreatedAt      = "created_at"
+	metaUpdatedAt      = "updated_at"
+	// Ревизии появились позже, в старых хэшах их нет и они читаются как ноль
+	metaRevision        = "revision"
+	metaHistoryRevision = "history_revision"
+)
+
+// encodeSessionMeta returns the metadata hash fields of a session
+func encodeSessionMeta(session *entities.ChatSession) map[string]interface{} {
+	fields := encodeSessionActivity(session)
+	fields[metaVersion] = currentSessionVersion
+	fields[metaIsActive] = strconv.FormatBool(session.IsActive)
+	fields[metaStartedBy] = session.StartedBy
+	fields[metaConversationID] = session.ConversationID
+	fields[metaCreatedAt] = session.CreatedAt.Format(time.RFC3339Nano)
+	fields[metaRevision] = session.Revision
+	fields[metaHistoryRevision] = session.HistoryRevision
+	return fields
+}
+
+// encodeSessionActivity returns the metadata fields changed by a new message
+func encodeSessionActivity(session *entities.ChatSession) map[string]interface{} {
+	return map[string]interface{}{
+		metaLastActivityAt: session.LastActivityAt.Format(time.RFC3339Nano),
+		metaExpiryWarned:   strconv.FormatBool(session.ExpiryWarned),
+		metaUpdatedAt:      session.UpdatedAt.Format(time.RFC3339Nano),
+	}
+}
+
+// decodeSessionMeta fills the session from its metadata hash
+func decodeSessionMeta(fields map[string]string, session *entities.ChatSession) error {
+	if version, _ := strconv.Atoi(fields[metaVersion]); version > currentSessionVersion {
+		return fmt.Errorf("%w: version %d, supported up to %d",
+			ErrUnsupportedSessionVersion, version, currentSessionVersion)
+	}
+
+	// Хэш мог создаться только из полей активности, если сессия истекла посреди ответа
+	session.IsActive = fields[metaIsActive] == "true"
+	session.ConversationID = fields[metaConversationID]
+	session.ExpiryWarned = fields[metaExpiryWarned] == "true"
+
+	var err error
+	numbers := map[string]*int64{
+		metaStartedBy:       &session.StartedBy,
+		metaRevision:        &session.Revision,
+		metaHistoryRevision: &session.HistoryRevision,
+	}
+	for name, target := range numbers {
+		value := fields[name]
+		if value == "" {
+			continue
+		}
+		if *target, err = strconv.ParseInt(value, 10, 64); err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+	}
+
+	times := map[string]*time.Time{
+		metaLastActivityAt: &session.LastActivityAt,
+		metaCreatedAt:      &session.CreatedAt,
+		metaUpdatedAt:      &session.UpdatedAt,
+	}
+	for name, target := range times {
+		value := fields[name]
+		if value == "" {
+			continue
+		}
+		if *target, err = time.Parse(time.RFC3339Nano, value); err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// decodeSessionBlob reads a session blob stored in any known version,
+// upgrading it first. It also returns the version the data was stored in.
+func decodeSessionBlob(data []byte) (*entities.ChatSession, int, error) {
+	storedVersion, err := sessionVersion(data)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	data, err = upgradeSession(data, storedVersion)
+	if err != nil {
+		return nil, storedVersion, err
+	}
+
+	var record sessionRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, storedVersion, fmt.Errorf("failed to unmarshal session data: %w", err)
+	}
+
+	session := &entities.ChatSession{
+		ChatID:         record.ChatID,
+		UserID:         record.UserID,
+		IsActive:       record.IsActive,
+		StartedBy:      record.StartedBy,
+		ConversationID: record.ConversationID,
+		Messages:       make([]entities.Message, 0, len(record.Messages)),
+		LastActivityAt: record.LastActivityAt,
+		ExpiryWarned:   record.ExpiryWarned,
+		CreatedAt:      record.CreatedAt,
+		UpdatedAt:      record.UpdatedAt,
+	}
+
+	for _, stored := range record.Messages {
+		session.Messages = append(session.Messages, stored.message())
+	}
+
+	return session, storedVersion, nil
+}
+
+// sessionVersion returns the storage format of the data. Sessions written
+// before the format was versioned have no version field and count as version 1.
+func sessionVersion(data []byte) (int, error) {
+	var header struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return 0, fmt.Errorf("failed to unmarshal session data: %w", err)
+	}
+
+	if header.Version == 0 {
+		return 1, nil
+	}
+	return header.Version, nil
+}
+
+// upgradeSession applies the registered upgrades one version at a time
+func upgradeSession(data []byte, version int) ([]byte, error) {
+	if version > blobSessionVersion {
+		return nil, fmt.Errorf("%w: blob version %d, supported up to %d",
+			ErrUnsupportedSessionVersion, version, blobSessionVersion)
+	}
+
+	for ; version < blobSessionVersion; version++ {
+		upgrade, ok := sessionUpgrades[version]
+		if !ok {
+			return nil, fmt.Errorf("no upgrade registered for session version %d", version)
+		}
+
+		var err error
+		data, err = upgrade(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to upgrade session from version %d: %w", version, err)
+		}
+	}
+
+	return data, nil
+}
+
+// sessionRecordV1 is the unversioned format: the entity marshalled with the
+// default Go field names. It must not change, old sessions are read with it.
+type sessionRecordV1 struct {
+	ChatID         int64
+	UserID         int64
+	IsActive       bool
+	StartedBy      int64
+	ConversationID string
+	Messages       []struct {
+		Role              string
+		Content           string
+		Truncated         bool
+		TelegramMessageID int
+		ToolCalls         []struct {
+			Name    string
+			Input   string
+			Output  string
+			IsError bool
+		}
+		Timestamp time.Time
+	}
+	LastActivityAt time.Time
+	ExpiryWarned   bool
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// upgradeSessionV1 moves the unversioned format to explicit snake_case fields
+func upgradeSessionV1(data []byte) ([]byte, error) {
+	var old sessionRecordV1
+	if err := json.Unmarshal(data, &old); err != nil {
+		return nil, err
+	}
+
+	record := sessionRecord{
+		Version:        2,
+		ChatID:         old.ChatID,
+		UserID:         old.UserID,
+		IsActive:       old.IsActive,
+		StartedBy:      old.StartedBy,
+		ConversationID: old.ConversationID,
+		Messages:       make([]messageRecord, 0, len(old.Messages)),
+		LastActivityAt: old.LastActivityAt,
+		ExpiryWarned:   old.ExpiryWarned,
+		CreatedAt:      old.CreatedAt,
+		UpdatedAt:      old.UpdatedAt,
+	}
+
+	for _, msg := range old.Messages {
+		stored := messageRecord{
+			Role:              msg.Role,
+			Content:           msg.Content,
+			Truncated:         msg.Truncated,
+			TelegramMessageID: msg.TelegramMessageID,
+			Timestamp:         msg.Timestamp,
+		}
+		for _, call := range msg.ToolCalls {
+			stored.ToolCalls = append(stored.ToolCalls, toolCallRecord(call))
+		}
+		record.Messages = append(record.Messages, stored)
+	}
+
+	return json.Marshal(record)
+}
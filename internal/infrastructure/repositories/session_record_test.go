@@ -0,0 +1,283 @@
+package repositories
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"telegram-chatbot/internal/domain/entities"
+	"testing"
+	"time"
+)
+
+func TestDecodeSessionBlob(t *testing.T) {
+	created := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	activity := created.Add(time.Hour)
+
+	tests := []struct {
+		name        string
+		data        string
+		wantVersion int
+		wantErr     string
+		want        *entities.ChatSession
+	}{
+		{
+			name: "v1 without version field",
+			data: `{"ChatID":-100,"UserID":7,"IsActive":true,"StartedBy":7,"ConversationID":"c1",
+				"Messages":[{"Role":"user","Content":"привет","Timestamp":"2024-03-01T10:00:00Z"},
+					{"Role":"assistant","Content":"здравствуй","Truncated":true,"TelegramMessageID":42,
+					 "ToolCalls":[{"Name":"calc","Input":"{}","Output":"4","IsError":false}],"Timestamp":"2024-03-01T10:00:00Z"}],
+				"LastActivityAt":"2024-03-01T11:00:00Z","ExpiryWarned":true,
+				"CreatedAt":"2024-03-01T10:00:00Z","UpdatedAt":"2024-03-01T11:00:00Z"}`,
+			wantVersion: 1,
+			want: &entities.ChatSession{
+				ChatID:         -100,
+				UserID:         7,
+				IsActive:       true,
+				StartedBy:      7,
+				ConversationID: "c1",
+				Messages: []entities.Message{
+					{Role: "user", Content: "привет", Timestamp: created},
+					{Role: "assistant", Content: "здравствуй", Truncated: true, TelegramMessageID: 42,
+						ToolCalls: []entities.ToolCall{{Name: "calc", Input: "{}", Output: "4"}}, Timestamp: created},
+				},
+				LastActivityAt: activity,
+				ExpiryWarned:   true,
+				CreatedAt:      created,
+				UpdatedAt:      activity,
+			},
+		},
+		{
+			name: "v2",
+			data: `{"version":2,"chat_id":5,"user_id":0,"is_active":false,
+				"messages":[{"role":"assistant","content":"ответ","model":"anthropic:claude","thinking":"мысли","timestamp":"2024-03-01T10:00:00Z"}],
+				"last_activity_at":"2024-03-01T11:00:00Z","created_at":"2024-03-01T10:00:00Z","updated_at":"2024-03-01T11:00:00Z"}`,
+			wantVersion: 2,
+			want: &entities.ChatSession{
+				ChatID: 5,
+				Messages: []entities.Message{
+					{Role: "assistant", Content: "ответ", Model: "anthropic:claude", Thinking: "мысли", Timestamp: created},
+				},
+				LastActivityAt: activity,
+				CreatedAt:      created,
+				UpdatedAt:      activity,
+			},
+		},
+		{
+			name:        "v3 is not a blob",
+			data:        `{"version":3}`,
+			wantVersion: 3,
+			wantErr:     ErrUnsupportedSessionVersion.Error(),
+		},
+		{
+			name:    "not JSON",
+			data:    `session`,
+			wantErr: "failed to unmarshal session data",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session, version, err := decodeSessionBlob([]byte(tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if version != tt.wantVersion {
+				t.Errorf("version = %d, want %d", version, tt.wantVersion)
+			}
+			if !reflect.DeepEqual(session, tt.want) {
+				t.Errorf("session = %+v, want %+v", session, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeSessionMeta(t *testing.T) {
+	created := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		fields  map[string]string
+		want    entities.ChatSession
+		wantErr string
+	}{
+		{
+			name: "current",
+			fields: map[string]string{
+				metaVersion: "3", metaIsActive: "true", metaStartedBy: "7", metaConversationID: "c1",
+				metaLastActivityAt: created.Format(time.RFC3339Nano), metaExpiryWarned: "true",
+				metaCreatedAt: created.Format(time.RFC3339Nano), metaUpdatedAt: created.Format(time.RFC3339Nano),
+				metaRevision: "12", metaHistoryRevision: "10",
+			},
+			want: entities.ChatSession{
+				IsActive: true, StartedBy: 7, ConversationID: "c1", LastActivityAt: created, ExpiryWarned: true,
+				CreatedAt: created, UpdatedAt: created, Revision: 12, HistoryRevision: 10,
+			},
+		},
+		{
+			name: "written before revisions",
+			fields: map[string]string{
+				metaVersion: "3", metaIsActive: "true", metaCreatedAt: created.Format(time.RFC3339Nano),
+			},
+			want: entities.ChatSession{IsActive: true, CreatedAt: created},
+		},
+		{
+			name: "activity fields only",
+			fields: map[string]string{
+				metaLastActivityAt: created.Format(time.RFC3339Nano), metaExpiryWarned: "false",
+			},
+			want: entities.ChatSession{LastActivityAt: created},
+		},
+		{
+			name:    "newer version",
+			fields:  map[string]string{metaVersion: "4"},
+			wantErr: ErrUnsupportedSessionVersion.Error(),
+		},
+		{
+			name:    "invalid revision",
+			fields:  map[string]string{metaRevision: "x"},
+			wantErr: "invalid revision",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var session entities.ChatSession
+			err := decodeSessionMeta(tt.fields, &session)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(session, tt.want) {
+				t.Errorf("session = %+v, want %+v", session, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionMetaRoundTrip(t *testing.T) {
+	now := time.Date(2024, 3, 1, 10, 0, 0, 123, time.UTC)
+	session := &entities.ChatSession{
+		IsActive:        true,
+		StartedBy:       -7,
+		ConversationID:  "c1",
+		LastActivityAt:  now,
+		ExpiryWarned:    true,
+		CreatedAt:       now.Add(-time.Hour),
+		UpdatedAt:       now,
+		Revision:        3,
+		HistoryRevision: 2,
+	}
+
+	// Redis отдаёт все поля хэша строками
+	fields := make(map[string]string)
+	for name, value := range encodeSessionMeta(session) {
+		fields[name] = fmt.Sprint(value)
+	}
+
+	var decoded entities.ChatSession
+	if err := decodeSessionMeta(fields, &decoded); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(&decoded, session) {
+		t.Errorf("decoded = %+v, want %+v", decoded, *session)
+	}
+}
+
+func TestMessageRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  entities.Message
+	}{
+		{
+			name: "user",
+			msg:  entities.Message{Role: "user", Content: "привет", Timestamp: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "assistant with tools",
+			msg: entities.Message{
+				Role: "assistant", Content: "4", Truncated: true, TelegramMessageID: 42, Model: "ollama:llama3.1",
+				Thinking: "2+2", Timestamp: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+				ToolCalls: []entities.ToolCall{{Name: "calc", Input: `{"expr":"2+2"}`, Output: "4"}, {Name: "x", IsError: true}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := encodeMessage(tt.msg)
+			if err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+			decoded, err := decodeMessage(data)
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if !reflect.DeepEqual(decoded, tt.msg) {
+				t.Errorf("decoded = %+v, want %+v", decoded, tt.msg)
+			}
+		})
+	}
+}
+
+// TestUpgradeSessionToHashLayout follows a blob session through the v3
+// conversion done on first read: metadata to the hash, messages to the list
+func TestUpgradeSessionToHashLayout(t *testing.T) {
+	blobs := map[string]string{
+		"v1": `{"ChatID":1,"UserID":2,"IsActive":true,"ConversationID":"c1",
+			"Messages":[{"Role":"user","Content":"вопрос","Timestamp":"2024-03-01T10:00:00Z"},
+				{"Role":"assistant","Content":"ответ","ToolCalls":[{"Name":"calc","Input":"{}","Output":"4"}],"Timestamp":"2024-03-01T10:00:01Z"}],
+			"LastActivityAt":"2024-03-01T10:00:01Z","CreatedAt":"2024-03-01T10:00:00Z","UpdatedAt":"2024-03-01T10:00:01Z"}`,
+		"v2": `{"version":2,"chat_id":1,"user_id":0,"is_active":true,"started_by":2,
+			"messages":[{"role":"user","content":"вопрос","timestamp":"2024-03-01T10:00:00Z"}],
+			"last_activity_at":"2024-03-01T10:00:00Z","expiry_warned":true,
+			"created_at":"2024-03-01T10:00:00Z","updated_at":"2024-03-01T10:00:00Z"}`,
+	}
+
+	for name, blob := range blobs {
+		t.Run(name, func(t *testing.T) {
+			session, _, err := decodeSessionBlob([]byte(blob))
+			if err != nil {
+				t.Fatalf("decode blob: %v", err)
+			}
+
+			fields := make(map[string]string)
+			for field, value := range encodeSessionMeta(session) {
+				fields[field] = fmt.Sprint(value)
+			}
+			if fields[metaVersion] != fmt.Sprint(currentSessionVersion) {
+				t.Errorf("version = %s, want %d", fields[metaVersion], currentSessionVersion)
+			}
+
+			upgraded := &entities.ChatSession{ChatID: session.ChatID, UserID: session.UserID, Messages: []entities.Message{}}
+			if err := decodeSessionMeta(fields, upgraded); err != nil {
+				t.Fatalf("decode meta: %v", err)
+			}
+			for _, msg := range session.Messages {
+				data, err := encodeMessage(msg)
+				if err != nil {
+					t.Fatalf("encode message: %v", err)
+				}
+				decoded, err := decodeMessage(data)
+				if err != nil {
+					t.Fatalf("decode message: %v", err)
+				}
+				upgraded.Messages = append(upgraded.Messages, decoded)
+			}
+
+			if !reflect.DeepEqual(upgraded, session) {
+				t.Errorf("upgraded = %+v, want %+v", upgraded, session)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+)
+
+// SQLDialect selects the SQL database flavour
+type SQLDialect string
+
+const (
+	SQLite   SQLDialect = "sqlite"
+	Postgres SQLDialect = "postgres"
+)
+
+// sqlMigration is one step of the schema. Statements run in order inside a
+// transaction and must work on both SQLite and Postgres.
+type sqlMigration struct {
+	version    int
+	statements []string
+}
+
+var sqlMigrations = []sqlMigration{
+	{
+		version: 1,
+		statements: []string{
+			`CREATE TABLE sessions (
+				chat_id          BIGINT    NOT NULL,
+				user_id          BIGINT    NOT NULL,
+				is_active        BOOLEAN   NOT NULL,
+				started_by       BIGINT    NOT NULL DEFAULT 0,
+				conversation_id  TEXT      NOT NULL DEFAULT '',
+				last_activity_at TIMESTAMP NULL,
+				expiry_warned    BOOLEAN   NOT NULL DEFAULT FALSE,
+				created_at       TIMESTAMP NOT NULL,
+				updated_at       TIMESTAMP NOT NULL,
+				PRIMARY KEY (chat_id, user_id)
+			)`,
+			`CREATE INDEX sessions_activity_idx ON sessions (is_active, last_activity_at)`,
+			`CREATE TABLE messages (
+				chat_id             BIGINT    NOT NULL,
+				user_id             BIGINT    NOT NULL,
+				position            INTEGER   NOT NULL,
+				role                TEXT      NOT NULL,
+				content             TEXT      NOT NULL,
+				truncated           BOOLEAN   NOT NULL DEFAULT FALSE,
+				telegram_message_id BIGINT    NOT NULL DEFAULT 0,
+				created_at          TIMESTAMP NOT NULL,
+				PRIMARY KEY (chat_id, user_id, position),
+				FOREIGN KEY (chat_id, user_id) REFERENCES sessions (chat_id, user_id) ON DELETE CASCADE
+			)`,
+			`CREATE TABLE tool_calls (
+				chat_id  BIGINT  NOT NULL,
+				user_id  BIGINT  NOT NULL,
+				position INTEGER NOT NULL,
+				seq      INTEGER NOT NULL,
+				name     TEXT    NOT NULL,
+				input    TEXT    NOT NULL,
+				output   TEXT    NOT NULL,
+				is_error BOOLEAN NOT NULL DEFAULT FALSE,
+				PRIMARY KEY (chat_id, user_id, position, seq),
+				FOREIGN KEY (chat_id, user_id, position) REFERENCES messages (chat_id, user_id, position) ON DELETE CASCADE
+			)`,
+		},
+	},
+	{
+		// Модель, сгенерировавшая ответ, с учётом резервных
+		version: 2,
+		statements: []string{
+			`ALTER TABLE messages ADD COLUMN model TEXT NOT NULL DEFAULT ''`,
+		},
+	},
+	{
+		// Ход рассуждений модели в режиме /think
+		version: 3,
+		statements: []string{
+			`ALTER TABLE messages ADD COLUMN thinking TEXT NOT NULL DEFAULT ''`,
+		},
+	},
+	{
+		// Ревизии для условной записи сессий
+		version: 4,
+		statements: []string{
+			`ALTER TABLE sessions ADD COLUMN revision BIGINT NOT NULL DEFAULT 0`,
+			`ALTER TABLE sessions ADD COLUMN history_revision BIGINT NOT NULL DEFAULT 0`,
+		},
+	},
+}
+
+// OpenSQLDatabase connects to the database and brings the schema up to date.
+// For SQLite dsn is the path to the database file.
+func OpenSQLDatabase(dialect SQLDialect, dsn string) (*sql.DB, error) {
+	var db *sql.DB
+	var err error
+
+	switch dialect {
+	case SQLite:
+		if dir := filepath.Dir(dsn); dir != "." {
+			if err := os.MkdirAll(dir, 0o700); err != nil {
+				return nil, fmt.Errorf("failed to create database directory: %w", err)
+			}
+		}
+		db, err = sql.Open("sqlite", "file:"+dsn+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+		if err == nil {
+			// SQLite допускает одного писателя, сериализуем доступ на стороне пула
+			db.SetMaxOpenConns(1)
+		}
+	case Postgres:
+		db, err = sql.Open("postgres", dsn)
+	default:
+		return nil, fmt.Errorf("unknown SQL dialect %q", dialect)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s database: %w", dialect, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to %s database: %w", dialect, err)
+	}
+
+	if err := migrateSQLDatabase(ctx, db, dialect); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// migrateSQLDatabase applies the migrations newer than the recorded schema version
+func migrateSQLDatabase(ctx context.Context, db *sql.DB, dialect SQLDialect) error {
+	if _, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER   PRIMARY KEY,
+		applied_at TIMESTAMP NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	var current int
+	if err := db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	for _, migration := range sqlMigrations {
+		if migration.version <= current {
+			continue
+		}
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to start migration %d: %w", migration.version, err)
+		}
+
+		for _, statement := range migration.statements {
+			if _, err := tx.ExecContext(ctx, statement); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("failed to apply migration %d: %w", migration.version, err)
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx, rebind(dialect, `INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`),
+			migration.version, time.Now().UTC()); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record migration %d: %w", migration.version, err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %d: %w", migration.version, err)
+		}
+	}
+
+	return nil
+}
+
+// rebind converts ? placeholders to the $1, $2 form used by Postgres
+func rebind(dialect SQLDialect, query string) string {
+	if dialect != Postgres {
+		return query
+	}
+
+	var sb strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			sb.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
@@ -0,0 +1,559 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"telegram-chatbot/internal/domain/entities"
+	"telegram-chatbot/internal/domain/repositories"
+	"telegram-chatbot/internal/infrastructure/encryption"
+	"time"
+)
+
+// sealedPrefix marks an encrypted column value: the record is stored in
+// base64, because TEXT columns in Postgres do not accept arbitrary bytes
+const sealedPrefix = "enc64:"
+
+// SQLSessionRepository keeps sessions in SQLite or Postgres. Messages and
+// tool calls live in their own tables and are never expired. With a keyring
+// the message text, thinking and tool input and output are encrypted.
+type SQLSessionRepository struct {
+	db      *sql.DB
+	dialect SQLDialect
+	keyring *encryption.Keyring
+}
+
+func NewSQLSessionRepository(db *sql.DB, dialect SQLDialect, keyring *encryption.Keyring) repositories.SessionRepository {
+	return &SQLSessionRepository{
+		db:      db,
+		dialect: dialect,
+		keyring: keyring,
+	}
+}
+
+func (r *SQLSessionRepository) query(query string) string {
+	return rebind(r.dialect, query)
+}
+
+// associatedData binds encrypted values to their session, the same way as
+// the history entries in Redis
+func (r *SQLSessionRepository) associatedData(chatID, userID int64) []byte {
+	return []byte(fmt.Sprintf("session:%d:%d:messages", chatID, userID))
+}
+
+// seal encrypts a column value with the current key
+func (r *SQLSessionRepository) seal(value string, associatedData []byte) (string, error) {
+	if !r.keyring.Enabled() || value == "" {
+		return value, nil
+	}
+
+	record, err := r.keyring.Encrypt([]byte(value), associatedData)
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt session message: %w", err)
+	}
+	return sealedPrefix + base64.StdEncoding.EncodeToString(record), nil
+}
+
+// open decrypts a column value written by seal. Values stored before
+// encryption was enabled are returned unchanged.
+func (r *SQLSessionRepository) open(value string, associatedData []byte) (string, error) {
+	if !strings.HasPrefix(value, sealedPrefix) {
+		return value, nil
+	}
+
+	record, err := base64.StdEncoding.DecodeString(value[len(sealedPrefix):])
+	if err != nil {
+		return "", fmt.Errorf("malformed encrypted session message: %w", err)
+	}
+	data, err := r.keyring.Decrypt(record, associatedData)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt session message: %w", err)
+	}
+	return string(data), nil
+}
+
+// sealAll encrypts several column values in place
+func (r *SQLSessionRepository) sealAll(associatedData []byte, values ...*string) error {
+	for _, value := range values {
+		sealed, err := r.seal(*value, associatedData)
+		if err != nil {
+			return err
+		}
+		*value = sealed
+	}
+	return nil
+}
+
+// openAll decrypts several column values in place
+func (r *SQLSessionRepository) openAll(associatedData []byte, values ...*string) error {
+	for _, value := range values {
+		opened, err := r.open(*value, associatedData)
+		if err != nil {
+			return err
+		}
+		*value = opened
+	}
+	return nil
+}
+
+func (r *SQLSessionRepository) GetSession(chatID, userID int64) (*entities.ChatSession, error) {
+	ctx := context.Background()
+
+	session := &entities.ChatSession{
+		ChatID: chatID,
+		UserID: userID,
+	}
+	var lastActivity sql.NullTime
+
+	err := r.db.QueryRowContext(ctx, r.query(`
+		SELECT is_active, started_by, conversation_id, last_activity_at, expiry_warned, revision, history_revision, created_at, updated_at
+		FROM sessions WHERE chat_id = ? AND user_id = ?`), chatID, userID).Scan(
+		&session.IsActive,
+		&session.StartedBy,
+		&session.ConversationID,
+		&lastActivity,
+		&session.ExpiryWarned,
+		&session.Revision,
+		&session.HistoryRevision,
+		&session.CreatedAt,
+		&session.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		// Session doesn't exist, create a new one
+		return &entities.ChatSession{
+			ChatID:    chatID,
+			UserID:    userID,
+			IsActive:  false,
+			Messages:  []entities.Message{},
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get session from database: %w", err)
+	}
+	session.LastActivityAt = lastActivity.Time
+
+	if session.Messages, err = r.loadMessages(ctx, chatID, userID); err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// loadMessages reads the history of a session with the tool calls of each message
+func (r *SQLSessionRepository) loadMessages(ctx context.Context, chatID, userID int64) ([]entities.Message, error) {
+	associatedData := r.associatedData(chatID, userID)
+
+	rows, err := r.db.QueryContext(ctx, r.query(`
+		SELECT role, content, truncated, telegram_message_id, model, thinking, created_at
+		FROM messages WHERE chat_id = ? AND user_id = ? ORDER BY position`), chatID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get messages from database: %w", err)
+	}
+	defer rows.Close()
+
+	messages := []entities.Message{}
+	for rows.Next() {
+		var msg entities.Message
+		if err := rows.Scan(&msg.Role, &msg.Content, &msg.Truncated, &msg.TelegramMessageID, &msg.Model, &msg.Thinking, &msg.Timestamp); err != nil {
+			return nil, fmt.Errorf("failed to scan message: %w", err)
+		}
+		if err := r.openAll(associatedData, &msg.Content, &msg.Thinking); err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read messages: %w", err)
+	}
+
+	toolRows, err := r.db.QueryContext(ctx, r.query(`
+		SELECT position, name, input, output, is_error
+		FROM tool_calls WHERE chat_id = ? AND user_id = ? ORDER BY position, seq`), chatID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tool calls from database: %w", err)
+	}
+	defer toolRows.Close()
+
+	for toolRows.Next() {
+		var position int
+		var call entities.ToolCall
+		if err := toolRows.Scan(&position, &call.Name, &call.Input, &call.Output, &call.IsError); err != nil {
+			return nil, fmt.Errorf("failed to scan tool call: %w", err)
+		}
+		if err := r.openAll(associatedData, &call.Input, &call.Output); err != nil {
+			return nil, err
+		}
+		if position >= 0 && position < len(messages) {
+			messages[position].ToolCalls = append(messages[position].ToolCalls, call)
+		}
+	}
+	if err := toolRows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read tool calls: %w", err)
+	}
+
+	return messages, nil
+}
+
+func (r *SQLSessionRepository) SaveSession(session *entities.ChatSession) error {
+	ctx := context.Background()
+
+	updatedAt := time.Now()
+	revision := session.Revision + 1
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	lastActivity := nullTime(session.LastActivityAt)
+
+	// Строка меняется, только если с момента чтения её никто не переписал
+	result, err := tx.ExecContext(ctx, r.query(`
+		UPDATE sessions SET
+			is_active = ?, started_by = ?, conversation_id = ?, last_activity_at = ?, expiry_warned = ?,
+			revision = ?, history_revision = ?, updated_at = ?
+		WHERE chat_id = ? AND user_id = ? AND revision = ?`),
+		session.IsActive, session.StartedBy, session.ConversationID, lastActivity, session.ExpiryWarned,
+		revision, revision, updatedAt.UTC(),
+		session.ChatID, session.UserID, session.Revision,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save session to database: %w", err)
+	}
+
+	if updated, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to save session to database: %w", err)
+	} else if updated == 0 {
+		// Строки нет: сессия новая, если её не удалили после чтения
+		if session.Revision != 0 {
+			return repositories.ErrSessionChanged
+		}
+		result, err := tx.ExecContext(ctx, r.query(`
+			INSERT INTO sessions (chat_id, user_id, is_active, started_by, conversation_id, last_activity_at, expiry_warned,
+				revision, history_revision, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (chat_id, user_id) DO NOTHING`),
+			session.ChatID, session.UserID, session.IsActive, session.StartedBy, session.ConversationID, lastActivity,
+			session.ExpiryWarned, revision, revision, session.CreatedAt.UTC(), updatedAt.UTC(),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to save session to database: %w", err)
+		}
+		if inserted, err := result.RowsAffected(); err == nil && inserted == 0 {
+			return repositories.ErrSessionChanged
+		}
+	}
+
+	// История переписывается целиком, новые сообщения добавляет AppendMessage
+	if err := r.deleteMessages(ctx, tx, session.ChatID, session.UserID); err != nil {
+		return err
+	}
+
+	for position, msg := range session.Messages {
+		if err := r.insertMessage(ctx, tx, session.ChatID, session.UserID, position, msg); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit session: %w", err)
+	}
+
+	session.UpdatedAt = updatedAt
+	session.Revision = revision
+	session.HistoryRevision = revision
+	return nil
+}
+
+// nullTime stores a zero time as NULL
+func nullTime(t time.Time) sql.NullTime {
+	if t.IsZero() {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
+
+func (r *SQLSessionRepository) insertMessage(ctx context.Context, tx *sql.Tx, chatID, userID int64, position int, msg entities.Message) error {
+	content, thinking := msg.Content, msg.Thinking
+	if err := r.sealAll(r.associatedData(chatID, userID), &content, &thinking); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, r.query(`
+		INSERT INTO messages (chat_id, user_id, position, role, content, truncated, telegram_message_id, model, thinking, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+		chatID, userID, position, msg.Role, content, msg.Truncated, msg.TelegramMessageID, msg.Model, thinking, msg.Timestamp.UTC(),
+	); err != nil {
+		return fmt.Errorf("failed to save message to database: %w", err)
+	}
+
+	return r.insertToolCalls(ctx, tx, chatID, userID, position, msg.ToolCalls)
+}
+
+func (r *SQLSessionRepository) insertToolCalls(ctx context.Context, tx *sql.Tx, chatID, userID int64, position int, calls []entities.ToolCall) error {
+	associatedData := r.associatedData(chatID, userID)
+	for seq, call := range calls {
+		if err := r.sealAll(associatedData, &call.Input, &call.Output); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, r.query(`
+			INSERT INTO tool_calls (chat_id, user_id, position, seq, name, input, output, is_error)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`),
+			chatID, userID, position, seq, call.Name, call.Input, call.Output, call.IsError,
+		); err != nil {
+			return fmt.Errorf("failed to save tool call to database: %w", err)
+		}
+	}
+	return nil
+}
+
+// lastPosition returns the position of the last stored message or -1
+func (r *SQLSessionRepository) lastPosition(ctx context.Context, tx *sql.Tx, chatID, userID int64) (int, error) {
+	var position int
+	err := tx.QueryRowContext(ctx, r.query(`
+		SELECT COALESCE(MAX(position), -1) FROM messages WHERE chat_id = ? AND user_id = ?`), chatID, userID).Scan(&position)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get last message position: %w", err)
+	}
+	return position, nil
+}
+
+func (r *SQLSessionRepository) AppendMessage(session *entities.ChatSession, messages ...entities.Message) error {
+	ctx := context.Background()
+
+	updatedAt := time.Now()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Ходы других запросов не мешают дописать свой, но в завершённую или
+	// начатую заново сессию ответ не попадает. Остальные поля не трогаем.
+	result, err := tx.ExecContext(ctx, r.query(`
+		UPDATE sessions SET last_activity_at = ?, expiry_warned = ?, updated_at = ?, revision = revision + 1
+		WHERE chat_id = ? AND user_id = ? AND is_active AND history_revision = ?`),
+		nullTime(session.LastActivityAt), session.ExpiryWarned, updatedAt.UTC(),
+		session.ChatID, session.UserID, session.HistoryRevision)
+	if err != nil {
+		return fmt.Errorf("failed to update session in database: %w", err)
+	}
+	if err := r.checkUpdated(result); err != nil {
+		return err
+	}
+
+	var revision int64
+	if err := tx.QueryRowContext(ctx, r.query(`
+		SELECT revision FROM sessions WHERE chat_id = ? AND user_id = ?`),
+		session.ChatID, session.UserID).Scan(&revision); err != nil {
+		return fmt.Errorf("failed to read session revision: %w", err)
+	}
+
+	position, err := r.lastPosition(ctx, tx, session.ChatID, session.UserID)
+	if err != nil {
+		return err
+	}
+
+	for _, msg := range messages {
+		position++
+		if err := r.insertMessage(ctx, tx, session.ChatID, session.UserID, position, msg); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit session messages: %w", err)
+	}
+
+	session.UpdatedAt = updatedAt
+	session.Revision = revision
+	return nil
+}
+
+// checkUpdated turns an update that matched no row into ErrSessionChanged
+func (r *SQLSessionRepository) checkUpdated(result sql.Result) error {
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update session in database: %w", err)
+	}
+	if updated == 0 {
+		return repositories.ErrSessionChanged
+	}
+	return nil
+}
+
+// bumpRevision moves the revision on if it still equals the session's
+func (r *SQLSessionRepository) bumpRevision(ctx context.Context, tx *sql.Tx, session *entities.ChatSession, updatedAt time.Time) error {
+	result, err := tx.ExecContext(ctx, r.query(`
+		UPDATE sessions SET updated_at = ?, revision = revision + 1
+		WHERE chat_id = ? AND user_id = ? AND revision = ?`),
+		updatedAt.UTC(), session.ChatID, session.UserID, session.Revision)
+	if err != nil {
+		return fmt.Errorf("failed to update session in database: %w", err)
+	}
+	return r.checkUpdated(result)
+}
+
+func (r *SQLSessionRepository) UpdateLastMessage(session *entities.ChatSession) error {
+	ctx := context.Background()
+
+	last := session.LastMessage()
+	if last == nil {
+		return nil
+	}
+
+	updatedAt := time.Now()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Раз ревизия не изменилась, последняя позиция - то самое сообщение
+	if err := r.bumpRevision(ctx, tx, session, updatedAt); err != nil {
+		return err
+	}
+
+	position, err := r.lastPosition(ctx, tx, session.ChatID, session.UserID)
+	if err != nil || position < 0 {
+		return err
+	}
+
+	content, thinking := last.Content, last.Thinking
+	if err := r.sealAll(r.associatedData(session.ChatID, session.UserID), &content, &thinking); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, r.query(`
+		UPDATE messages SET content = ?, truncated = ?, telegram_message_id = ?, model = ?, thinking = ?
+		WHERE chat_id = ? AND user_id = ? AND position = ?`),
+		content, last.Truncated, last.TelegramMessageID, last.Model, thinking, session.ChatID, session.UserID, position,
+	); err != nil {
+		return fmt.Errorf("failed to update message in database: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, r.query(`
+		DELETE FROM tool_calls WHERE chat_id = ? AND user_id = ? AND position = ?`),
+		session.ChatID, session.UserID, position,
+	); err != nil {
+		return fmt.Errorf("failed to delete tool calls from database: %w", err)
+	}
+	if err := r.insertToolCalls(ctx, tx, session.ChatID, session.UserID, position, last.ToolCalls); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit session message: %w", err)
+	}
+
+	session.UpdatedAt = updatedAt
+	session.Revision++
+	return nil
+}
+
+func (r *SQLSessionRepository) UpdateActivity(session *entities.ChatSession) error {
+	ctx := context.Background()
+
+	updatedAt := time.Now()
+
+	result, err := r.db.ExecContext(ctx, r.query(`
+		UPDATE sessions SET last_activity_at = ?, expiry_warned = ?, updated_at = ?, revision = revision + 1
+		WHERE chat_id = ? AND user_id = ? AND revision = ?`),
+		nullTime(session.LastActivityAt), session.ExpiryWarned, updatedAt.UTC(),
+		session.ChatID, session.UserID, session.Revision)
+	if err != nil {
+		return fmt.Errorf("failed to update session in database: %w", err)
+	}
+	if err := r.checkUpdated(result); err != nil {
+		return err
+	}
+
+	session.UpdatedAt = updatedAt
+	session.Revision++
+	return nil
+}
+
+func (r *SQLSessionRepository) deleteMessages(ctx context.Context, tx *sql.Tx, chatID, userID int64) error {
+	if _, err := tx.ExecContext(ctx, r.query(`DELETE FROM tool_calls WHERE chat_id = ? AND user_id = ?`), chatID, userID); err != nil {
+		return fmt.Errorf("failed to delete tool calls from database: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, r.query(`DELETE FROM messages WHERE chat_id = ? AND user_id = ?`), chatID, userID); err != nil {
+		return fmt.Errorf("failed to delete messages from database: %w", err)
+	}
+	return nil
+}
+
+func (r *SQLSessionRepository) DeleteSession(chatID, userID int64) error {
+	ctx := context.Background()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := r.deleteMessages(ctx, tx, chatID, userID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, r.query(`DELETE FROM sessions WHERE chat_id = ? AND user_id = ?`), chatID, userID); err != nil {
+		return fmt.Errorf("failed to delete session from database: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit session deletion: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SQLSessionRepository) IsSessionActive(chatID, userID int64) bool {
+	var active bool
+	err := r.db.QueryRowContext(context.Background(), r.query(`
+		SELECT is_active FROM sessions WHERE chat_id = ? AND user_id = ?`), chatID, userID).Scan(&active)
+	return err == nil && active
+}
+
+func (r *SQLSessionRepository) ListIdleSessions(idleSince time.Time) ([]*entities.ChatSession, error) {
+	ctx := context.Background()
+
+	rows, err := r.db.QueryContext(ctx, r.query(`
+		SELECT chat_id, user_id FROM sessions
+		WHERE is_active AND COALESCE(last_activity_at, updated_at) < ?`), idleSince.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get idle sessions from database: %w", err)
+	}
+
+	type sessionKey struct{ chatID, userID int64 }
+	var keys []sessionKey
+	for rows.Next() {
+		var key sessionKey
+		if err := rows.Scan(&key.chatID, &key.userID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan idle session: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read idle sessions: %w", err)
+	}
+
+	// Сессии читаем после закрытия курсора: у SQLite одно соединение
+	sessions := make([]*entities.ChatSession, 0, len(keys))
+	for _, key := range keys {
+		session, err := r.GetSession(key.chatID, key.userID)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, nil
+}
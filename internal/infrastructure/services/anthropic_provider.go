@@ -0,0 +1,310 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"telegram-chatbot/internal/domain/entities"
+	"time"
+)
+
+const anthropicMessagesURL = "https://api.anthropic.com/v1/messages"
+
+// Размышления заметно удлиняют ответ, поэтому им отводится больше времени
+const (
+	anthropicTimeout         = 30 * time.Second
+	anthropicThinkingTimeout = 3 * time.Minute
+)
+
+// AnthropicProvider talks to the Anthropic Messages API
+type AnthropicProvider struct {
+	apiKey     string
+	httpClient *http.Client
+}
+
+func NewAnthropicProvider(apiKey string) *AnthropicProvider {
+	return &AnthropicProvider{
+		apiKey: apiKey,
+		// Время ожидания задаётся контекстом каждого запроса
+		httpClient: &http.Client{},
+	}
+}
+
+// SupportsPrefill reports that the Messages API continues a prefilled reply
+func (p *AnthropicProvider) SupportsPrefill() bool {
+	return true
+}
+
+// modelsWithoutThinking are the Claude families released before extended
+// thinking. The API rejects the thinking parameter for them with 400.
+var modelsWithoutThinking = []string{
+	"claude-3-5-",
+	"claude-3-opus",
+	"claude-3-sonnet",
+	"claude-3-haiku",
+	"claude-2",
+	"claude-instant",
+}
+
+// SupportsThinking reports whether the model accepts extended thinking
+func (p *AnthropicProvider) SupportsThinking(model string) bool {
+	for _, prefix := range modelsWithoutThinking {
+		if strings.HasPrefix(model, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+type ClaudeMessage struct {
+	Role    string      `json:"role"`
+	Content interface{} `json:"content"` // строка или []ContentBlock
+}
+
+// ContentBlock is a typed block of message content (text, tool_use, tool_result)
+type ContentBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   string          `json:"content,omitempty"`
+	IsError   bool            `json:"is_error,omitempty"`
+	Thinking  string          `json:"thinking,omitempty"`
+	Signature string          `json:"signature,omitempty"`
+	Data      string          `json:"data,omitempty"` // redacted_thinking
+	// CacheControl marks the end of a prefix to cache
+	CacheControl *ClaudeCacheControl `json:"cache_control,omitempty"`
+}
+
+// ClaudeCacheControl is a prompt caching breakpoint. Ephemeral entries live
+// five minutes and are refreshed by every request that reads them.
+type ClaudeCacheControl struct {
+	Type string `json:"type"`
+}
+
+var ephemeralCache = &ClaudeCacheControl{Type: "ephemeral"}
+
+type ClaudeTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+type ClaudeToolChoice struct {
+	Type string `json:"type"`
+}
+
+// ClaudeThinking enables extended thinking with a token budget
+type ClaudeThinking struct {
+	Type         string `json:"type"`
+	BudgetTokens int    `json:"budget_tokens"`
+}
+
+type ClaudeRequest struct {
+	Model      string            `json:"model"`
+	MaxTokens  int               `json:"max_tokens"`
+	Messages   []ClaudeMessage   `json:"messages"`
+	System     []ContentBlock    `json:"system,omitempty"`
+	Tools      []ClaudeTool      `json:"tools,omitempty"`
+	ToolChoice *ClaudeToolChoice `json:"tool_choice,omitempty"`
+	Thinking   *ClaudeThinking   `json:"thinking,omitempty"`
+}
+
+type ClaudeResponse struct {
+	Content    []ContentBlock `json:"content"`
+	StopReason string         `json:"stop_reason"`
+	Usage      ClaudeUsage    `json:"usage"`
+}
+
+// ClaudeUsage reports billed tokens. input_tokens does not include the
+// tokens read from or written to the cache.
+type ClaudeUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+func (p *AnthropicProvider) Complete(ctx context.Context, request CompletionRequest) (*CompletionResponse, error) {
+	claudeRequest := ClaudeRequest{
+		Model:     request.Model,
+		MaxTokens: request.MaxTokens,
+		Messages:  make([]ClaudeMessage, 0, len(request.Messages)),
+	}
+
+	if request.System != "" {
+		system := ContentBlock{Type: "text", Text: request.System}
+		// Кэш включает инструменты и системный промпт, они идут перед сообщениями
+		if request.CacheSystem {
+			system.CacheControl = ephemeralCache
+		}
+		claudeRequest.System = []ContentBlock{system}
+	}
+
+	for i, msg := range request.Messages {
+		// API не принимает завершающий пробел в предзаполненном ответе ассистента
+		if i == len(request.Messages)-1 && msg.Role == "assistant" {
+			msg.Text = strings.TrimRight(msg.Text, " \t\n")
+		}
+		claudeRequest.Messages = append(claudeRequest.Messages, claudeMessage(msg))
+	}
+
+	for _, tool := range request.Tools {
+		claudeRequest.Tools = append(claudeRequest.Tools, ClaudeTool{
+			Name:        tool.Name,
+			Description: tool.Description,
+			InputSchema: tool.InputSchema,
+		})
+	}
+	if len(claudeRequest.Tools) > 0 && request.DisableTools {
+		claudeRequest.ToolChoice = &ClaudeToolChoice{Type: "none"}
+	}
+
+	timeout := anthropicTimeout
+	// Резервная модель может не уметь размышлять, тогда отвечает сразу
+	if request.ThinkingBudget > 0 && p.SupportsThinking(request.Model) && canThink(request.Messages) {
+		claudeRequest.Thinking = &ClaudeThinking{Type: "enabled", BudgetTokens: request.ThinkingBudget}
+		timeout = anthropicThinkingTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	claudeResp, err := p.send(ctx, claudeRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &CompletionResponse{
+		StopReason: claudeResp.StopReason,
+		Usage: entities.TokenUsage{
+			InputTokens:      claudeResp.Usage.InputTokens,
+			OutputTokens:     claudeResp.Usage.OutputTokens,
+			CacheReadTokens:  claudeResp.Usage.CacheReadInputTokens,
+			CacheWriteTokens: claudeResp.Usage.CacheCreationInputTokens,
+		},
+	}
+	var text strings.Builder
+	for _, block := range claudeResp.Content {
+		switch block.Type {
+		case "text":
+			text.WriteString(block.Text)
+		case "tool_use":
+			response.ToolUses = append(response.ToolUses, ToolUse{
+				ID:    block.ID,
+				Name:  block.Name,
+				Input: block.Input,
+			})
+		case "thinking":
+			response.Thinking = append(response.Thinking, ThinkingBlock{Text: block.Thinking, Signature: block.Signature})
+		case "redacted_thinking":
+			response.Thinking = append(response.Thinking, ThinkingBlock{Redacted: block.Data})
+		}
+	}
+	response.Text = text.String()
+
+	return response, nil
+}
+
+// canThink reports whether thinking may be enabled for the conversation. The
+// API rejects a prefilled assistant reply with thinking, and a tool call made
+// without thinking, for example by a fallback model, cannot be continued with it.
+func canThink(messages []CompletionMessage) bool {
+	if n := len(messages); n > 0 && messages[n-1].Role == "assistant" {
+		return false
+	}
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "assistant" {
+			return len(messages[i].ToolUses) == 0 || len(messages[i].Thinking) > 0
+		}
+	}
+	return true
+}
+
+// claudeMessage converts a turn into the Messages API form. Plain text turns
+// are sent as a string, turns with tools, thinking or a cache breakpoint as
+// content blocks.
+func claudeMessage(msg CompletionMessage) ClaudeMessage {
+	if len(msg.ToolUses) == 0 && len(msg.ToolResults) == 0 && len(msg.Thinking) == 0 && (!msg.CacheBreakpoint || msg.Text == "") {
+		return ClaudeMessage{Role: msg.Role, Content: msg.Text}
+	}
+
+	blocks := make([]ContentBlock, 0, 1+len(msg.Thinking)+len(msg.ToolUses)+len(msg.ToolResults))
+	// Размышления возвращаются первыми и без изменений, иначе подпись не сойдётся
+	for _, thinking := range msg.Thinking {
+		if thinking.Redacted != "" {
+			blocks = append(blocks, ContentBlock{Type: "redacted_thinking", Data: thinking.Redacted})
+		} else {
+			blocks = append(blocks, ContentBlock{Type: "thinking", Thinking: thinking.Text, Signature: thinking.Signature})
+		}
+	}
+	if msg.Text != "" {
+		blocks = append(blocks, ContentBlock{Type: "text", Text: msg.Text})
+	}
+	for _, use := range msg.ToolUses {
+		blocks = append(blocks, ContentBlock{
+			Type:  "tool_use",
+			ID:    use.ID,
+			Name:  use.Name,
+			Input: use.Input,
+		})
+	}
+	for _, result := range msg.ToolResults {
+		blocks = append(blocks, ContentBlock{
+			Type:      "tool_result",
+			ToolUseID: result.ToolUseID,
+			Content:   result.Content,
+			IsError:   result.IsError,
+		})
+	}
+
+	if msg.CacheBreakpoint && len(blocks) > 0 {
+		blocks[len(blocks)-1].CacheControl = ephemeralCache
+	}
+
+	return ClaudeMessage{Role: msg.Role, Content: blocks}
+}
+
+func (p *AnthropicProvider) send(ctx context.Context, request ClaudeRequest) (*ClaudeResponse, error) {
+	jsonData, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", anthropicMessagesURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", p.apiKey)
+	req.Header.Set("anthropic-version", "2023-06-01")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	var claudeResp ClaudeResponse
+	if err := json.Unmarshal(body, &claudeResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return &claudeResp, nil
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// CircuitBreaker stops sending requests to a backend after consecutive
+// failures. Once the cooldown passes, a single probe request is let through:
+// success closes the breaker, failure opens it for another cooldown.
+type CircuitBreaker struct {
+	maxFailures int
+	cooldown    time.Duration
+
+	mutex    sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+}
+
+func NewCircuitBreaker(maxFailures int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		maxFailures: maxFailures,
+		cooldown:    cooldown,
+	}
+}
+
+// Allow reports whether a request may be sent now. In the half-open state
+// only the first caller gets through until the probe reports its result.
+func (b *CircuitBreaker) Allow() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = breakerHalfOpen
+		return true
+	case breakerHalfOpen:
+		// Пробный запрос уже отправлен, ждём его результата
+		return false
+	default:
+		return true
+	}
+}
+
+// Success closes the breaker and resets the failure count
+func (b *CircuitBreaker) Success() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.state = breakerClosed
+	b.failures = 0
+}
+
+// Failure counts a failed request and opens the breaker when the limit is
+// reached or the half-open probe fails
+func (b *CircuitBreaker) Failure() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.maxFailures {
+		b.state = breakerOpen
+		b.openedAt = time.Now()
+	}
+}
+
+// Release returns an unanswered half-open probe, for example when the
+// request was cancelled, so the next caller can probe instead
+func (b *CircuitBreaker) Release() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.state == breakerHalfOpen {
+		b.state = breakerOpen
+		b.openedAt = time.Now().Add(-b.cooldown)
+	}
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// breakerStep is an action on the breaker; for "allow" want is the expected result
+type breakerStep struct {
+	action string
+	want   bool
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	// Без паузы: после открытия следующий Allow сразу пропускает пробный запрос
+	const noCooldown = 0
+	const longCooldown = time.Hour
+
+	tests := []struct {
+		name     string
+		cooldown time.Duration
+		steps    []breakerStep
+	}{
+		{
+			name:     "closed lets requests through",
+			cooldown: longCooldown,
+			steps: []breakerStep{
+				{"allow", true}, {"failure", false},
+				{"allow", true}, {"failure", false},
+				{"allow", true},
+			},
+		},
+		{
+			name:     "opens after consecutive failures",
+			cooldown: longCooldown,
+			steps: []breakerStep{
+				{"failure", false}, {"failure", false}, {"failure", false},
+				{"allow", false}, {"allow", false},
+			},
+		},
+		{
+			name:     "success resets the count",
+			cooldown: longCooldown,
+			steps: []breakerStep{
+				{"failure", false}, {"failure", false}, {"success", false},
+				{"failure", false}, {"failure", false},
+				{"allow", true},
+			},
+		},
+		{
+			name:     "one probe after the cooldown",
+			cooldown: noCooldown,
+			steps: []breakerStep{
+				{"failure", false}, {"failure", false}, {"failure", false},
+				{"allow", true}, // пробный запрос
+				{"allow", false},
+			},
+		},
+		{
+			name:     "successful probe closes",
+			cooldown: noCooldown,
+			steps: []breakerStep{
+				{"failure", false}, {"failure", false}, {"failure", false},
+				{"allow", true}, {"success", false},
+				{"allow", true}, {"allow", true},
+			},
+		},
+		{
+			name:     "failed probe opens again",
+			cooldown: noCooldown,
+			steps: []breakerStep{
+				{"failure", false}, {"failure", false}, {"failure", false},
+				{"allow", true}, {"failure", false},
+				{"allow", true}, // пауза нулевая, снова пробный запрос
+				{"allow", false},
+			},
+		},
+		{
+			name:     "released probe goes to the next caller",
+			cooldown: longCooldown,
+			steps: []breakerStep{
+				{"open-expired", false},
+				{"allow", true}, {"release", false},
+				{"allow", true}, {"allow", false},
+			},
+		},
+		{
+			name:     "release in closed state changes nothing",
+			cooldown: longCooldown,
+			steps: []breakerStep{
+				{"failure", false}, {"failure", false}, {"release", false},
+				{"allow", true}, {"failure", false},
+				{"allow", false},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breaker := NewCircuitBreaker(3, tt.cooldown)
+			for i, step := range tt.steps {
+				switch step.action {
+				case "allow":
+					if got := breaker.Allow(); got != step.want {
+						t.Fatalf("step %d: Allow() = %v, want %v", i, got, step.want)
+					}
+				case "success":
+					breaker.Success()
+				case "failure":
+					breaker.Failure()
+				case "release":
+					breaker.Release()
+				case "open-expired":
+					// Открыт так давно, что пауза уже прошла
+					breaker.state = breakerOpen
+					breaker.openedAt = time.Now().Add(-2 * tt.cooldown)
+				}
+			}
+		})
+	}
+}
+
+func TestIsBackendFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "bad request", err: &StatusError{StatusCode: 400}, want: false},
+		{name: "unauthorized", err: &StatusError{StatusCode: 401}, want: false},
+		{name: "not found", err: &StatusError{StatusCode: 404}, want: false},
+		{name: "rate limited", err: &StatusError{StatusCode: 429}, want: true},
+		{name: "server error", err: &StatusError{StatusCode: 500}, want: true},
+		{name: "overloaded", err: &StatusError{StatusCode: 529}, want: true},
+		{name: "wrapped", err: fmt.Errorf("failed to send request: %w", &StatusError{StatusCode: 400}), want: false},
+		{name: "transport", err: fmt.Errorf("connection refused"), want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isBackendFailure(tt.err); got != tt.want {
+				t.Errorf("isBackendFailure(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
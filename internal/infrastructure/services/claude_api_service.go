@@ -1,102 +1,378 @@
 package services
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
-	"net/http"
+	"strings"
 	"telegram-chatbot/internal/domain/entities"
+	"telegram-chatbot/internal/domain/redaction"
+	"telegram-chatbot/internal/domain/repositories"
 	"telegram-chatbot/internal/domain/services"
-	"time"
+	"telegram-chatbot/internal/domain/tools"
 )
 
+// MaxToolIterations caps the number of tool_use rounds in a single answer
+const MaxToolIterations = 5
+
+// MaxInjectedMemories caps the number of remembered facts added to the system prompt
+const MaxInjectedMemories = 30
+
+const baseSystemPrompt = "Ты семейный помощник-бот. Отвечай дружелюбно и полезно на русском языке."
+
+const redactionPrompt = "\n\nЛичные данные в переписке заменены метками вида [PHONE_1], [EMAIL_1], [CARD_1], [ADDRESS_1]. " +
+	"Используй эти метки в ответе и в инструментах без изменений, пользователь увидит вместо них настоящие данные."
+
+// ClaudeAPIService prepares the conversation, runs the tool loop and restores
+// masked data. The request itself goes to the provider chosen for the chat.
 type ClaudeAPIService struct {
-	apiKey     string
-	httpClient *http.Client
+	router       *ModelRouter
+	tools        *tools.Registry
+	memoryRepo   repositories.MemoryRepository
+	settingsRepo repositories.ChatSettingsRepository
+	redactor     *redaction.Redactor
+	usage        *UsageStats
+	// Лимиты ответа и персоны чатов меняются без перезапуска
+	settings services.RuntimeSettings
 }
 
-func NewClaudeAPIService(apiKey string) services.ClaudeService {
+func NewClaudeAPIService(
+	router *ModelRouter,
+	toolRegistry *tools.Registry,
+	memoryRepo repositories.MemoryRepository,
+	settingsRepo repositories.ChatSettingsRepository,
+	redactor *redaction.Redactor,
+	usage *UsageStats,
+	settings services.RuntimeSettings,
+) services.ClaudeService {
 	return &ClaudeAPIService{
-		apiKey: apiKey,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		router:       router,
+		tools:        toolRegistry,
+		memoryRepo:   memoryRepo,
+		settingsRepo: settingsRepo,
+		redactor:     redactor,
+		usage:        usage,
+		settings:     settings,
 	}
 }
 
-type ClaudeMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+func (s *ClaudeAPIService) GenerateResponse(ctx context.Context, messages []entities.Message) (*services.Response, error) {
+	// Личные данные заменяются метками до отправки и возвращаются в ответ
+	vault := s.newVault(ctx)
+
+	completionMessages := make([]CompletionMessage, 0, len(messages))
+
+	for _, msg := range messages {
+		completionMessages = append(completionMessages, CompletionMessage{
+			Role: msg.Role,
+			Text: s.redact(msg.Content, vault),
+		})
+	}
+	markStablePrefix(completionMessages)
+
+	system := s.redact(s.systemPrompt(ctx), vault)
+	if vault != nil {
+		system += redactionPrompt
+	}
+
+	provider := s.route(ctx)
+	request := CompletionRequest{
+		MaxTokens: s.settings.MaxResponseTokens(),
+		Messages:  completionMessages,
+		System:    system,
+		Tools:     s.toolDefinitions(),
+		// Системный промпт с памятью меняется редко и кэшируется между ответами
+		CacheSystem: true,
+	}
+	if s.thinkingEnabled(ctx) {
+		// Размышления расходуют max_tokens, ответу остаётся прежний лимит
+		budget := s.settings.ThinkingBudget()
+		request.ThinkingBudget = budget
+		request.MaxTokens += budget
+	}
+
+	// Текст каждой итерации - отдельный абзац ответа
+	var text []string
+	var toolCalls []entities.ToolCall
+	var usage entities.TokenUsage
+	var thinking []string
+	// Индекс последних результатов инструментов с точкой кэширования
+	toolBreakpoint := -1
+
+	for iteration := 0; ; iteration++ {
+		// На последней итерации запрещаем инструменты, чтобы получить текстовый ответ
+		if len(request.Tools) > 0 && iteration == MaxToolIterations {
+			request.DisableTools = true
+		}
+
+		completion, err := provider.Complete(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+
+		usage.Add(completion.Usage)
+		if s.usage != nil {
+			s.usage.Record(completion.Model.String(), completion.Usage)
+		}
+
+		if part := strings.TrimRight(completion.Text, " \t\n"); strings.TrimSpace(part) != "" {
+			// Начало первой части не трогаем: продолжение обрезанного ответа
+			// может начинаться с пробела
+			if len(text) > 0 {
+				part = strings.TrimLeft(part, " \t\n")
+			}
+			text = append(text, part)
+		}
+		for _, block := range completion.Thinking {
+			if block.Text != "" {
+				thinking = append(thinking, block.Text)
+			}
+		}
+
+		if completion.StopReason != stopReasonToolUse {
+			if len(text) == 0 {
+				return nil, fmt.Errorf("empty response from %s", completion.Model)
+			}
+
+			return &services.Response{
+				Text:       s.restore(strings.Join(text, "\n\n"), vault),
+				StopReason: completion.StopReason,
+				ToolCalls:  toolCalls,
+				Model:      completion.Model.String(),
+				Usage:      usage,
+				Thinking:   s.restore(strings.Join(thinking, "\n\n"), vault),
+				// Кнопку продолжения показываем только моделям с предзаполнением
+				Continuable: s.router.CanContinue(completion.Model),
+			}, nil
+		}
+
+		results := make([]ToolResult, 0, len(completion.ToolUses))
+		for _, use := range completion.ToolUses {
+			call := s.executeTool(ctx, use, vault)
+			toolCalls = append(toolCalls, call)
+			results = append(results, ToolResult{
+				ToolUseID: use.ID,
+				Name:      use.Name,
+				Content:   s.redact(call.Output, vault),
+				IsError:   call.IsError,
+			})
+		}
+
+		// Следующая итерация повторяет весь запрос, поэтому точка кэширования
+		// переносится на новые результаты: точек в запросе не больше четырёх
+		if toolBreakpoint >= 0 {
+			request.Messages[toolBreakpoint].CacheBreakpoint = false
+		}
+		request.Messages = append(request.Messages,
+			CompletionMessage{Role: "assistant", Text: completion.Text, ToolUses: completion.ToolUses, Thinking: completion.Thinking},
+			CompletionMessage{Role: "user", ToolResults: results, CacheBreakpoint: true},
+		)
+		toolBreakpoint = len(request.Messages) - 1
+	}
 }
 
-type ClaudeRequest struct {
-	Model     string          `json:"model"`
-	MaxTokens int             `json:"max_tokens"`
-	Messages  []ClaudeMessage `json:"messages"`
-	System    string          `json:"system,omitempty"`
+func (s *ClaudeAPIService) GenerateOneShot(ctx context.Context, prompt string) (*services.Response, error) {
+	vault := s.newVault(ctx)
+
+	completion, err := s.route(ctx).Complete(ctx, CompletionRequest{
+		MaxTokens: s.settings.MaxResponseTokens(),
+		Messages:  []CompletionMessage{{Role: "user", Text: s.redact(prompt, vault)}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if s.usage != nil {
+		s.usage.Record(completion.Model.String(), completion.Usage)
+	}
+	if strings.TrimSpace(completion.Text) == "" {
+		return nil, fmt.Errorf("empty response from %s", completion.Model)
+	}
+
+	return &services.Response{
+		Text:       s.restore(completion.Text, vault),
+		StopReason: completion.StopReason,
+		Model:      completion.Model.String(),
+		Usage:      completion.Usage,
+	}, nil
 }
 
-type ClaudeResponse struct {
-	Content []struct {
-		Text string `json:"text"`
-	} `json:"content"`
+// markStablePrefix sets a cache breakpoint on the last user message. The
+// history up to it is resent unchanged with the next message, so the next
+// answer reads it from the cache instead of paying the full input price.
+func markStablePrefix(messages []CompletionMessage) {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			messages[i].CacheBreakpoint = true
+			return
+		}
+	}
 }
 
-func (s *ClaudeAPIService) GenerateResponse(messages []entities.Message) (string, error) {
-	claudeMessages := make([]ClaudeMessage, 0, len(messages))
+// route picks the model chain for the calling chat, taking the model chosen
+// with /model into account
+func (s *ClaudeAPIService) route(ctx context.Context) *FallbackProvider {
+	caller, ok := tools.CallerFromContext(ctx)
+	if !ok {
+		return s.router.Default()
+	}
+	return s.router.Route(caller.ChatID, s.modelAlias(caller.ChatID))
+}
 
-	for _, msg := range messages {
-		claudeMessages = append(claudeMessages, ClaudeMessage{
-			Role:    msg.Role,
-			Content: msg.Content,
-		})
+// modelAlias returns the model chosen in the chat with /model, if any
+func (s *ClaudeAPIService) modelAlias(chatID int64) string {
+	if s.settingsRepo == nil {
+		return ""
+	}
+	// Без настроек отвечает модель чата по умолчанию
+	settings, err := s.settingsRepo.GetSettings(chatID)
+	if err != nil {
+		return ""
 	}
+	return settings.Model
+}
 
-	request := ClaudeRequest{
-		Model:     "claude-3-5-sonnet-20241022", // Экономичная модель с доступом в интернет
-		MaxTokens: 1024,
-		Messages:  claudeMessages,
-		System:    "Ты семейный помощник-бот. Отвечай дружелюбно и полезно на русском языке.",
+func (s *ClaudeAPIService) CanThink(chatID int64) bool {
+	return s.router.CanThink(s.router.Model(chatID, s.modelAlias(chatID)))
+}
+
+// thinkingEnabled reports whether the model should think before answering:
+// when asked for this answer or when the chat turned thinking on, and only
+// if the chat's model can think
+func (s *ClaudeAPIService) thinkingEnabled(ctx context.Context) bool {
+	if s.settings.ThinkingBudget() == 0 {
+		return false
+	}
+	if caller, ok := tools.CallerFromContext(ctx); ok && !s.CanThink(caller.ChatID) {
+		return false
+	}
+	if services.ExtendedThinkingRequested(ctx) {
+		return true
 	}
 
-	jsonData, err := json.Marshal(request)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+	caller, ok := tools.CallerFromContext(ctx)
+	if !ok || s.settingsRepo == nil {
+		return false
 	}
+	settings, err := s.settingsRepo.GetSettings(caller.ChatID)
+	return err == nil && settings.Thinking
+}
 
-	req, err := http.NewRequest("POST", "https://api.anthropic.com/v1/messages", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+// systemPrompt adds the remembered facts of the calling chat and user to the
+// chat's persona or the base prompt
+func (s *ClaudeAPIService) systemPrompt(ctx context.Context) string {
+	caller, ok := tools.CallerFromContext(ctx)
+	if !ok {
+		return s.basePrompt(0)
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-api-key", s.apiKey)
-	req.Header.Set("anthropic-version", "2023-06-01")
+	prompt := s.basePrompt(caller.ChatID)
+	if s.memoryRepo == nil {
+		return prompt
+	}
 
-	resp, err := s.httpClient.Do(req)
+	memories, err := s.memoryRepo.ListMemories(caller.ChatID, caller.UserID)
 	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
+		// Память не критична для ответа, отвечаем без неё
+		return prompt
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+	facts := make([]string, 0, len(memories))
+	for _, memory := range memories {
+		if !memory.Pending {
+			facts = append(facts, "- "+memory.Fact)
+		}
+	}
+	if len(facts) == 0 {
+		return prompt
+	}
+	if len(facts) > MaxInjectedMemories {
+		facts = facts[len(facts)-MaxInjectedMemories:]
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+	return prompt + "\n\nЧто ты знаешь о пользователе и его семье:\n" + strings.Join(facts, "\n")
+}
+
+// basePrompt returns the persona configured for the chat or the built-in prompt
+func (s *ClaudeAPIService) basePrompt(chatID int64) string {
+	if prompt := s.settings.SystemPrompt(chatID); prompt != "" {
+		return prompt
+	}
+	return baseSystemPrompt
+}
+
+// newVault starts a placeholder map for one answer unless the calling chat has
+// redaction turned off. Without a caller the chat is unknown, so the data is
+// masked as well.
+func (s *ClaudeAPIService) newVault(ctx context.Context) *redaction.Vault {
+	if s.redactor == nil {
+		return nil
 	}
 
-	var claudeResp ClaudeResponse
-	if err := json.Unmarshal(body, &claudeResp); err != nil {
-		return "", fmt.Errorf("failed to unmarshal response: %w", err)
+	caller, ok := tools.CallerFromContext(ctx)
+	if ok && s.settingsRepo != nil {
+		// Если настройки недоступны, лучше замаскировать лишнее, чем отправить данные
+		settings, err := s.settingsRepo.GetSettings(caller.ChatID)
+		if err == nil && !settings.RedactPII {
+			return nil
+		}
 	}
 
-	if len(claudeResp.Content) == 0 {
-		return "", fmt.Errorf("empty response from Claude API")
+	return redaction.NewVault()
+}
+
+func (s *ClaudeAPIService) redact(text string, vault *redaction.Vault) string {
+	if vault == nil {
+		return text
+	}
+	return s.redactor.Redact(text, vault)
+}
+
+func (s *ClaudeAPIService) restore(text string, vault *redaction.Vault) string {
+	if vault == nil {
+		return text
+	}
+	return vault.Restore(text)
+}
+
+// toolDefinitions describes the registered tools for the model
+func (s *ClaudeAPIService) toolDefinitions() []ToolDefinition {
+	if s.tools == nil {
+		return nil
+	}
+
+	registered := s.tools.List()
+	definitions := make([]ToolDefinition, 0, len(registered))
+	for _, tool := range registered {
+		definitions = append(definitions, ToolDefinition{
+			Name:        tool.Name,
+			Description: tool.Description,
+			InputSchema: tool.InputSchema,
+		})
+	}
+	return definitions
+}
+
+// executeTool runs a tool requested by the model. Tool errors are reported
+// back to the model instead of failing the whole answer. Masked values are
+// restored in the input, so tools work with the real data.
+func (s *ClaudeAPIService) executeTool(ctx context.Context, use ToolUse, vault *redaction.Vault) entities.ToolCall {
+	input := use.Input
+	if vault != nil {
+		input = vault.RestoreJSON(input)
+	}
+
+	call := entities.ToolCall{
+		Name:  use.Name,
+		Input: string(input),
+	}
+
+	output, err := s.tools.Execute(ctx, use.Name, input)
+	if err != nil {
+		call.Output = err.Error()
+		call.IsError = true
+		return call
 	}
 
-	return claudeResp.Content[0].Text, nil
+	call.Output = output
+	return call
 }
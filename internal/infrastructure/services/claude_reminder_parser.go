@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"telegram-chatbot/internal/domain/services"
+	"time"
+)
+
+// ClaudeReminderParser asks Claude to turn a free-form request such as
+// "забрать детей в 5" into reminder text and an exact time
+type ClaudeReminderParser struct {
+	claudeService services.ClaudeService
+	location      *time.Location
+}
+
+func NewClaudeReminderParser(claudeService services.ClaudeService, location *time.Location) services.ReminderParser {
+	return &ClaudeReminderParser{
+		claudeService: claudeService,
+		location:      location,
+	}
+}
+
+const reminderPromptTemplate = `Сейчас %s (%s, часовой пояс %s).
+Разбери просьбу о напоминании и ответь только JSON-объектом без пояснений:
+{"text": "о чём напомнить, в повелительной форме", "due_at": "время в формате RFC3339 с учётом часового пояса"}
+Если время не указано или непонятно, ответь {"error": "причина"}.
+Время без даты относится к ближайшему будущему моменту. "В 5" днём означает 17:00.
+
+Просьба: %s`
+
+type parsedReminderJSON struct {
+	Text  string `json:"text"`
+	DueAt string `json:"due_at"`
+	Error string `json:"error"`
+}
+
+func (p *ClaudeReminderParser) ParseReminder(ctx context.Context, request string, now time.Time) (*services.ParsedReminder, error) {
+	now = now.In(p.location)
+	prompt := fmt.Sprintf(reminderPromptTemplate,
+		now.Format("2006-01-02 15:04"), now.Weekday(), p.location.String(), request)
+
+	// Без инструментов: разбор просьбы не должен менять списки или память
+	response, err := p.claudeService.GenerateOneShot(ctx, prompt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse reminder: %w", err)
+	}
+
+	// Claude иногда оборачивает JSON в текст или markdown, берём сам объект
+	text := response.Text
+	start, end := strings.Index(text, "{"), strings.LastIndex(text, "}")
+	if start < 0 || end < start {
+		return nil, services.ErrReminderNotUnderstood
+	}
+
+	var parsed parsedReminderJSON
+	if err := json.Unmarshal([]byte(text[start:end+1]), &parsed); err != nil {
+		return nil, services.ErrReminderNotUnderstood
+	}
+
+	if parsed.Error != "" || parsed.Text == "" || parsed.DueAt == "" {
+		return nil, services.ErrReminderNotUnderstood
+	}
+
+	dueAt, err := time.Parse(time.RFC3339, parsed.DueAt)
+	if err != nil {
+		return nil, services.ErrReminderNotUnderstood
+	}
+
+	return &services.ParsedReminder{
+		Text:  parsed.Text,
+		DueAt: dueAt,
+	}, nil
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"telegram-chatbot/internal/domain/entities"
+
+	"go.uber.org/zap"
+)
+
+// ErrBackendUnavailable is reported for a model whose circuit breaker is open
+var ErrBackendUnavailable = errors.New("backend is temporarily disabled after repeated failures")
+
+type fallbackBackend struct {
+	model    entities.ModelRef
+	provider LLMProvider
+	breaker  *CircuitBreaker
+}
+
+// FallbackProvider tries its models in order until one answers. It works per
+// request, so tools already run in this answer are not repeated on the next model.
+type FallbackProvider struct {
+	backends []fallbackBackend
+	logger   *zap.Logger
+}
+
+func (p *FallbackProvider) Complete(ctx context.Context, request CompletionRequest) (*CompletionResponse, error) {
+	var errs []error
+
+	for _, backend := range p.backends {
+		if !backend.breaker.Allow() {
+			errs = append(errs, fmt.Errorf("%s: %w", backend.model, ErrBackendUnavailable))
+			continue
+		}
+
+		request.Model = backend.model.Model
+		response, err := backend.provider.Complete(ctx, request)
+		if err == nil {
+			backend.breaker.Success()
+			response.Model = backend.model
+			return response, nil
+		}
+
+		// Отменённый запрос не говорит о здоровье бэкенда
+		if ctx.Err() != nil {
+			backend.breaker.Release()
+			return nil, fmt.Errorf("%s: %w", backend.model, err)
+		}
+
+		// Отклонённый запрос (4xx) не значит, что бэкенд неисправен,
+		// но другая модель может его принять
+		if isBackendFailure(err) {
+			backend.breaker.Failure()
+		} else {
+			backend.breaker.Release()
+		}
+		p.logger.Warn("Model request failed, trying the next one",
+			zap.String("model", backend.model.String()),
+			zap.Error(err))
+		errs = append(errs, fmt.Errorf("%s: %w", backend.model, err))
+	}
+
+	return nil, fmt.Errorf("all models failed: %w", errors.Join(errs...))
+}
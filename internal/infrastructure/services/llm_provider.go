@@ -0,0 +1,265 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"telegram-chatbot/internal/domain/entities"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Нормализованные причины остановки, общие для всех провайдеров
+const (
+	stopReasonEndTurn = "end_turn"
+	stopReasonToolUse = "tool_use"
+)
+
+// continuationPrompt asks models without assistant prefill to continue a cut-off reply
+const continuationPrompt = "Продолжи свой предыдущий ответ ровно с того места, где он оборвался, без повторов и вступлений."
+
+// minOverlap is the shortest restated tail cut from a continuation, so a
+// continuation that merely starts like the reply ends is kept intact
+const minOverlap = 12
+
+// trimOverlap removes the part of a continuation that restates the end of the
+// cut-off reply. Models asked with continuationPrompt often repeat the whole
+// reply or its last sentence before going on.
+func trimOverlap(previous, continuation string) string {
+	previous = strings.TrimRight(previous, " \t\n")
+	text := strings.TrimLeft(continuation, " \t\n")
+	if previous == "" {
+		return continuation
+	}
+	if strings.HasPrefix(text, previous) {
+		return text[len(previous):]
+	}
+	for size := min(len(previous), len(text)); size >= minOverlap; size-- {
+		if strings.HasSuffix(previous, text[:size]) {
+			return text[size:]
+		}
+	}
+	return continuation
+}
+
+// StatusError is a non-OK HTTP response of a model API
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("API request failed with status %d: %s", e.StatusCode, e.Body)
+}
+
+// Temporary reports whether the status points to an overloaded or failing
+// backend rather than a request the backend rejected
+func (e *StatusError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError
+}
+
+// isBackendFailure reports whether the error should count toward the circuit
+// breaker: 5xx, 429 and transport errors. A rejected request (4xx) would fail
+// the same way on a healthy backend.
+func isBackendFailure(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Temporary()
+	}
+	return true
+}
+
+// prefillProvider is implemented by providers that continue a prefilled
+// assistant turn exactly where it stopped
+type prefillProvider interface {
+	SupportsPrefill() bool
+}
+
+// thinkingProvider is implemented by providers with extended thinking
+type thinkingProvider interface {
+	SupportsThinking(model string) bool
+}
+
+// LLMProvider sends a single completion request to a model backend. The tool
+// loop, memory and redaction are handled by ClaudeAPIService.
+type LLMProvider interface {
+	Complete(ctx context.Context, request CompletionRequest) (*CompletionResponse, error)
+}
+
+// CompletionRequest is a provider-neutral request for the next assistant turn
+type CompletionRequest struct {
+	Model     string
+	System    string
+	Messages  []CompletionMessage
+	Tools     []ToolDefinition
+	MaxTokens int
+	// DisableTools forbids tool calls so the model has to answer with text
+	DisableTools bool
+	// CacheSystem marks the tools and system prompt for prompt caching
+	CacheSystem bool
+	// ThinkingBudget enables extended thinking with this many tokens out of
+	// MaxTokens. Providers without it ignore the budget.
+	ThinkingBudget int
+}
+
+// CompletionMessage is a conversation turn. Assistant turns may request
+// tools, and the following user turn carries their results.
+type CompletionMessage struct {
+	Role        string // "user" or "assistant"
+	Text        string
+	ToolUses    []ToolUse
+	ToolResults []ToolResult
+	// Thinking of an assistant turn is sent back unchanged while tools run
+	Thinking []ThinkingBlock
+	// CacheBreakpoint ends a prefix that stays the same in the next requests.
+	// Providers with explicit prompt caching cache everything up to this turn.
+	CacheBreakpoint bool
+}
+
+type ToolUse struct {
+	ID    string
+	Name  string
+	Input json.RawMessage
+}
+
+type ToolResult struct {
+	ToolUseID string
+	Name      string
+	Content   string
+	IsError   bool
+}
+
+// ThinkingBlock is the model's reasoning. The signature lets the provider
+// verify it when it is sent back; redacted blocks carry only encrypted data.
+type ThinkingBlock struct {
+	Text      string
+	Signature string
+	Redacted  string
+}
+
+type ToolDefinition struct {
+	Name        string
+	Description string
+	InputSchema json.RawMessage
+}
+
+// CompletionResponse is the generated turn with a normalized stop reason
+type CompletionResponse struct {
+	Text       string
+	StopReason string
+	ToolUses   []ToolUse
+	Thinking   []ThinkingBlock
+	Usage      entities.TokenUsage
+	// Model is the model that answered, set by FallbackProvider
+	Model entities.ModelRef
+}
+
+// ModelRouting configures which models answer and how failures are handled
+type ModelRouting struct {
+	Default entities.ModelRef
+	PerChat map[int64]entities.ModelRef
+	// Fallbacks are tried in order when the chat's model fails
+	Fallbacks       []entities.ModelRef
+	BreakerFailures int
+	BreakerCooldown time.Duration
+	// Catalog lists the models chats may choose with /model
+	Catalog entities.ModelCatalog
+}
+
+// ModelRouter builds the fallback chain for a chat: the chat's own model or
+// the default one, followed by the fallbacks. Circuit breakers are kept per
+// model and shared by all chains.
+type ModelRouter struct {
+	providers map[string]LLMProvider
+	routing   ModelRouting
+	breakers  map[entities.ModelRef]*CircuitBreaker
+	logger    *zap.Logger
+}
+
+// NewModelRouter checks that every configured model has a provider
+func NewModelRouter(providers map[string]LLMProvider, routing ModelRouting, logger *zap.Logger) (*ModelRouter, error) {
+	models := append([]entities.ModelRef{routing.Default}, routing.Fallbacks...)
+	for _, model := range routing.PerChat {
+		models = append(models, model)
+	}
+	for _, option := range routing.Catalog {
+		models = append(models, option.Model)
+	}
+
+	breakers := make(map[entities.ModelRef]*CircuitBreaker, len(models))
+	for _, model := range models {
+		if _, ok := providers[model.Provider]; !ok {
+			return nil, fmt.Errorf("no provider configured for model %s", model)
+		}
+		breakers[model] = NewCircuitBreaker(routing.BreakerFailures, routing.BreakerCooldown)
+	}
+
+	return &ModelRouter{
+		providers: providers,
+		routing:   routing,
+		breakers:  breakers,
+		logger:    logger,
+	}, nil
+}
+
+// Route returns the fallback chain for the chat, starting with its model
+func (r *ModelRouter) Route(chatID int64, alias string) *FallbackProvider {
+	return r.chain(r.Model(chatID, alias))
+}
+
+// Model returns the model answering in the chat. A model chosen with /model
+// takes precedence over the one configured for the chat; an alias no longer
+// in the catalog is ignored.
+func (r *ModelRouter) Model(chatID int64, alias string) entities.ModelRef {
+	if option, ok := r.routing.Catalog.Find(alias); ok {
+		return option.Model
+	}
+
+	model, ok := r.routing.PerChat[chatID]
+	if !ok {
+		model = r.routing.Default
+	}
+	return model
+}
+
+// CanThink reports whether the model supports extended thinking
+func (r *ModelRouter) CanThink(model entities.ModelRef) bool {
+	provider, ok := r.providers[model.Provider].(thinkingProvider)
+	return ok && provider.SupportsThinking(model.Model)
+}
+
+// CanContinue reports whether the model's provider continues a cut-off reply
+// from where it stopped. Others restate the reply, so it is not offered.
+func (r *ModelRouter) CanContinue(model entities.ModelRef) bool {
+	provider, ok := r.providers[model.Provider].(prefillProvider)
+	return ok && provider.SupportsPrefill()
+}
+
+// Default returns the chain used for requests without a chat
+func (r *ModelRouter) Default() *FallbackProvider {
+	return r.chain(r.routing.Default)
+}
+
+func (r *ModelRouter) chain(primary entities.ModelRef) *FallbackProvider {
+	chain := &FallbackProvider{logger: r.logger}
+	seen := make(map[entities.ModelRef]bool)
+
+	for _, model := range append([]entities.ModelRef{primary}, r.routing.Fallbacks...) {
+		if seen[model] {
+			continue
+		}
+		seen[model] = true
+
+		chain.backends = append(chain.backends, fallbackBackend{
+			model:    model,
+			provider: r.providers[model.Provider],
+			breaker:  r.breakers[model],
+		})
+	}
+
+	return chain
+}
@@ -0,0 +1,56 @@
+package services
+
+import "testing"
+
+func TestTrimOverlap(t *testing.T) {
+	const previous = "Первый шаг: взять муку. Второй шаг: добав"
+
+	tests := []struct {
+		name         string
+		continuation string
+		want         string
+	}{
+		{name: "plain continuation", continuation: "ить яйца.", want: "ить яйца."},
+		{name: "whole reply restated", continuation: previous + "ить яйца.", want: "ить яйца."},
+		{name: "last sentence restated", continuation: "Второй шаг: добавить яйца.", want: "ить яйца."},
+		{name: "short overlap is kept", continuation: "добавить яйца.", want: "добавить яйца."},
+		{name: "leading space kept", continuation: " и ещё", want: " и ещё"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := trimOverlap(previous, tt.continuation); got != tt.want {
+				t.Errorf("trimOverlap() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if got := trimOverlap("", "ответ"); got != "ответ" {
+		t.Errorf("trimOverlap() without a previous reply = %q", got)
+	}
+}
+
+func TestAnthropicSupportsThinking(t *testing.T) {
+	provider := NewAnthropicProvider("")
+
+	tests := []struct {
+		model string
+		want  bool
+	}{
+		{model: "claude-3-5-sonnet-20241022", want: false},
+		{model: "claude-3-5-haiku-latest", want: false},
+		{model: "claude-3-opus-20240229", want: false},
+		{model: "claude-3-haiku-20240307", want: false},
+		{model: "claude-3-7-sonnet-20250219", want: true},
+		{model: "claude-sonnet-4-20250514", want: true},
+		{model: "claude-opus-4-1", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			if got := provider.SupportsThinking(tt.model); got != tt.want {
+				t.Errorf("SupportsThinking(%q) = %v, want %v", tt.model, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"telegram-chatbot/internal/domain/entities"
+	"telegram-chatbot/internal/domain/services"
+	"time"
+)
+
+// OllamaProvider talks to a local Ollama server through its native chat API
+type OllamaProvider struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+func NewOllamaProvider(baseURL string) *OllamaProvider {
+	return &OllamaProvider{
+		baseURL: baseURL,
+		httpClient: &http.Client{
+			// Локальная модель на слабом железе отвечает заметно дольше облачной
+			Timeout: 3 * time.Minute,
+		},
+	}
+}
+
+type ollamaRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Tools    []openAITool    `json:"tools,omitempty"`
+	Stream   bool            `json:"stream"`
+	Options  *ollamaOptions  `json:"options,omitempty"`
+}
+
+type ollamaOptions struct {
+	NumPredict int `json:"num_predict,omitempty"`
+}
+
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"`
+}
+
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"` // JSON-объект
+	} `json:"function"`
+}
+
+type ollamaResponse struct {
+	Message         ollamaMessage `json:"message"`
+	DoneReason      string        `json:"done_reason"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+}
+
+func (p *OllamaProvider) Complete(ctx context.Context, request CompletionRequest) (*CompletionResponse, error) {
+	ollamaReq := ollamaRequest{
+		Model:    request.Model,
+		Messages: make([]ollamaMessage, 0, len(request.Messages)+2),
+		Stream:   false,
+	}
+	if request.MaxTokens > 0 {
+		ollamaReq.Options = &ollamaOptions{NumPredict: request.MaxTokens}
+	}
+	// Выбора инструментов в Ollama нет, на последней итерации просто не передаём их
+	if !request.DisableTools {
+		ollamaReq.Tools = openAITools(request.Tools)
+	}
+
+	if request.System != "" {
+		ollamaReq.Messages = append(ollamaReq.Messages, ollamaMessage{Role: "system", Content: request.System})
+	}
+
+	for _, msg := range request.Messages {
+		ollamaReq.Messages = append(ollamaReq.Messages, ollamaMessages(msg)...)
+	}
+
+	var previous *CompletionMessage
+	if n := len(request.Messages); n > 0 && request.Messages[n-1].Role == "assistant" {
+		previous = &request.Messages[n-1]
+		ollamaReq.Messages = append(ollamaReq.Messages, ollamaMessage{Role: "user", Content: continuationPrompt})
+	}
+
+	var ollamaResp ollamaResponse
+	if err := p.post(ctx, ollamaReq, &ollamaResp); err != nil {
+		return nil, err
+	}
+
+	response := &CompletionResponse{
+		Text:       ollamaResp.Message.Content,
+		StopReason: stopReasonEndTurn,
+		Usage: entities.TokenUsage{
+			InputTokens:  ollamaResp.PromptEvalCount,
+			OutputTokens: ollamaResp.EvalCount,
+		},
+	}
+	// Ollama не присваивает вызовам ID, результаты связываются по порядку
+	for i, call := range ollamaResp.Message.ToolCalls {
+		input := call.Function.Arguments
+		if len(input) == 0 || !json.Valid(input) {
+			input = json.RawMessage("{}")
+		}
+		response.ToolUses = append(response.ToolUses, ToolUse{
+			ID:    fmt.Sprintf("call_%d", i),
+			Name:  call.Function.Name,
+			Input: input,
+		})
+	}
+
+	// Продолжение часто начинается с повтора оборванного ответа
+	if previous != nil {
+		response.Text = trimOverlap(previous.Text, response.Text)
+	}
+
+	switch {
+	case len(response.ToolUses) > 0:
+		response.StopReason = stopReasonToolUse
+	case ollamaResp.DoneReason == "length":
+		response.StopReason = services.StopReasonMaxTokens
+	}
+
+	return response, nil
+}
+
+func ollamaMessages(msg CompletionMessage) []ollamaMessage {
+	if len(msg.ToolResults) > 0 {
+		messages := make([]ollamaMessage, 0, len(msg.ToolResults))
+		for _, result := range msg.ToolResults {
+			messages = append(messages, ollamaMessage{
+				Role:     "tool",
+				Content:  toolResultContent(result),
+				ToolName: result.Name,
+			})
+		}
+		return messages
+	}
+
+	converted := ollamaMessage{Role: msg.Role, Content: msg.Text}
+	for _, use := range msg.ToolUses {
+		var call ollamaToolCall
+		call.Function.Name = use.Name
+		call.Function.Arguments = use.Input
+		converted.ToolCalls = append(converted.ToolCalls, call)
+	}
+	return []ollamaMessage{converted}
+}
+
+func (p *OllamaProvider) post(ctx context.Context, request ollamaRequest, response *ollamaResponse) error {
+	jsonData, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/api/chat", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	if err := json.Unmarshal(body, response); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,255 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"telegram-chatbot/internal/domain/entities"
+	"telegram-chatbot/internal/domain/services"
+	"time"
+)
+
+// OpenAIProvider talks to OpenAI-compatible chat completion endpoints:
+// OpenAI itself, OpenRouter, vLLM, LM Studio and the like
+type OpenAIProvider struct {
+	apiKey     string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewOpenAIProvider creates the client. The key may be empty for local servers.
+func NewOpenAIProvider(apiKey, baseURL string) *OpenAIProvider {
+	return &OpenAIProvider{
+		apiKey:  apiKey,
+		baseURL: baseURL,
+		httpClient: &http.Client{
+			Timeout: 60 * time.Second,
+		},
+	}
+}
+
+type openAIRequest struct {
+	Model      string          `json:"model"`
+	Messages   []openAIMessage `json:"messages"`
+	Tools      []openAITool    `json:"tools,omitempty"`
+	ToolChoice string          `json:"tool_choice,omitempty"`
+	MaxTokens  int             `json:"max_tokens,omitempty"`
+}
+
+type openAIMessage struct {
+	Role       string           `json:"role"`
+	Content    string           `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type openAIToolCall struct {
+	ID       string             `json:"id"`
+	Type     string             `json:"type"`
+	Function openAIFunctionCall `json:"function"`
+}
+
+type openAIFunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"` // JSON-объект строкой
+}
+
+// openAITool is the function definition format, Ollama uses it as well
+type openAITool struct {
+	Type     string         `json:"type"`
+	Function openAIFunction `json:"function"`
+}
+
+type openAIFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Parameters  json.RawMessage `json:"parameters"`
+}
+
+type openAIResponse struct {
+	Choices []struct {
+		Message      openAIMessage `json:"message"`
+		FinishReason string        `json:"finish_reason"`
+	} `json:"choices"`
+	Usage openAIUsage `json:"usage"`
+}
+
+// openAIUsage reports billed tokens. Prompts are cached automatically, the
+// cached part is included in prompt_tokens.
+type openAIUsage struct {
+	PromptTokens        int `json:"prompt_tokens"`
+	CompletionTokens    int `json:"completion_tokens"`
+	PromptTokensDetails struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details"`
+}
+
+func (p *OpenAIProvider) Complete(ctx context.Context, request CompletionRequest) (*CompletionResponse, error) {
+	openAIReq := openAIRequest{
+		Model:     request.Model,
+		Messages:  make([]openAIMessage, 0, len(request.Messages)+2),
+		Tools:     openAITools(request.Tools),
+		MaxTokens: request.MaxTokens,
+	}
+	if len(openAIReq.Tools) > 0 && request.DisableTools {
+		openAIReq.ToolChoice = "none"
+	}
+
+	if request.System != "" {
+		openAIReq.Messages = append(openAIReq.Messages, openAIMessage{Role: "system", Content: request.System})
+	}
+
+	for _, msg := range request.Messages {
+		openAIReq.Messages = append(openAIReq.Messages, openAIMessages(msg)...)
+	}
+
+	// Предзаполнение ответа не поддерживается, продолжение просим отдельной репликой
+	var previous *CompletionMessage
+	if n := len(request.Messages); n > 0 && request.Messages[n-1].Role == "assistant" {
+		previous = &request.Messages[n-1]
+		openAIReq.Messages = append(openAIReq.Messages, openAIMessage{Role: "user", Content: continuationPrompt})
+	}
+
+	var openAIResp openAIResponse
+	if err := p.post(ctx, openAIReq, &openAIResp); err != nil {
+		return nil, err
+	}
+
+	if len(openAIResp.Choices) == 0 {
+		return nil, fmt.Errorf("empty response from OpenAI-compatible API")
+	}
+	choice := openAIResp.Choices[0]
+
+	cached := openAIResp.Usage.PromptTokensDetails.CachedTokens
+	response := &CompletionResponse{
+		Text:       choice.Message.Content,
+		StopReason: stopReasonEndTurn,
+		Usage: entities.TokenUsage{
+			InputTokens:     openAIResp.Usage.PromptTokens - cached,
+			OutputTokens:    openAIResp.Usage.CompletionTokens,
+			CacheReadTokens: cached,
+		},
+	}
+	for _, call := range choice.Message.ToolCalls {
+		response.ToolUses = append(response.ToolUses, ToolUse{
+			ID:    call.ID,
+			Name:  call.Function.Name,
+			Input: toolArguments(call.Function.Arguments),
+		})
+	}
+
+	// Продолжение часто начинается с повтора оборванного ответа
+	if previous != nil {
+		response.Text = trimOverlap(previous.Text, response.Text)
+	}
+
+	switch {
+	case len(response.ToolUses) > 0:
+		response.StopReason = stopReasonToolUse
+	case choice.FinishReason == "length":
+		response.StopReason = services.StopReasonMaxTokens
+	}
+
+	return response, nil
+}
+
+// openAIMessages converts a turn. Every tool result becomes its own "tool" message.
+func openAIMessages(msg CompletionMessage) []openAIMessage {
+	if len(msg.ToolResults) > 0 {
+		messages := make([]openAIMessage, 0, len(msg.ToolResults))
+		for _, result := range msg.ToolResults {
+			messages = append(messages, openAIMessage{
+				Role:       "tool",
+				Content:    toolResultContent(result),
+				ToolCallID: result.ToolUseID,
+			})
+		}
+		return messages
+	}
+
+	converted := openAIMessage{Role: msg.Role, Content: msg.Text}
+	for _, use := range msg.ToolUses {
+		converted.ToolCalls = append(converted.ToolCalls, openAIToolCall{
+			ID:   use.ID,
+			Type: "function",
+			Function: openAIFunctionCall{
+				Name:      use.Name,
+				Arguments: string(use.Input),
+			},
+		})
+	}
+	return []openAIMessage{converted}
+}
+
+func openAITools(tools []ToolDefinition) []openAITool {
+	converted := make([]openAITool, 0, len(tools))
+	for _, tool := range tools {
+		converted = append(converted, openAITool{
+			Type: "function",
+			Function: openAIFunction{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.InputSchema,
+			},
+		})
+	}
+	return converted
+}
+
+// toolResultContent marks failed tool calls, the format has no error flag
+func toolResultContent(result ToolResult) string {
+	if result.IsError {
+		return "Ошибка: " + result.Content
+	}
+	return result.Content
+}
+
+// toolArguments turns the arguments string into a JSON object. Broken JSON
+// from the model becomes an empty object, the tool then reports what is missing.
+func toolArguments(arguments string) json.RawMessage {
+	if !json.Valid([]byte(arguments)) {
+		return json.RawMessage("{}")
+	}
+	return json.RawMessage(arguments)
+}
+
+func (p *OpenAIProvider) post(ctx context.Context, request openAIRequest, response *openAIResponse) error {
+	jsonData, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/chat/completions", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	if err := json.Unmarshal(body, response); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return nil
+}
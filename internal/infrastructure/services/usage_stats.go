@@ -0,0 +1,81 @@
+package services
+
+import (
+	"sync"
+	"telegram-chatbot/internal/domain/entities"
+	"time"
+)
+
+// UsageStats accumulates token usage per model since the bot started
+type UsageStats struct {
+	mutex     sync.Mutex
+	startedAt time.Time
+	requests  map[string]int
+	models    map[string]*entities.TokenUsage
+}
+
+func NewUsageStats() *UsageStats {
+	return &UsageStats{
+		startedAt: time.Now(),
+		requests:  make(map[string]int),
+		models:    make(map[string]*entities.TokenUsage),
+	}
+}
+
+// ModelUsage is the usage of one model
+type ModelUsage struct {
+	Requests int `json:"requests"`
+	entities.TokenUsage
+	// CacheHitRatio is the share of prompt tokens read from the cache
+	CacheHitRatio float64 `json:"cache_hit_ratio"`
+}
+
+// UsageSnapshot is a copy of the statistics at a point in time
+type UsageSnapshot struct {
+	Since  time.Time             `json:"since"`
+	Total  ModelUsage            `json:"total"`
+	Models map[string]ModelUsage `json:"models"`
+}
+
+// Record adds the usage of a single model request
+func (s *UsageStats) Record(model string, usage entities.TokenUsage) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	total, ok := s.models[model]
+	if !ok {
+		total = &entities.TokenUsage{}
+		s.models[model] = total
+	}
+	total.Add(usage)
+	s.requests[model]++
+}
+
+func (s *UsageStats) Snapshot() UsageSnapshot {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	snapshot := UsageSnapshot{
+		Since:  s.startedAt,
+		Models: make(map[string]ModelUsage, len(s.models)),
+	}
+
+	var total entities.TokenUsage
+	var totalRequests int
+	for model, usage := range s.models {
+		snapshot.Models[model] = newModelUsage(s.requests[model], *usage)
+		total.Add(*usage)
+		totalRequests += s.requests[model]
+	}
+	snapshot.Total = newModelUsage(totalRequests, total)
+
+	return snapshot
+}
+
+func newModelUsage(requests int, usage entities.TokenUsage) ModelUsage {
+	modelUsage := ModelUsage{Requests: requests, TokenUsage: usage}
+	if prompt := usage.PromptTokens(); prompt > 0 {
+		modelUsage.CacheHitRatio = float64(usage.CacheReadTokens) / float64(prompt)
+	}
+	return modelUsage
+}
@@ -2,28 +2,67 @@ package telegram
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strings"
+	"sync"
 	"telegram-chatbot/internal/application/handlers"
 	"telegram-chatbot/internal/config"
 	"telegram-chatbot/internal/domain/commands"
+	"telegram-chatbot/internal/domain/repositories"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"go.uber.org/zap"
 )
 
+const (
+	callbackEndChat    = "end_chat"
+	callbackRegenerate = "regenerate"
+	callbackContinue   = "continue"
+
+	callbackCancelReminderPrefix = "cancel_reminder:"
+)
+
+// sessionChangedNotice answers a button or an edit whose result could not be
+// stored because another request changed the session meanwhile
+const sessionChangedNotice = "ℹ️ Пока готовился ответ, разговор изменился. Попробуй ещё раз."
+
+const (
+	minInlineQueryLength    = 3
+	inlineDescriptionLength = 100
+	// maxMessageLength is the Telegram limit for the text of one message
+	maxMessageLength = 4096
+	// inlineQueryDebounce is how long the user has to stop typing before the
+	// model is asked
+	inlineQueryDebounce = 700 * time.Millisecond
+)
+
 type Bot struct {
-	api            *tgbotapi.BotAPI
-	config         *config.Config
-	commandHandler *handlers.CommandHandler
-	logger         *zap.Logger
+	api             *tgbotapi.BotAPI
+	config          *config.Live
+	commandHandler  *handlers.CommandHandler
+	reminderHandler *handlers.ReminderHandler
+	listHandler     *handlers.ListHandler
+	memoryHandler   *handlers.MemoryHandler
+	modelHandler    *handlers.ModelHandler
+	logger          *zap.Logger
+
+	// Последний inline-запрос каждого пользователя, прежние уже не нужны
+	latestInlineQueries map[int64]string
+	inlineMutex         sync.Mutex
 }
 
 func NewBot(
-	config *config.Config,
+	config *config.Live,
 	commandHandler *handlers.CommandHandler,
+	reminderHandler *handlers.ReminderHandler,
+	listHandler *handlers.ListHandler,
+	memoryHandler *handlers.MemoryHandler,
+	modelHandler *handlers.ModelHandler,
 	logger *zap.Logger,
 ) (*Bot, error) {
-	bot, err := tgbotapi.NewBotAPI(config.TelegramBotToken)
+	bot, err := tgbotapi.NewBotAPI(config.Get().TelegramBotToken)
 	if err != nil {
 		return nil, err
 	}
@@ -35,10 +74,16 @@ func NewBot(
 	}
 
 	return &Bot{
-		api:            bot,
-		config:         config,
-		commandHandler: commandHandler,
-		logger:         logger,
+		api:             bot,
+		config:          config,
+		commandHandler:  commandHandler,
+		reminderHandler: reminderHandler,
+		listHandler:     listHandler,
+		memoryHandler:   memoryHandler,
+		modelHandler:    modelHandler,
+		logger:          logger,
+
+		latestInlineQueries: make(map[int64]string),
 	}, nil
 }
 
@@ -64,6 +109,58 @@ func setBotCommands(bot *tgbotapi.BotAPI) error {
 			Command:     "whoami",
 			Description: "Информация о пользователе и группе",
 		},
+		{
+			Command:     "undo",
+			Description: "Удалить последний вопрос и ответ",
+		},
+		{
+			Command:     "retry",
+			Description: "Повторить последний вопрос",
+		},
+		{
+			Command:     "remind",
+			Description: "Создать напоминание, например: забрать детей в 5",
+		},
+		{
+			Command:     "reminders",
+			Description: "Список напоминаний и их отмена",
+		},
+		{
+			Command:     "list",
+			Description: "Семейные списки: покупки, дела",
+		},
+		{
+			Command:     "remember",
+			Description: "Запомнить факт надолго",
+		},
+		{
+			Command:     "memories",
+			Description: "Что бот помнит о тебе и семье",
+		},
+		{
+			Command:     "forget",
+			Description: "Забыть факт по номеру",
+		},
+		{
+			Command:     "search",
+			Description: "Поиск по прошлым разговорам",
+		},
+		{
+			Command:     "privacy",
+			Description: "Маскировать личные данные перед отправкой в Claude (on/off)",
+		},
+		{
+			Command:     "think",
+			Description: "Размышлять перед ответом (on/off) или задать один вопрос",
+		},
+		{
+			Command:     "model",
+			Description: "Выбрать модель для этого чата",
+		},
+		{
+			Command:     "group_mode",
+			Description: "Общая сессия для всей группы (on/off)",
+		},
 	}
 
 	config := tgbotapi.NewSetMyCommands(commands...)
@@ -92,6 +189,16 @@ func (b *Bot) Start(ctx context.Context) error {
 
 			if update.Message != nil {
 				go b.handleUpdate(ctx, update)
+				continue
+			}
+
+			if update.EditedMessage != nil {
+				go b.handleEditedMessage(ctx, update.EditedMessage)
+				continue
+			}
+
+			if update.InlineQuery != nil {
+				go b.handleInlineQuery(ctx, update.InlineQuery)
 			}
 		}
 	}
@@ -100,15 +207,7 @@ func (b *Bot) Start(ctx context.Context) error {
 func (b *Bot) handleUpdate(ctx context.Context, update tgbotapi.Update) {
 	message := update.Message
 
-	authorized := false
-	for _, allowedChatID := range b.config.AllowedChatIDs {
-		if message.Chat.ID == allowedChatID {
-			authorized = true
-			break
-		}
-	}
-
-	if !authorized {
+	if !b.isAuthorized(message.Chat.ID) {
 		b.logger.Warn("Message from unauthorized chat", zap.Int64("chatID", message.Chat.ID))
 		return
 	}
@@ -118,14 +217,18 @@ func (b *Bot) handleUpdate(ctx context.Context, update tgbotapi.Update) {
 
 	var response string
 	var err error
+	// Только новые ответы Claude получают кнопки перегенерации и продолжения
+	var answered bool
+	var revision int64
 
 	// Обработка команд
 	if message.IsCommand() {
 		switch message.Command() {
 		case "start":
 			response, err = b.commandHandler.HandleStart(ctx, commands.StartCommand{
-				ChatID: chatID,
-				UserID: userID,
+				ChatID:  chatID,
+				UserID:  userID,
+				IsAdmin: b.isChatAdmin(message.Chat, userID),
 			})
 		case "help":
 			response, err = b.commandHandler.HandleHelp(ctx, commands.HelpCommand{
@@ -134,13 +237,15 @@ func (b *Bot) handleUpdate(ctx context.Context, update tgbotapi.Update) {
 			})
 		case "begin_chat":
 			response, err = b.commandHandler.HandleBeginChat(ctx, commands.StartBeginCommand{
-				ChatID: chatID,
-				UserID: userID,
+				ChatID:  chatID,
+				UserID:  userID,
+				IsAdmin: b.isChatAdmin(message.Chat, userID),
 			})
 		case "end_chat":
 			response, err = b.commandHandler.HandleEndChat(ctx, commands.EndChatCommand{
-				ChatID: chatID,
-				UserID: userID,
+				ChatID:  chatID,
+				UserID:  userID,
+				IsAdmin: b.isChatAdmin(message.Chat, userID),
 			})
 		case "whoami":
 			response, err = b.commandHandler.HandleWhoAmI(ctx, commands.WhoAmICommand{
@@ -150,6 +255,97 @@ func (b *Bot) handleUpdate(ctx context.Context, update tgbotapi.Update) {
 				FirstName: message.From.FirstName,
 				LastName:  message.From.LastName,
 			})
+		case "undo":
+			response, err = b.commandHandler.HandleUndo(ctx, commands.UndoCommand{
+				ChatID: chatID,
+				UserID: userID,
+			})
+		case "retry":
+			b.sendTypingAction(chatID)
+			var reply handlers.Reply
+			reply, err = b.commandHandler.HandleRetry(ctx, commands.RetryCommand{
+				ChatID: chatID,
+				UserID: userID,
+			})
+			response, answered, revision = reply.Text, reply.Answered, reply.Revision
+		case "remind":
+			response, err = b.reminderHandler.HandleRemind(ctx, commands.RemindCommand{
+				ChatID:      chatID,
+				UserID:      userID,
+				DisplayName: displayName(message.From),
+				Request:     message.CommandArguments(),
+			})
+		case "reminders":
+			b.sendReminderList(ctx, message)
+			return
+		case "list":
+			b.sendList(ctx, message)
+			return
+		case "remember":
+			response, err = b.memoryHandler.HandleRemember(ctx, commands.RememberCommand{
+				ChatID: chatID,
+				UserID: userID,
+				Fact:   message.CommandArguments(),
+			})
+		case "memories":
+			response, err = b.memoryHandler.HandleListMemories(ctx, commands.ListMemoriesCommand{
+				ChatID: chatID,
+				UserID: userID,
+			})
+		case "forget":
+			response, err = b.memoryHandler.HandleForget(ctx, commands.ForgetCommand{
+				ChatID: chatID,
+				UserID: userID,
+				Args:   message.CommandArguments(),
+			})
+		case "search":
+			b.sendSearchResults(ctx, message)
+			return
+		case "privacy":
+			response, err = b.commandHandler.HandlePrivacy(ctx, commands.PrivacyCommand{
+				ChatID:  chatID,
+				UserID:  userID,
+				IsAdmin: b.isChatAdmin(message.Chat, userID),
+				IsGroup: b.isFromGroup(message),
+				Mode:    strings.ToLower(strings.TrimSpace(message.CommandArguments())),
+			})
+		case "think":
+			args := strings.TrimSpace(message.CommandArguments())
+			switch mode := strings.ToLower(args); mode {
+			case "", "on", "off":
+				response, err = b.commandHandler.HandleThink(ctx, commands.ThinkCommand{
+					ChatID:  chatID,
+					UserID:  userID,
+					IsAdmin: b.isChatAdmin(message.Chat, userID),
+					IsGroup: b.isFromGroup(message),
+					Mode:    mode,
+				})
+			default:
+				// Вопрос после команды получает ответ с размышлениями
+				b.sendTypingAction(chatID)
+				var reply handlers.Reply
+				reply, err = b.commandHandler.HandleMessage(ctx, commands.ProcessMessageCommand{
+					ChatID:      chatID,
+					UserID:      userID,
+					MessageID:   message.MessageID,
+					Message:     args,
+					Username:    message.From.UserName,
+					DisplayName: displayName(message.From),
+					Think:       true,
+				})
+				response, answered, revision = reply.Text, reply.Answered, reply.Revision
+			}
+		case "model":
+			b.sendModelMenu(ctx, message)
+			return
+		case "group_mode":
+			response, err = b.commandHandler.HandleGroupMode(ctx, commands.GroupModeCommand{
+				ChatID:  chatID,
+				UserID:  userID,
+				IsAdmin: b.isChatAdmin(message.Chat, userID),
+				IsGroup: b.isFromGroup(message),
+				Mode:    strings.ToLower(strings.TrimSpace(message.CommandArguments())),
+			})
 		default:
 			return // Неизвестная команда - игнорируем
 		}
@@ -160,12 +356,16 @@ func (b *Bot) handleUpdate(ctx context.Context, update tgbotapi.Update) {
 
 		b.sendTypingAction(chatID)
 
-		response, err = b.commandHandler.HandleMessage(ctx, commands.ProcessMessageCommand{
-			ChatID:   chatID,
-			UserID:   userID,
-			Message:  b.cleanMessage(message.Text),
-			Username: message.From.UserName,
+		var reply handlers.Reply
+		reply, err = b.commandHandler.HandleMessage(ctx, commands.ProcessMessageCommand{
+			ChatID:      chatID,
+			UserID:      userID,
+			MessageID:   message.MessageID,
+			Message:     b.cleanMessage(message.Text),
+			Username:    message.From.UserName,
+			DisplayName: displayName(message.From),
 		})
+		response, answered, revision = reply.Text, reply.Answered, reply.Revision
 	}
 
 	if err != nil {
@@ -174,22 +374,169 @@ func (b *Bot) handleUpdate(ctx context.Context, update tgbotapi.Update) {
 	}
 
 	if response != "" {
+		if answered && b.config.Get().ThinkingShow {
+			b.sendThinking(ctx, chatID, userID, message.MessageID)
+		}
+
 		msg := tgbotapi.NewMessage(chatID, response)
 		msg.ReplyToMessageID = message.MessageID
 		msg.DisableNotification = true
 
-		session, err := b.commandHandler.GetSession(ctx, chatID, userID)
-		if err == nil && session.IsActive {
-			endChatButton := tgbotapi.NewInlineKeyboardButtonData("Завершить сессию", "end_chat")
-			row := tgbotapi.NewInlineKeyboardRow(endChatButton)
-			keyboard := tgbotapi.NewInlineKeyboardMarkup(row)
-			msg.ReplyMarkup = keyboard
+		if keyboard := b.sessionKeyboard(ctx, chatID, userID, answered); keyboard != nil {
+			msg.ReplyMarkup = *keyboard
 		}
 
-		if _, err := b.api.Send(msg); err != nil {
+		sent, err := b.api.Send(msg)
+		if err != nil {
 			b.logger.Error("Failed to send message", zap.Error(err))
+			return
+		}
+
+		// Заметки и тексты ошибок не привязываются к ответу в истории
+		if answered {
+			b.recordReply(ctx, chatID, userID, revision, sent.MessageID)
+		}
+	}
+}
+
+func (b *Bot) isAuthorized(chatID int64) bool {
+	for _, allowedChatID := range b.config.Get().AllowedChatIDs {
+		if chatID == allowedChatID {
+			return true
 		}
 	}
+	return false
+}
+
+// isUserAuthorized checks users that reach the bot outside allowed chats,
+// e.g. through inline queries
+func (b *Bot) isUserAuthorized(userID int64) bool {
+	for _, allowedUserID := range b.config.Get().AllowedUserIDs {
+		if userID == allowedUserID {
+			return true
+		}
+	}
+	return false
+}
+
+// SendText sends a plain message from the operator to an allowed chat
+func (b *Bot) SendText(ctx context.Context, chatID int64, text string) error {
+	if !b.isAuthorized(chatID) {
+		return fmt.Errorf("chat %d is not allowed", chatID)
+	}
+
+	_, err := b.api.Send(tgbotapi.NewMessage(chatID, text))
+	return err
+}
+
+// recordReply remembers which Telegram message holds the answer stored at the
+// given session revision and offers the facts Claude proposed to remember
+// while writing it
+func (b *Bot) recordReply(ctx context.Context, chatID, userID, revision int64, messageID int) {
+	err := b.commandHandler.HandleRecordReply(ctx, commands.RecordReplyCommand{
+		ChatID:    chatID,
+		UserID:    userID,
+		MessageID: messageID,
+		Revision:  revision,
+	})
+	if err != nil {
+		b.logger.Warn("Failed to record reply message", zap.Error(err))
+	}
+
+	b.offerMemoryProposals(ctx, chatID, userID)
+}
+
+// handleEditedMessage regenerates the answer when a user edits a question that
+// is part of the session history, and edits the bot's original reply in place
+func (b *Bot) handleEditedMessage(ctx context.Context, message *tgbotapi.Message) {
+	if !b.isAuthorized(message.Chat.ID) || message.IsCommand() {
+		return
+	}
+
+	if b.isFromGroup(message) && !b.isBotMentioned(message) && !b.isReplyToBot(message) {
+		return
+	}
+
+	chatID := message.Chat.ID
+	userID := message.From.ID
+
+	b.sendTypingAction(chatID)
+
+	reply, replyID, err := b.commandHandler.HandleEditedMessage(ctx, commands.EditMessageCommand{
+		ChatID:      chatID,
+		UserID:      userID,
+		MessageID:   message.MessageID,
+		Message:     b.cleanMessage(message.Text),
+		DisplayName: displayName(message.From),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, handlers.ErrMessageNotInHistory):
+		case errors.Is(err, repositories.ErrSessionChanged):
+			b.sendNotice(chatID, sessionChangedNotice)
+		default:
+			b.logger.Error("Failed to handle edited message", zap.Error(err))
+			b.sendNotice(chatID, "😔 Не удалось обновить ответ на исправленное сообщение. Попробуй позже.")
+		}
+		return
+	}
+
+	keyboard := b.sessionKeyboard(ctx, chatID, userID, true)
+
+	if replyID != 0 {
+		edit := tgbotapi.NewEditMessageText(chatID, replyID, reply.Text)
+		edit.ReplyMarkup = keyboard
+
+		if _, err := b.api.Send(edit); err != nil {
+			b.logger.Error("Failed to edit reply to edited message", zap.Error(err))
+		}
+
+		b.offerMemoryProposals(ctx, chatID, userID)
+		return
+	}
+
+	// Исходный ответ неизвестен, отвечаем новым сообщением
+	msg := tgbotapi.NewMessage(chatID, reply.Text)
+	msg.ReplyToMessageID = message.MessageID
+	msg.DisableNotification = true
+	if keyboard != nil {
+		msg.ReplyMarkup = *keyboard
+	}
+
+	sent, err := b.api.Send(msg)
+	if err != nil {
+		b.logger.Error("Failed to send reply to edited message", zap.Error(err))
+		return
+	}
+
+	b.recordReply(ctx, chatID, userID, reply.Revision, sent.MessageID)
+}
+
+// sessionKeyboard builds the inline keyboard attached to replies in an active
+// session. Reply buttons (regenerate, continue) are only added to Claude's answers.
+func (b *Bot) sessionKeyboard(ctx context.Context, chatID, userID int64, withReplyButtons bool) *tgbotapi.InlineKeyboardMarkup {
+	session, err := b.commandHandler.GetSession(ctx, chatID, userID)
+	if err != nil || !session.IsActive {
+		return nil
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+
+	if last := session.LastMessage(); withReplyButtons && last != nil && last.Role == "assistant" {
+		replyRow := tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🔄 Regenerate", callbackRegenerate),
+		)
+		if last.Truncated {
+			replyRow = append(replyRow, tgbotapi.NewInlineKeyboardButtonData("➡️ Continue", callbackContinue))
+		}
+		rows = append(rows, replyRow)
+	}
+
+	endChatButton := tgbotapi.NewInlineKeyboardButtonData("Завершить сессию", callbackEndChat)
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(endChatButton))
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return &keyboard
 }
 
 func (b *Bot) sendTypingAction(chatID int64) {
@@ -203,6 +550,36 @@ func (b *Bot) isFromGroup(message *tgbotapi.Message) bool {
 	return message.Chat.IsGroup() || message.Chat.IsSuperGroup()
 }
 
+// isChatAdmin reports whether the user administers the chat. In private chats
+// the user is always treated as the admin.
+func (b *Bot) isChatAdmin(chat *tgbotapi.Chat, userID int64) bool {
+	if chat.IsPrivate() {
+		return true
+	}
+
+	member, err := b.api.GetChatMember(tgbotapi.GetChatMemberConfig{
+		ChatConfigWithUser: tgbotapi.ChatConfigWithUser{
+			ChatID: chat.ID,
+			UserID: userID,
+		},
+	})
+	if err != nil {
+		b.logger.Warn("Failed to get chat member", zap.Int64("chatID", chat.ID), zap.Int64("userID", userID), zap.Error(err))
+		return false
+	}
+
+	return member.IsCreator() || member.IsAdministrator()
+}
+
+// displayName returns the name a user is shown under in shared sessions
+func displayName(user *tgbotapi.User) string {
+	name := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	if name == "" {
+		name = user.UserName
+	}
+	return name
+}
+
 func (b *Bot) isBotMentioned(message *tgbotapi.Message) bool {
 	botUsername := "@" + b.api.Self.UserName
 	return strings.Contains(message.Text, botUsername)
@@ -229,10 +606,17 @@ func (b *Bot) handleCallbackQuery(ctx context.Context, callbackQuery *tgbotapi.C
 	}
 
 	switch callbackQuery.Data {
-	case "end_chat":
+	case callbackRegenerate:
+		b.handleRegenerateCallback(ctx, callbackQuery)
+	case callbackContinue:
+		b.handleContinueCallback(ctx, callbackQuery)
+	case callbackKeepSession:
+		b.handleKeepSessionCallback(ctx, callbackQuery)
+	case callbackEndChat:
 		response, err := b.commandHandler.HandleEndChat(ctx, commands.EndChatCommand{
-			ChatID: callbackQuery.Message.Chat.ID,
-			UserID: callbackQuery.From.ID,
+			ChatID:  callbackQuery.Message.Chat.ID,
+			UserID:  callbackQuery.From.ID,
+			IsAdmin: b.isChatAdmin(callbackQuery.Message.Chat, callbackQuery.From.ID),
 		})
 
 		if err != nil {
@@ -246,5 +630,189 @@ func (b *Bot) handleCallbackQuery(ctx context.Context, callbackQuery *tgbotapi.C
 		if _, err := b.api.Send(msg); err != nil {
 			b.logger.Error("Failed to send end chat confirmation", zap.Error(err))
 		}
+	default:
+		switch {
+		case strings.HasPrefix(callbackQuery.Data, callbackCancelReminderPrefix):
+			b.handleCancelReminderCallback(ctx, callbackQuery)
+		case strings.HasPrefix(callbackQuery.Data, callbackListTogglePrefix),
+			strings.HasPrefix(callbackQuery.Data, callbackListClearPrefix):
+			b.handleListCallback(ctx, callbackQuery)
+		case strings.HasPrefix(callbackQuery.Data, callbackMemoryAcceptPrefix),
+			strings.HasPrefix(callbackQuery.Data, callbackMemoryRejectPrefix):
+			b.handleMemoryCallback(ctx, callbackQuery)
+		case strings.HasPrefix(callbackQuery.Data, callbackReopenPrefix):
+			b.handleReopenCallback(ctx, callbackQuery)
+		case strings.HasPrefix(callbackQuery.Data, callbackModelPrefix):
+			b.handleModelCallback(ctx, callbackQuery)
+		}
+	}
+}
+
+// handleRegenerateCallback replaces the answer in the message with a fresh sample
+func (b *Bot) handleRegenerateCallback(ctx context.Context, callbackQuery *tgbotapi.CallbackQuery) {
+	chatID := callbackQuery.Message.Chat.ID
+	userID := callbackQuery.From.ID
+
+	b.sendTypingAction(chatID)
+
+	response, err := b.commandHandler.HandleRegenerate(ctx, commands.RegenerateCommand{
+		ChatID:    chatID,
+		UserID:    userID,
+		MessageID: callbackQuery.Message.MessageID,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, handlers.ErrNothingToRegenerate):
+			b.sendNotice(chatID, "ℹ️ Этот ответ уже нельзя перегенерировать.")
+		case errors.Is(err, repositories.ErrSessionChanged):
+			b.sendNotice(chatID, sessionChangedNotice)
+		default:
+			b.logger.Error("Failed to regenerate response", zap.Error(err))
+			b.sendNotice(chatID, "😔 Не удалось перегенерировать ответ. Попробуй позже.")
+		}
+		return
+	}
+
+	edit := tgbotapi.NewEditMessageText(chatID, callbackQuery.Message.MessageID, response)
+	edit.ReplyMarkup = b.sessionKeyboard(ctx, chatID, userID, true)
+
+	if _, err := b.api.Send(edit); err != nil {
+		b.logger.Error("Failed to edit regenerated message", zap.Error(err))
+	}
+
+	b.offerMemoryProposals(ctx, chatID, userID)
+}
+
+// handleContinueCallback sends the continuation of a truncated answer as a
+// reply to it and removes the buttons from the original message
+func (b *Bot) handleContinueCallback(ctx context.Context, callbackQuery *tgbotapi.CallbackQuery) {
+	chatID := callbackQuery.Message.Chat.ID
+	userID := callbackQuery.From.ID
+
+	b.sendTypingAction(chatID)
+
+	reply, err := b.commandHandler.HandleContinue(ctx, commands.ContinueCommand{
+		ChatID:    chatID,
+		UserID:    userID,
+		MessageID: callbackQuery.Message.MessageID,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, handlers.ErrNothingToContinue):
+			b.sendNotice(chatID, "ℹ️ Этот ответ уже нельзя продолжить.")
+		case errors.Is(err, repositories.ErrSessionChanged):
+			b.sendNotice(chatID, sessionChangedNotice)
+		default:
+			b.logger.Error("Failed to continue response", zap.Error(err))
+			b.sendNotice(chatID, "😔 Не удалось продолжить ответ. Попробуй позже.")
+		}
+		return
+	}
+
+	clearMarkup := tgbotapi.NewEditMessageReplyMarkup(chatID, callbackQuery.Message.MessageID, tgbotapi.InlineKeyboardMarkup{
+		InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{},
+	})
+	if _, err := b.api.Request(clearMarkup); err != nil {
+		b.logger.Debug("Failed to clear reply markup", zap.Error(err))
+	}
+
+	msg := tgbotapi.NewMessage(chatID, reply.Text)
+	msg.ReplyToMessageID = callbackQuery.Message.MessageID
+	msg.DisableNotification = true
+	if keyboard := b.sessionKeyboard(ctx, chatID, userID, true); keyboard != nil {
+		msg.ReplyMarkup = *keyboard
+	}
+
+	sent, err := b.api.Send(msg)
+	if err != nil {
+		b.logger.Error("Failed to send continuation", zap.Error(err))
+		return
+	}
+
+	b.recordReply(ctx, chatID, userID, reply.Revision, sent.MessageID)
+}
+
+func (b *Bot) sendNotice(chatID int64, text string) {
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.DisableNotification = true
+
+	if _, err := b.api.Send(msg); err != nil {
+		b.logger.Error("Failed to send notice", zap.Error(err))
+	}
+}
+
+// handleInlineQuery answers "@bot question" typed in any chat with a single
+// article holding Claude's one-shot reply
+func (b *Bot) handleInlineQuery(ctx context.Context, inlineQuery *tgbotapi.InlineQuery) {
+	if !b.isUserAuthorized(inlineQuery.From.ID) {
+		b.logger.Warn("Inline query from unauthorized user", zap.Int64("userID", inlineQuery.From.ID))
+		return
+	}
+
+	query := strings.TrimSpace(inlineQuery.Query)
+	if len([]rune(query)) < minInlineQueryLength {
+		return
+	}
+
+	// Telegram присылает запрос на каждое нажатие клавиши, модель вызывается,
+	// когда пользователь перестал печатать
+	if !b.waitInlineTyping(ctx, inlineQuery) {
+		return
+	}
+
+	response, err := b.commandHandler.HandleInlineQuery(ctx, commands.InlineQueryCommand{
+		UserID: inlineQuery.From.ID,
+		Query:  query,
+	})
+	if err != nil {
+		b.logger.Error("Failed to handle inline query", zap.Error(err))
+		return
+	}
+
+	article := tgbotapi.NewInlineQueryResultArticle(inlineQuery.ID, truncateRunes(query, inlineDescriptionLength), truncateRunes(response, maxMessageLength))
+	article.Description = truncateRunes(response, inlineDescriptionLength)
+
+	answer := tgbotapi.InlineConfig{
+		InlineQueryID: inlineQuery.ID,
+		Results:       []interface{}{article},
+		IsPersonal:    true,
+	}
+
+	if _, err := b.api.Request(answer); err != nil {
+		b.logger.Error("Failed to answer inline query", zap.Error(err))
+	}
+}
+
+// waitInlineTyping waits for the debounce delay and reports whether the query
+// is still the user's latest one
+func (b *Bot) waitInlineTyping(ctx context.Context, inlineQuery *tgbotapi.InlineQuery) bool {
+	userID := inlineQuery.From.ID
+
+	b.inlineMutex.Lock()
+	b.latestInlineQueries[userID] = inlineQuery.ID
+	b.inlineMutex.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(inlineQueryDebounce):
+	}
+
+	b.inlineMutex.Lock()
+	defer b.inlineMutex.Unlock()
+
+	if b.latestInlineQueries[userID] != inlineQuery.ID {
+		return false
+	}
+	delete(b.latestInlineQueries, userID)
+	return true
+}
+
+// truncateRunes shortens text to at most limit characters
+func truncateRunes(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
 	}
+	return string(runes[:limit-1]) + "…"
 }
@@ -0,0 +1,119 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"telegram-chatbot/internal/domain/commands"
+	"telegram-chatbot/internal/domain/entities"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	callbackListTogglePrefix = "list_toggle:" // list_toggle:<номер>:<список>
+	callbackListClearPrefix  = "list_clear:"  // list_clear:<список>
+)
+
+// sendList runs a /list command and replies with the list and its checkboxes
+func (b *Bot) sendList(ctx context.Context, message *tgbotapi.Message) {
+	text, list, err := b.listHandler.HandleList(ctx, commands.ListCommand{
+		ChatID:      message.Chat.ID,
+		UserID:      message.From.ID,
+		DisplayName: displayName(message.From),
+		Args:        message.CommandArguments(),
+	})
+	if err != nil {
+		b.logger.Error("Failed to handle list command", zap.Error(err))
+		text = "😔 Произошла ошибка. Попробуй позже."
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, text)
+	msg.ReplyToMessageID = message.MessageID
+	msg.DisableNotification = true
+	if keyboard := listKeyboard(list); keyboard != nil {
+		msg.ReplyMarkup = *keyboard
+	}
+
+	if _, err := b.api.Send(msg); err != nil {
+		b.logger.Error("Failed to send list", zap.Error(err))
+	}
+}
+
+// listKeyboard builds one checkbox button per item and a button removing done items
+func listKeyboard(list *entities.FamilyList) *tgbotapi.InlineKeyboardMarkup {
+	if list == nil || len(list.Items) == 0 {
+		return nil
+	}
+
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(list.Items)+1)
+	hasDone := false
+
+	for i, item := range list.Items {
+		mark := "☐"
+		if item.Done {
+			mark = "☑"
+			hasDone = true
+		}
+		data := fmt.Sprintf("%s%d:%s", callbackListTogglePrefix, i+1, list.Name)
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%s %s", mark, item.Text), data)))
+	}
+
+	if hasDone {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🧹 Убрать отмеченные", callbackListClearPrefix+list.Name)))
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return &keyboard
+}
+
+// handleListCallback toggles an item or clears done items and redraws the list
+func (b *Bot) handleListCallback(ctx context.Context, callbackQuery *tgbotapi.CallbackQuery) {
+	chatID := callbackQuery.Message.Chat.ID
+	userID := callbackQuery.From.ID
+	data := callbackQuery.Data
+
+	var text string
+	var list *entities.FamilyList
+	var err error
+
+	switch {
+	case strings.HasPrefix(data, callbackListTogglePrefix):
+		numberStr, name, found := strings.Cut(strings.TrimPrefix(data, callbackListTogglePrefix), ":")
+		number, convErr := strconv.Atoi(numberStr)
+		if !found || convErr != nil {
+			return
+		}
+		text, list, err = b.listHandler.HandleToggleListItem(ctx, commands.ToggleListItemCommand{
+			ChatID:   chatID,
+			UserID:   userID,
+			ListName: name,
+			Number:   number,
+		})
+	case strings.HasPrefix(data, callbackListClearPrefix):
+		text, list, err = b.listHandler.HandleClearList(ctx, commands.ClearListCommand{
+			ChatID:   chatID,
+			UserID:   userID,
+			ListName: strings.TrimPrefix(data, callbackListClearPrefix),
+		})
+	default:
+		return
+	}
+
+	if err != nil {
+		b.logger.Error("Failed to update list", zap.Error(err))
+		b.sendNotice(chatID, "😔 Не удалось обновить список. Попробуй позже.")
+		return
+	}
+
+	edit := tgbotapi.NewEditMessageText(chatID, callbackQuery.Message.MessageID, text)
+	edit.ReplyMarkup = listKeyboard(list)
+
+	if _, err := b.api.Send(edit); err != nil {
+		b.logger.Error("Failed to redraw list", zap.Error(err))
+	}
+}
@@ -0,0 +1,66 @@
+package telegram
+
+import (
+	"context"
+	"strings"
+	"telegram-chatbot/internal/domain/commands"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	callbackMemoryAcceptPrefix = "memory_accept:"
+	callbackMemoryRejectPrefix = "memory_reject:"
+)
+
+// offerMemoryProposals asks the user to confirm facts Claude proposed to remember
+func (b *Bot) offerMemoryProposals(ctx context.Context, chatID, userID int64) {
+	proposals, err := b.memoryHandler.HandleTakeMemoryProposals(ctx, commands.TakeMemoryProposalsCommand{
+		ChatID: chatID,
+		UserID: userID,
+	})
+	if err != nil {
+		b.logger.Warn("Failed to get memory proposals", zap.Error(err))
+		return
+	}
+
+	for _, proposal := range proposals {
+		msg := tgbotapi.NewMessage(chatID, "💡 Запомнить на будущее?\n«"+proposal.Fact+"»")
+		msg.DisableNotification = true
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Запомнить", callbackMemoryAcceptPrefix+proposal.ID),
+			tgbotapi.NewInlineKeyboardButtonData("❌ Не надо", callbackMemoryRejectPrefix+proposal.ID),
+		))
+
+		if _, err := b.api.Send(msg); err != nil {
+			b.logger.Error("Failed to send memory proposal", zap.Error(err))
+		}
+	}
+}
+
+// handleMemoryCallback applies the user's decision and replaces the question with the result
+func (b *Bot) handleMemoryCallback(ctx context.Context, callbackQuery *tgbotapi.CallbackQuery) {
+	chatID := callbackQuery.Message.Chat.ID
+	accept := strings.HasPrefix(callbackQuery.Data, callbackMemoryAcceptPrefix)
+
+	memoryID := strings.TrimPrefix(callbackQuery.Data, callbackMemoryAcceptPrefix)
+	memoryID = strings.TrimPrefix(memoryID, callbackMemoryRejectPrefix)
+
+	result, err := b.memoryHandler.HandleConfirmMemory(ctx, commands.ConfirmMemoryCommand{
+		ChatID:   chatID,
+		UserID:   callbackQuery.From.ID,
+		MemoryID: memoryID,
+		Accept:   accept,
+	})
+	if err != nil {
+		b.logger.Error("Failed to confirm memory", zap.Error(err))
+		b.sendNotice(chatID, "😔 Не удалось сохранить решение. Попробуй позже.")
+		return
+	}
+
+	edit := tgbotapi.NewEditMessageText(chatID, callbackQuery.Message.MessageID, result)
+	if _, err := b.api.Send(edit); err != nil {
+		b.logger.Error("Failed to update memory proposal", zap.Error(err))
+	}
+}
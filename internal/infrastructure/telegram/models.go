@@ -0,0 +1,90 @@
+package telegram
+
+import (
+	"context"
+	"strings"
+	"telegram-chatbot/internal/application/handlers"
+	"telegram-chatbot/internal/domain/commands"
+	"telegram-chatbot/internal/domain/entities"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+const callbackModelPrefix = "model:"
+
+// sendModelMenu shows the models of the chat with a button for each, or
+// switches right away when the alias is given as an argument
+func (b *Bot) sendModelMenu(ctx context.Context, message *tgbotapi.Message) {
+	text, catalog, err := b.modelHandler.HandleModel(ctx, commands.ModelCommand{
+		ChatID:  message.Chat.ID,
+		UserID:  message.From.ID,
+		IsAdmin: b.isChatAdmin(message.Chat, message.From.ID),
+		IsGroup: b.isFromGroup(message),
+		Alias:   strings.ToLower(strings.TrimSpace(message.CommandArguments())),
+	})
+	if err != nil {
+		b.logger.Error("Failed to handle model command", zap.Error(err))
+		text = "😔 Произошла ошибка. Попробуй позже."
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, text)
+	msg.ReplyToMessageID = message.MessageID
+	msg.DisableNotification = true
+	if keyboard := modelKeyboard(catalog); keyboard != nil {
+		msg.ReplyMarkup = *keyboard
+	}
+
+	if _, err := b.api.Send(msg); err != nil {
+		b.logger.Error("Failed to send model menu", zap.Error(err))
+	}
+}
+
+// modelKeyboard builds a button per model and one to return to the default
+func modelKeyboard(catalog entities.ModelCatalog) *tgbotapi.InlineKeyboardMarkup {
+	if len(catalog) == 0 {
+		return nil
+	}
+
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(catalog)+1)
+	for _, option := range catalog {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(option.Alias, callbackModelPrefix+option.Alias)))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("По умолчанию", callbackModelPrefix+handlers.DefaultModelAlias)))
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return &keyboard
+}
+
+// handleModelCallback applies the chosen model and replaces the menu with the result
+func (b *Bot) handleModelCallback(ctx context.Context, callbackQuery *tgbotapi.CallbackQuery) {
+	chatID := callbackQuery.Message.Chat.ID
+	isAdmin := b.isChatAdmin(callbackQuery.Message.Chat, callbackQuery.From.ID)
+	isGroup := b.isFromGroup(callbackQuery.Message)
+
+	result, _, err := b.modelHandler.HandleModel(ctx, commands.ModelCommand{
+		ChatID:  chatID,
+		UserID:  callbackQuery.From.ID,
+		IsAdmin: isAdmin,
+		IsGroup: isGroup,
+		Alias:   strings.TrimPrefix(callbackQuery.Data, callbackModelPrefix),
+	})
+	if err != nil {
+		b.logger.Error("Failed to change model", zap.Error(err))
+		b.sendNotice(chatID, "😔 Не удалось сменить модель. Попробуй позже.")
+		return
+	}
+
+	// Отказ не убирает меню, им ещё может воспользоваться администратор
+	if isGroup && !isAdmin {
+		b.sendNotice(chatID, result)
+		return
+	}
+
+	edit := tgbotapi.NewEditMessageText(chatID, callbackQuery.Message.MessageID, result)
+	if _, err := b.api.Send(edit); err != nil {
+		b.logger.Error("Failed to update model menu", zap.Error(err))
+	}
+}
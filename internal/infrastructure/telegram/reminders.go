@@ -0,0 +1,129 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"telegram-chatbot/internal/domain/commands"
+	"telegram-chatbot/internal/domain/entities"
+	"unicode/utf16"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// SendReminder delivers a due reminder. In groups the user is mentioned so
+// Telegram notifies them.
+func (b *Bot) SendReminder(ctx context.Context, reminder entities.Reminder) error {
+	var msg tgbotapi.MessageConfig
+
+	if reminder.ChatID == reminder.UserID {
+		msg = tgbotapi.NewMessage(reminder.ChatID, "⏰ Напоминаю: "+reminder.Text)
+	} else {
+		prefix := "⏰ "
+		msg = tgbotapi.NewMessage(reminder.ChatID, fmt.Sprintf("%s%s, напоминаю: %s", prefix, reminder.DisplayName, reminder.Text))
+		msg.Entities = []tgbotapi.MessageEntity{{
+			Type:   "text_mention",
+			Offset: utf16Len(prefix),
+			Length: utf16Len(reminder.DisplayName),
+			User:   &tgbotapi.User{ID: reminder.UserID},
+		}}
+	}
+
+	_, err := b.api.Send(msg)
+	return err
+}
+
+// utf16Len returns the length of the text in UTF-16 code units used by Telegram entity offsets
+func utf16Len(text string) int {
+	return len(utf16.Encode([]rune(text)))
+}
+
+// sendReminderList sends the user's reminders with a cancel button for each
+func (b *Bot) sendReminderList(ctx context.Context, message *tgbotapi.Message) {
+	text, reminders, err := b.reminderHandler.HandleListReminders(ctx, commands.ListRemindersCommand{
+		ChatID: message.Chat.ID,
+		UserID: message.From.ID,
+	})
+	if err != nil {
+		b.logger.Error("Failed to list reminders", zap.Error(err))
+		text = "😔 Произошла ошибка. Попробуй позже."
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, text)
+	msg.ReplyToMessageID = message.MessageID
+	msg.DisableNotification = true
+	if keyboard := reminderKeyboard(reminders); keyboard != nil {
+		msg.ReplyMarkup = *keyboard
+	}
+
+	if _, err := b.api.Send(msg); err != nil {
+		b.logger.Error("Failed to send reminder list", zap.Error(err))
+	}
+}
+
+// reminderKeyboard builds a row of numbered cancel buttons
+func reminderKeyboard(reminders []entities.Reminder) *tgbotapi.InlineKeyboardMarkup {
+	if len(reminders) == 0 {
+		return nil
+	}
+
+	const buttonsPerRow = 5
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+
+	for i, reminder := range reminders {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(
+			"❌ "+strconv.Itoa(i+1), callbackCancelReminderPrefix+reminder.ID))
+		if len(row) == buttonsPerRow {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return &keyboard
+}
+
+// handleCancelReminderCallback cancels the chosen reminder and refreshes the list
+func (b *Bot) handleCancelReminderCallback(ctx context.Context, callbackQuery *tgbotapi.CallbackQuery) {
+	chatID := callbackQuery.Message.Chat.ID
+	userID := callbackQuery.From.ID
+
+	// Список принадлежит тому, кто его запросил
+	if owner := callbackQuery.Message.ReplyToMessage; owner != nil && owner.From != nil && owner.From.ID != userID {
+		return
+	}
+
+	result, err := b.reminderHandler.HandleCancelReminder(ctx, commands.CancelReminderCommand{
+		ChatID:     chatID,
+		UserID:     userID,
+		ReminderID: strings.TrimPrefix(callbackQuery.Data, callbackCancelReminderPrefix),
+	})
+	if err != nil {
+		b.logger.Error("Failed to cancel reminder", zap.Error(err))
+		b.sendNotice(chatID, "😔 Не удалось отменить напоминание. Попробуй позже.")
+		return
+	}
+
+	list, reminders, err := b.reminderHandler.HandleListReminders(ctx, commands.ListRemindersCommand{
+		ChatID: chatID,
+		UserID: userID,
+	})
+	if err != nil {
+		b.logger.Error("Failed to list reminders", zap.Error(err))
+		b.sendNotice(chatID, result)
+		return
+	}
+
+	edit := tgbotapi.NewEditMessageText(chatID, callbackQuery.Message.MessageID, result+"\n\n"+list)
+	edit.ReplyMarkup = reminderKeyboard(reminders)
+
+	if _, err := b.api.Send(edit); err != nil {
+		b.logger.Error("Failed to update reminder list", zap.Error(err))
+	}
+}
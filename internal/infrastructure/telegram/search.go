@@ -0,0 +1,87 @@
+package telegram
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"telegram-chatbot/internal/domain/commands"
+	"telegram-chatbot/internal/domain/entities"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+const callbackReopenPrefix = "reopen:"
+
+// sendSearchResults answers /search with the found snippets and a reopen
+// button for each conversation
+func (b *Bot) sendSearchResults(ctx context.Context, message *tgbotapi.Message) {
+	text, matches, err := b.commandHandler.HandleSearch(ctx, commands.SearchCommand{
+		ChatID: message.Chat.ID,
+		UserID: message.From.ID,
+		Query:  message.CommandArguments(),
+	})
+	if err != nil {
+		b.logger.Error("Failed to search archive", zap.Error(err))
+		text = "😔 Произошла ошибка. Попробуй позже."
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, text)
+	msg.ReplyToMessageID = message.MessageID
+	msg.DisableNotification = true
+	if keyboard := searchKeyboard(matches); keyboard != nil {
+		msg.ReplyMarkup = *keyboard
+	}
+
+	if _, err := b.api.Send(msg); err != nil {
+		b.logger.Error("Failed to send search results", zap.Error(err))
+	}
+}
+
+// searchKeyboard builds a row of numbered reopen buttons
+func searchKeyboard(matches []entities.ConversationMatch) *tgbotapi.InlineKeyboardMarkup {
+	if len(matches) == 0 {
+		return nil
+	}
+
+	row := make([]tgbotapi.InlineKeyboardButton, 0, len(matches))
+	for i, match := range matches {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(
+			"📂 "+strconv.Itoa(i+1), callbackReopenPrefix+match.Conversation.ID))
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(row)
+	return &keyboard
+}
+
+// handleReopenCallback restores the chosen conversation into the session
+func (b *Bot) handleReopenCallback(ctx context.Context, callbackQuery *tgbotapi.CallbackQuery) {
+	chatID := callbackQuery.Message.Chat.ID
+	userID := callbackQuery.From.ID
+
+	// Результаты поиска принадлежат тому, кто искал
+	if owner := callbackQuery.Message.ReplyToMessage; owner != nil && owner.From != nil && owner.From.ID != userID {
+		return
+	}
+
+	response, err := b.commandHandler.HandleReopenConversation(ctx, commands.ReopenConversationCommand{
+		ChatID:         chatID,
+		UserID:         userID,
+		IsAdmin:        b.isChatAdmin(callbackQuery.Message.Chat, userID),
+		ConversationID: strings.TrimPrefix(callbackQuery.Data, callbackReopenPrefix),
+	})
+	if err != nil {
+		b.logger.Error("Failed to reopen conversation", zap.Error(err))
+		response = "😔 Не удалось открыть разговор. Попробуй позже."
+	}
+
+	msg := tgbotapi.NewMessage(chatID, response)
+	msg.DisableNotification = true
+	if keyboard := b.sessionKeyboard(ctx, chatID, userID, false); keyboard != nil {
+		msg.ReplyMarkup = *keyboard
+	}
+
+	if _, err := b.api.Send(msg); err != nil {
+		b.logger.Error("Failed to send reopen result", zap.Error(err))
+	}
+}
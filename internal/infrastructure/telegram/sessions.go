@@ -0,0 +1,86 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"telegram-chatbot/internal/domain/commands"
+	"telegram-chatbot/internal/domain/entities"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+const callbackKeepSession = "keep_session"
+
+// SendSessionExpiryWarning warns that an idle session ends soon and offers to keep it
+func (b *Bot) SendSessionExpiryWarning(ctx context.Context, session entities.ChatSession, expiresAt time.Time) error {
+	minutes := int(math.Ceil(time.Until(expiresAt).Minutes()))
+	if minutes < 1 {
+		minutes = 1
+	}
+
+	msg := sessionNotice(session, "⏳ ", "Сессия", fmt.Sprintf(" завершится через %d мин. из-за неактивности.", minutes))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("⏱ Продлить сессию", callbackKeepSession),
+		),
+	)
+
+	_, err := b.api.Send(msg)
+	return err
+}
+
+// SendSessionExpired tells the user an idle session was ended
+func (b *Bot) SendSessionExpired(ctx context.Context, session entities.ChatSession) error {
+	msg := sessionNotice(session, "⌛ ", "Сессия",
+		" завершена из-за неактивности. Разговор сохранён, его можно найти через /search. Начать новую сессию: /begin_chat")
+
+	_, err := b.api.Send(msg)
+	return err
+}
+
+// sessionNotice builds a message about a session. In groups a personal
+// session's owner is mentioned on the label so Telegram notifies them.
+func sessionNotice(session entities.ChatSession, prefix, label, text string) tgbotapi.MessageConfig {
+	msg := tgbotapi.NewMessage(session.ChatID, prefix+label+text)
+
+	if !session.IsShared() && session.ChatID != session.UserID {
+		msg.Entities = []tgbotapi.MessageEntity{{
+			Type:   "text_mention",
+			Offset: utf16Len(prefix),
+			Length: utf16Len(label),
+			User:   &tgbotapi.User{ID: session.UserID},
+		}}
+	}
+
+	return msg
+}
+
+// handleKeepSessionCallback extends the session and replaces the warning with the result
+func (b *Bot) handleKeepSessionCallback(ctx context.Context, callbackQuery *tgbotapi.CallbackQuery) {
+	chatID := callbackQuery.Message.Chat.ID
+
+	// Личную сессию в группе продлевает только её владелец, упомянутый в предупреждении
+	for _, entity := range callbackQuery.Message.Entities {
+		if entity.Type == "text_mention" && entity.User != nil && entity.User.ID != callbackQuery.From.ID {
+			return
+		}
+	}
+
+	response, err := b.commandHandler.HandleKeepSession(ctx, commands.KeepSessionCommand{
+		ChatID: chatID,
+		UserID: callbackQuery.From.ID,
+	})
+	if err != nil {
+		b.logger.Error("Failed to keep session", zap.Error(err))
+		b.sendNotice(chatID, "😔 Не удалось продлить сессию. Попробуй позже.")
+		return
+	}
+
+	edit := tgbotapi.NewEditMessageText(chatID, callbackQuery.Message.MessageID, response)
+	if _, err := b.api.Send(edit); err != nil {
+		b.logger.Error("Failed to update session warning", zap.Error(err))
+	}
+}
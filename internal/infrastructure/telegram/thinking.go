@@ -0,0 +1,43 @@
+package telegram
+
+import (
+	"context"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// maxThinkingLength keeps the reasoning within one Telegram message
+const maxThinkingLength = 3500
+
+// sendThinking shows the reasoning behind the last answer of the session in a
+// collapsed quote, the reader expands it if interested
+func (b *Bot) sendThinking(ctx context.Context, chatID, userID int64, replyToMessageID int) {
+	session, err := b.commandHandler.GetSession(ctx, chatID, userID)
+	if err != nil || !session.IsActive {
+		return
+	}
+
+	// У ещё не отправленного ответа нет ID сообщения, иначе это старый ответ,
+	// а новый не получился
+	last := session.LastMessage()
+	if last == nil || last.Role != "assistant" || last.TelegramMessageID != 0 || last.Thinking == "" {
+		return
+	}
+
+	header := "💭 Ход рассуждений\n"
+	thinking := truncateRunes(last.Thinking, maxThinkingLength)
+
+	msg := tgbotapi.NewMessage(chatID, header+thinking)
+	msg.ReplyToMessageID = replyToMessageID
+	msg.DisableNotification = true
+	msg.Entities = []tgbotapi.MessageEntity{{
+		Type:   "expandable_blockquote",
+		Offset: utf16Len(header),
+		Length: utf16Len(thinking),
+	}}
+
+	if _, err := b.api.Send(msg); err != nil {
+		b.logger.Error("Failed to send thinking", zap.Error(err))
+	}
+}
@@ -0,0 +1,25 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"telegram-chatbot/internal/domain/tools"
+	"time"
+)
+
+// NewBuiltinTools returns the safe tools available in every deployment
+func NewBuiltinTools(location *time.Location) []tools.Tool {
+	return []tools.Tool{
+		NewDateTimeTool(location),
+		NewCalculatorTool(),
+		NewUnitConversionTool(),
+	}
+}
+
+// decodeInput unmarshals the tool input into the given struct
+func decodeInput(input json.RawMessage, v interface{}) error {
+	if err := json.Unmarshal(input, v); err != nil {
+		return fmt.Errorf("invalid tool input: %w", err)
+	}
+	return nil
+}
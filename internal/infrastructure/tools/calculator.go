@@ -0,0 +1,282 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"telegram-chatbot/internal/domain/tools"
+	"unicode"
+)
+
+// NewCalculatorTool evaluates arithmetic expressions
+func NewCalculatorTool() tools.Tool {
+	return tools.Tool{
+		Name: "calculator",
+		Description: "Evaluates an arithmetic expression exactly. Supports + - * / ^, parentheses, " +
+			"the functions sqrt, abs, round, floor, ceil, ln, log10, sin, cos, tan and the constants pi and e. " +
+			"Use it for any non-trivial arithmetic, e.g. percentages as 2400*15/100.",
+		InputSchema: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"expression": {"type": "string", "description": "Expression to evaluate, e.g. (2+3)*4^2"}
+			},
+			"required": ["expression"]
+		}`),
+		Handler: func(ctx context.Context, input json.RawMessage) (string, error) {
+			var params struct {
+				Expression string `json:"expression"`
+			}
+			if err := decodeInput(input, &params); err != nil {
+				return "", err
+			}
+
+			result, err := Evaluate(params.Expression)
+			if err != nil {
+				return "", err
+			}
+
+			return formatNumber(result), nil
+		},
+	}
+}
+
+// Evaluate computes the value of an arithmetic expression
+func Evaluate(expression string) (float64, error) {
+	p := &exprParser{input: []rune(expression)}
+
+	value, err := p.parseExpression()
+	if err != nil {
+		return 0, err
+	}
+
+	p.skipSpaces()
+	if p.pos < len(p.input) {
+		return 0, fmt.Errorf("unexpected %q at position %d", p.input[p.pos], p.pos+1)
+	}
+
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, fmt.Errorf("result is not a finite number")
+	}
+
+	return value, nil
+}
+
+func formatNumber(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+var calculatorFunctions = map[string]func(float64) float64{
+	"sqrt":  math.Sqrt,
+	"abs":   math.Abs,
+	"round": math.Round,
+	"floor": math.Floor,
+	"ceil":  math.Ceil,
+	"ln":    math.Log,
+	"log10": math.Log10,
+	"sin":   math.Sin,
+	"cos":   math.Cos,
+	"tan":   math.Tan,
+}
+
+var calculatorConstants = map[string]float64{
+	"pi": math.Pi,
+	"e":  math.E,
+}
+
+// exprParser is a recursive descent parser for the grammar:
+//
+//	expression = term { ("+" | "-") term }
+//	term       = unary { ("*" | "/") unary }
+//	unary      = ( "-" | "+" ) unary | power
+//	power      = primary [ "^" unary ]
+//	primary    = number | name [ "(" expression ")" ] | "(" expression ")"
+type exprParser struct {
+	input []rune
+	pos   int
+}
+
+func (p *exprParser) skipSpaces() {
+	for p.pos < len(p.input) && unicode.IsSpace(p.input[p.pos]) {
+		p.pos++
+	}
+}
+
+func (p *exprParser) peek() rune {
+	p.skipSpaces()
+	if p.pos >= len(p.input) {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+func (p *exprParser) parseExpression() (float64, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return 0, err
+	}
+
+	for {
+		switch p.peek() {
+		case '+':
+			p.pos++
+			right, err := p.parseTerm()
+			if err != nil {
+				return 0, err
+			}
+			left += right
+		case '-':
+			p.pos++
+			right, err := p.parseTerm()
+			if err != nil {
+				return 0, err
+			}
+			left -= right
+		default:
+			return left, nil
+		}
+	}
+}
+
+func (p *exprParser) parseTerm() (float64, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return 0, err
+	}
+
+	for {
+		switch p.peek() {
+		case '*':
+			p.pos++
+			right, err := p.parseUnary()
+			if err != nil {
+				return 0, err
+			}
+			left *= right
+		case '/':
+			p.pos++
+			right, err := p.parseUnary()
+			if err != nil {
+				return 0, err
+			}
+			if right == 0 {
+				return 0, fmt.Errorf("division by zero")
+			}
+			left /= right
+		default:
+			return left, nil
+		}
+	}
+}
+
+func (p *exprParser) parsePower() (float64, error) {
+	base, err := p.parsePrimary()
+	if err != nil {
+		return 0, err
+	}
+
+	if p.peek() == '^' {
+		p.pos++
+		exponent, err := p.parseUnary()
+		if err != nil {
+			return 0, err
+		}
+		return math.Pow(base, exponent), nil
+	}
+
+	return base, nil
+}
+
+func (p *exprParser) parseUnary() (float64, error) {
+	switch p.peek() {
+	case '-':
+		p.pos++
+		value, err := p.parseUnary()
+		return -value, err
+	case '+':
+		p.pos++
+		return p.parseUnary()
+	}
+	return p.parsePower()
+}
+
+func (p *exprParser) parsePrimary() (float64, error) {
+	r := p.peek()
+
+	switch {
+	case r == '(':
+		p.pos++
+		value, err := p.parseExpression()
+		if err != nil {
+			return 0, err
+		}
+		if p.peek() != ')' {
+			return 0, fmt.Errorf("missing closing parenthesis")
+		}
+		p.pos++
+		return value, nil
+	case unicode.IsDigit(r) || r == '.':
+		return p.parseNumber()
+	case unicode.IsLetter(r):
+		return p.parseName()
+	case r == 0:
+		return 0, fmt.Errorf("unexpected end of expression")
+	default:
+		return 0, fmt.Errorf("unexpected %q at position %d", r, p.pos+1)
+	}
+}
+
+func (p *exprParser) parseNumber() (float64, error) {
+	start := p.pos
+	for p.pos < len(p.input) && (unicode.IsDigit(p.input[p.pos]) || p.input[p.pos] == '.') {
+		p.pos++
+	}
+
+	// Экспоненциальная запись: 1.5e3
+	if p.pos < len(p.input) && (p.input[p.pos] == 'e' || p.input[p.pos] == 'E') {
+		next := p.pos + 1
+		if next < len(p.input) && (p.input[next] == '+' || p.input[next] == '-') {
+			next++
+		}
+		if next < len(p.input) && unicode.IsDigit(p.input[next]) {
+			p.pos = next
+			for p.pos < len(p.input) && unicode.IsDigit(p.input[p.pos]) {
+				p.pos++
+			}
+		}
+	}
+
+	text := string(p.input[start:p.pos])
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", text)
+	}
+	return value, nil
+}
+
+func (p *exprParser) parseName() (float64, error) {
+	start := p.pos
+	for p.pos < len(p.input) && (unicode.IsLetter(p.input[p.pos]) || unicode.IsDigit(p.input[p.pos])) {
+		p.pos++
+	}
+	name := strings.ToLower(string(p.input[start:p.pos]))
+
+	if fn, ok := calculatorFunctions[name]; ok {
+		if p.peek() != '(' {
+			return 0, fmt.Errorf("function %s requires an argument in parentheses", name)
+		}
+		arg, err := p.parsePrimary()
+		if err != nil {
+			return 0, err
+		}
+		return fn(arg), nil
+	}
+
+	if value, ok := calculatorConstants[name]; ok {
+		return value, nil
+	}
+
+	return 0, fmt.Errorf("unknown name %q", name)
+}
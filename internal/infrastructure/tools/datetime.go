@@ -0,0 +1,27 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"telegram-chatbot/internal/domain/tools"
+	"time"
+)
+
+var russianWeekdays = [...]string{
+	"воскресенье", "понедельник", "вторник", "среда", "четверг", "пятница", "суббота",
+}
+
+// NewDateTimeTool reports the current date and time in the family's time zone
+func NewDateTimeTool(location *time.Location) tools.Tool {
+	return tools.Tool{
+		Name:        "current_datetime",
+		Description: "Returns the current date, time and weekday in the family's time zone.",
+		InputSchema: json.RawMessage(`{"type":"object","properties":{}}`),
+		Handler: func(ctx context.Context, input json.RawMessage) (string, error) {
+			now := time.Now().In(location)
+			return fmt.Sprintf("%s, %s (часовой пояс %s)",
+				now.Format("2006-01-02 15:04:05"), russianWeekdays[now.Weekday()], location.String()), nil
+		},
+	}
+}
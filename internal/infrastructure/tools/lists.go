@@ -0,0 +1,187 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"telegram-chatbot/internal/domain/entities"
+	"telegram-chatbot/internal/domain/repositories"
+	"telegram-chatbot/internal/domain/tools"
+)
+
+const listNameSchema = `"list": {"type": "string", "description": "List name, e.g. покупки or дела. Defaults to покупки"}`
+
+// NewListTools exposes the chat's family lists to Claude
+func NewListTools(listRepo repositories.ListRepository) []tools.Tool {
+	lt := &listTools{listRepo: listRepo}
+
+	return []tools.Tool{
+		{
+			Name:        "list_show",
+			Description: "Shows the items of a family list of the current chat and the names of all its lists.",
+			InputSchema: json.RawMessage(`{"type": "object", "properties": {` + listNameSchema + `}}`),
+			Handler:     lt.show,
+		},
+		{
+			Name:        "list_add",
+			Description: "Adds items to a family list of the current chat, e.g. the shopping list.",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					` + listNameSchema + `,
+					"items": {"type": "array", "items": {"type": "string"}, "description": "Items to add, one per entry"}
+				},
+				"required": ["items"]
+			}`),
+			Handler: lt.add,
+		},
+		{
+			Name:        "list_mark_done",
+			Description: "Marks items of a family list as done (bought, completed). Items are given by text or by number.",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					` + listNameSchema + `,
+					"items": {"type": "array", "items": {"type": "string"}, "description": "Item texts or numbers"}
+				},
+				"required": ["items"]
+			}`),
+			Handler: lt.markDone,
+		},
+		{
+			Name:        "list_remove_done",
+			Description: "Removes all items marked as done from a family list.",
+			InputSchema: json.RawMessage(`{"type": "object", "properties": {` + listNameSchema + `}}`),
+			Handler:     lt.removeDone,
+		},
+	}
+}
+
+type listTools struct {
+	listRepo repositories.ListRepository
+}
+
+type listInput struct {
+	List  string   `json:"list"`
+	Items []string `json:"items"`
+}
+
+// load decodes the input and loads the list of the calling chat
+func (t *listTools) load(ctx context.Context, input json.RawMessage) (*entities.FamilyList, listInput, error) {
+	var params listInput
+	if err := decodeInput(input, &params); err != nil {
+		return nil, params, err
+	}
+
+	caller, ok := tools.CallerFromContext(ctx)
+	if !ok {
+		return nil, params, fmt.Errorf("lists are only available inside a chat")
+	}
+
+	// Название попадает в данные кнопок, длинное сломало бы клавиатуру списка
+	name := entities.NormalizeListName(params.List)
+	if err := entities.ValidateListName(name); err != nil {
+		return nil, params, fmt.Errorf("list name %q is too long, use at most %d bytes", name, entities.MaxListNameBytes)
+	}
+
+	list, err := t.listRepo.GetList(caller.ChatID, name)
+	if err != nil {
+		return nil, params, err
+	}
+
+	return list, params, nil
+}
+
+func (t *listTools) show(ctx context.Context, input json.RawMessage) (string, error) {
+	list, _, err := t.load(ctx, input)
+	if err != nil {
+		return "", err
+	}
+
+	names, err := t.listRepo.ListNames(list.ChatID)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("List %q:\n", list.Name))
+	if len(list.Items) == 0 {
+		sb.WriteString("(empty)\n")
+	}
+	for i, item := range list.Items {
+		mark := " "
+		if item.Done {
+			mark = "x"
+		}
+		sb.WriteString(fmt.Sprintf("%d. [%s] %s\n", i+1, mark, item.Text))
+	}
+	if len(names) > 0 {
+		sb.WriteString("All lists: " + strings.Join(names, ", "))
+	}
+
+	return sb.String(), nil
+}
+
+func (t *listTools) add(ctx context.Context, input json.RawMessage) (string, error) {
+	list, params, err := t.load(ctx, input)
+	if err != nil {
+		return "", err
+	}
+
+	added := list.AddItems(params.Items, "Claude")
+	if err := t.listRepo.SaveList(list); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Added %d item(s) to %q, it now has %d item(s).", added, list.Name, len(list.Items)), nil
+}
+
+func (t *listTools) markDone(ctx context.Context, input json.RawMessage) (string, error) {
+	list, params, err := t.load(ctx, input)
+	if err != nil {
+		return "", err
+	}
+
+	var notFound []string
+	marked := 0
+	for _, item := range params.Items {
+		number, err := strconv.Atoi(strings.TrimSpace(item))
+		if err != nil {
+			number, err = list.FindItem(item)
+		}
+		if err == nil {
+			err = list.SetDone(number, true)
+		}
+		if err != nil {
+			notFound = append(notFound, item)
+			continue
+		}
+		marked++
+	}
+
+	if err := t.listRepo.SaveList(list); err != nil {
+		return "", err
+	}
+
+	result := fmt.Sprintf("Marked %d item(s) as done in %q.", marked, list.Name)
+	if len(notFound) > 0 {
+		result += " Not found: " + strings.Join(notFound, ", ")
+	}
+	return result, nil
+}
+
+func (t *listTools) removeDone(ctx context.Context, input json.RawMessage) (string, error) {
+	list, _, err := t.load(ctx, input)
+	if err != nil {
+		return "", err
+	}
+
+	removed := list.RemoveDone()
+	if err := t.listRepo.SaveList(list); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Removed %d done item(s) from %q.", removed, list.Name), nil
+}
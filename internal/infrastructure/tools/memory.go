@@ -0,0 +1,71 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"telegram-chatbot/internal/domain/entities"
+	"telegram-chatbot/internal/domain/repositories"
+	"telegram-chatbot/internal/domain/tools"
+	"time"
+)
+
+// NewMemoryTools lets Claude suggest facts worth keeping across sessions.
+// Suggestions are stored as pending until the user confirms them.
+func NewMemoryTools(memoryRepo repositories.MemoryRepository) []tools.Tool {
+	return []tools.Tool{
+		{
+			Name: "propose_memory",
+			Description: "Suggests remembering a short, lasting fact about the user or the family " +
+				"(allergies, birthdays, preferences) for future conversations. The user confirms it with a button. " +
+				"Use sparingly and never for temporary details.",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"fact": {"type": "string", "description": "The fact as a short sentence in Russian"},
+					"scope": {"type": "string", "enum": ["user", "chat"], "description": "user: about the current user, chat: about the whole family"}
+				},
+				"required": ["fact"]
+			}`),
+			Handler: func(ctx context.Context, input json.RawMessage) (string, error) {
+				var params struct {
+					Fact  string `json:"fact"`
+					Scope string `json:"scope"`
+				}
+				if err := decodeInput(input, &params); err != nil {
+					return "", err
+				}
+
+				caller, ok := tools.CallerFromContext(ctx)
+				if !ok {
+					return "", fmt.Errorf("memories are only available inside a chat")
+				}
+
+				fact := strings.TrimSpace(params.Fact)
+				if fact == "" {
+					return "", fmt.Errorf("fact is empty")
+				}
+
+				userID := caller.UserID
+				if params.Scope == "chat" {
+					userID = entities.SharedSessionUserID
+				}
+
+				memory := &entities.Memory{
+					ID:        entities.NewID(),
+					ChatID:    caller.ChatID,
+					UserID:    userID,
+					Fact:      fact,
+					Pending:   true,
+					CreatedAt: time.Now(),
+				}
+				if err := memoryRepo.SaveMemory(memory); err != nil {
+					return "", err
+				}
+
+				return "The suggestion was shown to the user, it will be remembered once they confirm it.", nil
+			},
+		},
+	}
+}
@@ -0,0 +1,127 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"telegram-chatbot/internal/domain/tools"
+)
+
+type unit struct {
+	category string
+	factor   float64 // множитель к базовой единице категории
+}
+
+// units maps unit names to their category and factor relative to the base
+// unit (metre, kilogram, litre, second, square metre, metre per second, byte)
+var units = map[string]unit{
+	"mm": {"length", 0.001}, "cm": {"length", 0.01}, "m": {"length", 1}, "km": {"length", 1000},
+	"in": {"length", 0.0254}, "ft": {"length", 0.3048}, "yd": {"length", 0.9144}, "mi": {"length", 1609.344},
+
+	"mg": {"mass", 0.000001}, "g": {"mass", 0.001}, "kg": {"mass", 1}, "t": {"mass", 1000},
+	"oz": {"mass", 0.028349523125}, "lb": {"mass", 0.45359237},
+
+	"ml": {"volume", 0.001}, "l": {"volume", 1}, "m3": {"volume", 1000},
+	"tsp": {"volume", 0.00492892159375}, "tbsp": {"volume", 0.01478676478125}, "cup": {"volume", 0.2365882365},
+	"floz": {"volume", 0.0295735295625}, "gal": {"volume", 3.785411784},
+
+	"s": {"time", 1}, "min": {"time", 60}, "h": {"time", 3600}, "day": {"time", 86400}, "week": {"time", 604800},
+
+	"m2": {"area", 1}, "km2": {"area", 1000000}, "ha": {"area", 10000}, "sotka": {"area", 100},
+	"ft2": {"area", 0.09290304}, "acre": {"area", 4046.8564224},
+
+	"m/s": {"speed", 1}, "km/h": {"speed", 1 / 3.6}, "mph": {"speed", 0.44704}, "kn": {"speed", 0.514444},
+
+	"b": {"data", 1}, "kb": {"data", 1024}, "mb": {"data", 1024 * 1024}, "gb": {"data", 1024 * 1024 * 1024},
+	"tb": {"data", 1024 * 1024 * 1024 * 1024},
+}
+
+var temperatureUnits = map[string]bool{"c": true, "f": true, "k": true}
+
+// NewUnitConversionTool converts values between units of measurement
+func NewUnitConversionTool() tools.Tool {
+	return tools.Tool{
+		Name: "convert_units",
+		Description: "Converts a value between units of measurement. Supported units: " +
+			"length (mm, cm, m, km, in, ft, yd, mi), mass (mg, g, kg, t, oz, lb), " +
+			"volume (ml, l, m3, tsp, tbsp, cup, floz, gal), time (s, min, h, day, week), " +
+			"area (m2, km2, ha, sotka, ft2, acre), speed (m/s, km/h, mph, kn), " +
+			"data (b, kb, mb, gb, tb) and temperature (c, f, k).",
+		InputSchema: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"value": {"type": "number"},
+				"from": {"type": "string", "description": "Source unit, e.g. lb"},
+				"to": {"type": "string", "description": "Target unit, e.g. kg"}
+			},
+			"required": ["value", "from", "to"]
+		}`),
+		Handler: func(ctx context.Context, input json.RawMessage) (string, error) {
+			var params struct {
+				Value float64 `json:"value"`
+				From  string  `json:"from"`
+				To    string  `json:"to"`
+			}
+			if err := decodeInput(input, &params); err != nil {
+				return "", err
+			}
+
+			result, err := ConvertUnits(params.Value, params.From, params.To)
+			if err != nil {
+				return "", err
+			}
+
+			return fmt.Sprintf("%s %s = %s %s", formatNumber(params.Value), params.From, formatNumber(result), params.To), nil
+		},
+	}
+}
+
+// ConvertUnits converts value from one unit to another of the same category
+func ConvertUnits(value float64, from, to string) (float64, error) {
+	from = strings.ToLower(strings.TrimSpace(from))
+	to = strings.ToLower(strings.TrimSpace(to))
+
+	if temperatureUnits[from] || temperatureUnits[to] {
+		return convertTemperature(value, from, to)
+	}
+
+	fromUnit, ok := units[from]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit %q", from)
+	}
+	toUnit, ok := units[to]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit %q", to)
+	}
+	if fromUnit.category != toUnit.category {
+		return 0, fmt.Errorf("cannot convert %s (%s) to %s (%s)", from, fromUnit.category, to, toUnit.category)
+	}
+
+	return value * fromUnit.factor / toUnit.factor, nil
+}
+
+func convertTemperature(value float64, from, to string) (float64, error) {
+	if !temperatureUnits[from] || !temperatureUnits[to] {
+		return 0, fmt.Errorf("cannot convert %s to %s", from, to)
+	}
+
+	var celsius float64
+	switch from {
+	case "c":
+		celsius = value
+	case "f":
+		celsius = (value - 32) * 5 / 9
+	case "k":
+		celsius = value - 273.15
+	}
+
+	switch to {
+	case "f":
+		return celsius*9/5 + 32, nil
+	case "k":
+		return celsius + 273.15, nil
+	default:
+		return celsius, nil
+	}
+}